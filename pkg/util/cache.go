@@ -87,6 +87,27 @@ func (c *Cache[T]) Get(key string) (T, bool) {
 	return value.Item, true
 }
 
+// GetWithExpiration returns the item and the time it expires at. The zero time
+// is returned for items that never expire.
+func (c *Cache[T]) GetWithExpiration(key string) (T, time.Time, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	value, found := c.data[key]
+	if !found {
+		return getZero[T](), time.Time{}, false
+	}
+
+	if value.Expires > 0 {
+		if time.Now().UnixNano() > value.Expires {
+			return getZero[T](), time.Time{}, false
+		}
+		return value.Item, time.Unix(0, value.Expires), true
+	}
+
+	return value.Item, time.Time{}, true
+}
+
 func (c *Cache[T]) Keys() []string {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
@@ -94,6 +94,28 @@ func TestCacheGet(t *testing.T) {
 	}
 }
 
+func TestCacheGetWithExpiration(t *testing.T) {
+	c := NewCache[int](0)
+	expires := time.Now().Add(time.Minute)
+
+	c.data["expiring"] = CacheItem[int]{Item: 10, Expires: expires.UnixNano()}
+	c.data["expired"] = CacheItem[int]{Item: 20, Expires: time.Now().Add(-time.Minute).UnixNano()}
+	c.Set("forever", 30, DefaultCacheExpiration)
+
+	value, expiration, ok := c.GetWithExpiration("expiring")
+	assert.True(t, ok)
+	assert.Equal(t, 10, value)
+	assert.Equal(t, expires.UnixNano(), expiration.UnixNano())
+
+	_, _, ok = c.GetWithExpiration("expired")
+	assert.False(t, ok)
+
+	value, expiration, ok = c.GetWithExpiration("forever")
+	assert.True(t, ok)
+	assert.Equal(t, 30, value)
+	assert.True(t, expiration.IsZero())
+}
+
 func TestCacheClearExpired(t *testing.T) {
 	c := NewCache[int](1 * time.Millisecond)
 	n := time.Now()
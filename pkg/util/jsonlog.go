@@ -0,0 +1,242 @@
+package util
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// MinCompactionLines is the number of lines a JSONLog can grow to before it is
+// compacted, regardless of the number of live entries
+const MinCompactionLines = 1024
+
+// jsonLogHeader is the first line of a JSONLog
+type jsonLogHeader struct {
+	Version int `json:"version"`
+}
+
+// JSONLog is an append only log of JSON lines that stores use to persist
+// their state. The owner keeps the state in memory and describes it to the
+// log with the live entries that replace the log when it is compacted. The log
+// is compacted once outdated lines outnumber the live entries, such that
+// frequent writes don't rewrite the log each time.
+//
+// The first line of the log holds the version of the entries, such that a
+// change to the entry format is not misread by an older or newer owner. A
+// change to the entry format requires a new version.
+type JSONLog[T any] struct {
+	mu      sync.Mutex
+	path    string
+	name    string
+	version int
+	file    *os.File
+	lines   int
+	// live returns the entries that describe the current state of the owner
+	live func() []T
+	// size returns the number of live entries without building them
+	size func() int
+}
+
+// NewJSONLog returns a log at the provided path with entries of the provided
+// version. The name describes the owner of the log in errors. The log is
+// opened for appending by the first Compact.
+func NewJSONLog[T any](path, name string, version int, live func() []T, size func() int) *JSONLog[T] {
+	return &JSONLog[T]{
+		path:    path,
+		name:    name,
+		version: version,
+		live:    live,
+		size:    size,
+	}
+}
+
+// Load decodes every line of the log and passes it to apply in the order it
+// was written. Lines that cannot be decoded, e.g. a partially written last
+// line after a crash, are dropped. A missing log is not an error, a log of
+// another version is.
+func (l *JSONLog[T]) Load(apply func(T)) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	f, err := os.Open(l.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to open %s: %w", l.name, err)
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	for header := true; ; header = false {
+		line, err := r.ReadBytes('\n')
+		if header && len(line) > 0 {
+			if err := l.checkVersion(line); err != nil {
+				return err
+			}
+		} else if len(line) > 0 {
+			var entry T
+			if json.Unmarshal(line, &entry) == nil {
+				apply(entry)
+			}
+		}
+
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", l.name, err)
+		}
+	}
+}
+
+// Compact atomically replaces the log with the live entries and reopens the
+// log for appending
+func (l *JSONLog[T]) Compact() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.compact()
+}
+
+// CompactIfOutdated compacts the log once outdated lines outnumber the live
+// entries
+func (l *JSONLog[T]) CompactIfOutdated() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.compactIfOutdated()
+}
+
+// Write applies the entry to the state of the owner and appends it to the log
+// once it is applied, such that the log never holds an entry that the state
+// rejected. The log is compacted if it is outdated afterwards.
+func (l *JSONLog[T]) Write(entry T, apply func() error) error {
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return fmt.Errorf("%s is closed", l.name)
+	}
+
+	if err := apply(); err != nil {
+		return err
+	}
+
+	if _, err := l.file.Write(append(b, '\n')); err != nil {
+		return fmt.Errorf("failed to write %s: %w", l.name, err)
+	}
+	l.lines++
+
+	return l.compactIfOutdated()
+}
+
+// Lines returns the number of lines in the log
+func (l *JSONLog[T]) Lines() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.lines
+}
+
+// Close closes the underlying file. The log cannot be written to after it is
+// closed.
+func (l *JSONLog[T]) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// checkVersion returns an error if the header line is not of the version of
+// the log
+func (l *JSONLog[T]) checkVersion(line []byte) error {
+	var header jsonLogHeader
+	if err := json.Unmarshal(line, &header); err != nil {
+		return fmt.Errorf("failed to read the version of %s: %w", l.name, err)
+	}
+	if header.Version != l.version {
+		return fmt.Errorf("%s has unsupported version %d, expected version %d", l.name, header.Version, l.version)
+	}
+	return nil
+}
+
+func (l *JSONLog[T]) compactIfOutdated() error {
+	if l.lines > 2*l.size()+MinCompactionLines {
+		return l.compact()
+	}
+	return nil
+}
+
+func (l *JSONLog[T]) compact() error {
+	entries := l.live()
+
+	tmp, err := os.CreateTemp(filepath.Dir(l.path), filepath.Base(l.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to compact %s: %w", l.name, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(w)
+
+	if err := enc.Encode(jsonLogHeader{Version: l.version}); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	for _, entry := range entries {
+		if err := enc.Encode(entry); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if l.file != nil {
+		_ = l.file.Close()
+		l.file = nil
+	}
+
+	if err := os.Rename(tmp.Name(), l.path); err != nil {
+		return fmt.Errorf("failed to compact %s: %w", l.name, err)
+	}
+
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", l.name, err)
+	}
+	l.file = f
+	l.lines = len(entries) + 1
+
+	return nil
+}
@@ -0,0 +1,126 @@
+package util
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testLogEntry struct {
+	Key   string `json:"key"`
+	Value int    `json:"value"`
+}
+
+// testLogState is the state of a log owner that keeps the latest value of
+// every key
+type testLogState map[string]int
+
+func (s testLogState) entries() []testLogEntry {
+	entries := make([]testLogEntry, 0, len(s))
+	for key, value := range s {
+		entries = append(entries, testLogEntry{Key: key, Value: value})
+	}
+	return entries
+}
+
+func (s testLogState) size() int {
+	return len(s)
+}
+
+func (s testLogState) write(l *JSONLog[testLogEntry], key string, value int) error {
+	return l.Write(testLogEntry{Key: key, Value: value}, func() error {
+		s[key] = value
+		return nil
+	})
+}
+
+func TestJSONLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.jsonl")
+
+	state := testLogState{}
+	l := NewJSONLog[testLogEntry](path, "test log", 1, state.entries, state.size)
+	assert.ErrorContains(t, state.write(l, "a", 0), "test log is closed")
+	assert.Empty(t, state, "entries are not applied to a closed log")
+
+	// a missing log loads nothing
+	require.NoError(t, l.Load(func(testLogEntry) { t.Fatal("unexpected entry") }))
+
+	state["a"] = 1
+	require.NoError(t, l.Compact())
+	require.NoError(t, state.write(l, "b", 2))
+	require.NoError(t, state.write(l, "a", 3))
+	assert.Equal(t, 4, l.Lines())
+
+	// entries that the state rejects are not written
+	assert.ErrorContains(t, l.Write(testLogEntry{Key: "c"}, func() error { return errors.New("rejected") }), "rejected")
+	assert.Equal(t, 4, l.Lines())
+	require.NoError(t, l.Close())
+
+	// a partially written last line is dropped
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"key":"c","val`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	var loaded []testLogEntry
+	require.NoError(t, NewJSONLog[testLogEntry](path, "test log", 1, state.entries, state.size).Load(func(e testLogEntry) {
+		loaded = append(loaded, e)
+	}))
+	assert.Equal(t, []testLogEntry{{Key: "a", Value: 1}, {Key: "b", Value: 2}, {Key: "a", Value: 3}}, loaded)
+
+	// compaction replaces the log without leaving temporary files behind
+	state = testLogState{"a": 3}
+	l = NewJSONLog[testLogEntry](path, "test log", 1, state.entries, state.size)
+	require.NoError(t, l.Compact())
+	assert.Equal(t, 2, l.Lines())
+
+	loaded = nil
+	require.NoError(t, l.Load(func(e testLogEntry) {
+		loaded = append(loaded, e)
+	}))
+	assert.Equal(t, []testLogEntry{{Key: "a", Value: 3}}, loaded)
+
+	files, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, files, 1)
+
+	// the log is compacted once outdated lines outnumber the live entries
+	for i := 0; i < MinCompactionLines; i++ {
+		require.NoError(t, state.write(l, "a", i))
+	}
+	assert.Equal(t, MinCompactionLines+2, l.Lines())
+	require.NoError(t, state.write(l, "a", 0))
+	assert.Equal(t, 2, l.Lines())
+	require.NoError(t, l.Close())
+
+	// a log of another version is not loaded
+	err = NewJSONLog[testLogEntry](path, "test log", 2, state.entries, state.size).Load(func(testLogEntry) {
+		t.Fatal("unexpected entry")
+	})
+	assert.ErrorContains(t, err, "test log has unsupported version 1, expected version 2")
+}
+
+func TestJSONLog_LoadsLongLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.jsonl")
+
+	state := testLogState{}
+	l := NewJSONLog[testLogEntry](path, "test log", 1, state.entries, state.size)
+	require.NoError(t, l.Compact())
+
+	long := strings.Repeat("x", 2*1024*1024)
+	require.NoError(t, state.write(l, long, 1))
+	require.NoError(t, state.write(l, "b", 2))
+	require.NoError(t, l.Close())
+
+	var loaded []testLogEntry
+	require.NoError(t, l.Load(func(e testLogEntry) {
+		loaded = append(loaded, e)
+	}))
+	assert.Equal(t, []testLogEntry{{Key: long, Value: 1}, {Key: "b", Value: 2}}, loaded)
+}
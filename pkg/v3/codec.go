@@ -0,0 +1,505 @@
+package ocr2keepers
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+
+	ocr2keepers "github.com/smartcontractkit/chainlink-common/pkg/types/automation"
+)
+
+// NOTE: The binary codec is used to send observations and outcomes on the p2p
+// network. Any change to the layout of an existing version is a breaking change.
+// Decoders reject trailing bytes such that every value has exactly one
+// encoding, so new fields require a new version. The only exception is the
+// optional reported history section at the end of a V1 outcome. Decoders must
+// keep accepting all versions that could still be produced by nodes in a DON
+// during a rolling upgrade.
+const (
+	// codecVersionV1 is the first version of the length prefixed binary encoding
+	codecVersionV1 byte = 1
+	// codecVersionV2 encodes the block history of observations as runs of
+	// consecutive block numbers instead of full block keys
+	codecVersionV2 byte = 2
+
+	// legacyJSONPrefix is the first byte of every json encoded observation or
+	// outcome produced by previous releases
+	legacyJSONPrefix byte = '{'
+)
+
+const (
+	// bigIntNil, bigIntPositive and bigIntNegative are the markers written
+	// ahead of the magnitude of a big.Int
+	bigIntNil byte = iota
+	bigIntPositive
+	bigIntNegative
+)
+
+var (
+	errEmptyEncoding = errors.New("empty encoding")
+	errShortBuffer   = errors.New("unexpected end of encoded data")
+	errInvalidLength = errors.New("encoded length exceeds remaining data")
+	errTrailingBytes = errors.New("unexpected trailing bytes after encoded data")
+	errNilSection    = errors.New("optional section cannot encode a nil value")
+)
+
+// isLegacyJSON returns true if the provided data was encoded by the json codec
+// used by previous releases.
+func isLegacyJSON(data []byte) bool {
+	return len(data) > 0 && data[0] == legacyJSONPrefix
+}
+
+// binaryEncoder writes values to a growing byte buffer. Slices and byte arrays
+// are length prefixed with a uvarint where 0 represents a nil value and n+1
+// represents a value of length n, such that nil and empty values survive an
+// encode/decode round trip.
+type binaryEncoder struct {
+	buf []byte
+}
+
+func newBinaryEncoder(version byte) *binaryEncoder {
+	return &binaryEncoder{buf: []byte{version}}
+}
+
+func (e *binaryEncoder) bytes() []byte {
+	return e.buf
+}
+
+func (e *binaryEncoder) writeByte(b byte) {
+	e.buf = append(e.buf, b)
+}
+
+func (e *binaryEncoder) writeBool(b bool) {
+	if b {
+		e.writeByte(1)
+	} else {
+		e.writeByte(0)
+	}
+}
+
+func (e *binaryEncoder) writeUvarint(v uint64) {
+	e.buf = binary.AppendUvarint(e.buf, v)
+}
+
+func (e *binaryEncoder) writeFixed(b []byte) {
+	e.buf = append(e.buf, b...)
+}
+
+func (e *binaryEncoder) writeLength(n int, isNil bool) {
+	if isNil {
+		e.writeUvarint(0)
+		return
+	}
+	e.writeUvarint(uint64(n) + 1)
+}
+
+func (e *binaryEncoder) writeBytes(b []byte) {
+	e.writeLength(len(b), b == nil)
+	e.writeFixed(b)
+}
+
+func (e *binaryEncoder) writeString(s string) {
+	e.writeUvarint(uint64(len(s)))
+	e.buf = append(e.buf, s...)
+}
+
+func (e *binaryEncoder) writeStrings(values []string) {
+	e.writeLength(len(values), values == nil)
+	for _, v := range values {
+		e.writeString(v)
+	}
+}
+
+func (e *binaryEncoder) writeBigInt(i *big.Int) {
+	switch {
+	case i == nil:
+		e.writeByte(bigIntNil)
+		return
+	case i.Sign() < 0:
+		e.writeByte(bigIntNegative)
+	default:
+		e.writeByte(bigIntPositive)
+	}
+	e.writeBytes(i.Bytes())
+}
+
+func (e *binaryEncoder) writeTrigger(t ocr2keepers.Trigger) {
+	e.writeUvarint(uint64(t.BlockNumber))
+	e.writeFixed(t.BlockHash[:])
+	e.writeBool(t.LogTriggerExtension != nil)
+	if t.LogTriggerExtension != nil {
+		e.writeFixed(t.LogTriggerExtension.TxHash[:])
+		e.writeUvarint(uint64(t.LogTriggerExtension.Index))
+		e.writeFixed(t.LogTriggerExtension.BlockHash[:])
+		e.writeUvarint(uint64(t.LogTriggerExtension.BlockNumber))
+	}
+}
+
+func (e *binaryEncoder) writeCheckResult(r ocr2keepers.CheckResult) {
+	e.writeByte(r.PipelineExecutionState)
+	e.writeBool(r.Retryable)
+	e.writeBool(r.Eligible)
+	e.writeByte(r.IneligibilityReason)
+	e.writeFixed(r.UpkeepID[:])
+	e.writeTrigger(r.Trigger)
+	e.writeString(r.WorkID)
+	e.writeUvarint(r.GasAllocated)
+	e.writeBytes(r.PerformData)
+	e.writeBigInt(r.FastGasWei)
+	e.writeBigInt(r.LinkNative)
+}
+
+func (e *binaryEncoder) writeCheckResults(results []ocr2keepers.CheckResult) {
+	e.writeLength(len(results), results == nil)
+	for _, r := range results {
+		e.writeCheckResult(r)
+	}
+}
+
+func (e *binaryEncoder) writeProposal(p ocr2keepers.CoordinatedBlockProposal) {
+	e.writeFixed(p.UpkeepID[:])
+	e.writeTrigger(p.Trigger)
+	e.writeString(p.WorkID)
+}
+
+func (e *binaryEncoder) writeProposals(proposals []ocr2keepers.CoordinatedBlockProposal) {
+	e.writeLength(len(proposals), proposals == nil)
+	for _, p := range proposals {
+		e.writeProposal(p)
+	}
+}
+
+func (e *binaryEncoder) writeReportedWork(reported []ReportedWork) {
+	e.writeLength(len(reported), reported == nil)
+	for _, r := range reported {
+		e.writeString(r.WorkID)
+		e.writeUvarint(uint64(r.CheckBlock))
+	}
+}
+
+func (e *binaryEncoder) writeBlockKey(block ocr2keepers.BlockKey) {
+	e.writeUvarint(uint64(block.Number))
+	e.writeFixed(block.Hash[:])
+}
+
+func (e *binaryEncoder) writeBlockHistory(history ocr2keepers.BlockHistory) {
+	e.writeLength(len(history), history == nil)
+	for _, block := range history {
+		e.writeBlockKey(block)
+	}
+}
+
+// writeCompactBlockHistory writes the block history as runs of consecutive
+// block numbers. Each run is written as the number of its first block and its
+// length, with the lowest bit set for ascending runs, followed by the block
+// hashes. A history of sequential blocks thereby carries a single block number.
+func (e *binaryEncoder) writeCompactBlockHistory(history ocr2keepers.BlockHistory) {
+	e.writeLength(len(history), history == nil)
+	for start := 0; start < len(history); {
+		end, ascending := start+1, false
+		if end < len(history) {
+			ascending = history[end].Number == history[start].Number+1
+		}
+		for end < len(history) && history[end].Number == nextInRun(history[end-1].Number, ascending) {
+			end++
+		}
+		e.writeUvarint(uint64(history[start].Number))
+		runHeader := uint64(end-start) << 1
+		if ascending {
+			runHeader |= 1
+		}
+		e.writeUvarint(runHeader)
+		for _, block := range history[start:end] {
+			e.writeFixed(block.Hash[:])
+		}
+		start = end
+	}
+}
+
+// nextInRun returns the block number following number in a run of
+// consecutive blocks
+func nextInRun(number ocr2keepers.BlockNumber, ascending bool) ocr2keepers.BlockNumber {
+	if ascending {
+		return number + 1
+	}
+	return number - 1
+}
+
+// binaryDecoder reads values written by a binaryEncoder. The first error
+// encountered is kept and all subsequent reads return zero values, so callers
+// only need to check err once all values have been read.
+type binaryDecoder struct {
+	buf []byte
+	off int
+	err error
+}
+
+func newBinaryDecoder(data []byte) *binaryDecoder {
+	return &binaryDecoder{buf: data}
+}
+
+func (d *binaryDecoder) remaining() int {
+	return len(d.buf) - d.off
+}
+
+func (d *binaryDecoder) fail(err error) {
+	if d.err == nil {
+		d.err = fmt.Errorf("%w at offset %d", err, d.off)
+	}
+}
+
+// finish fails the decoder if any data is left after the last value
+func (d *binaryDecoder) finish() {
+	if d.remaining() > 0 {
+		d.fail(errTrailingBytes)
+	}
+}
+
+func (d *binaryDecoder) readByte() byte {
+	if d.err != nil {
+		return 0
+	}
+	if d.remaining() < 1 {
+		d.fail(errShortBuffer)
+		return 0
+	}
+	b := d.buf[d.off]
+	d.off++
+	return b
+}
+
+func (d *binaryDecoder) readBool() bool {
+	switch b := d.readByte(); b {
+	case 0:
+		return false
+	case 1:
+		return true
+	default:
+		d.fail(fmt.Errorf("invalid bool value %d", b))
+		return false
+	}
+}
+
+func (d *binaryDecoder) readUvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(d.buf[d.off:])
+	if n <= 0 {
+		d.fail(errShortBuffer)
+		return 0
+	}
+	d.off += n
+	return v
+}
+
+func (d *binaryDecoder) readFixed(dst []byte) {
+	if d.err != nil {
+		return
+	}
+	if d.remaining() < len(dst) {
+		d.fail(errShortBuffer)
+		return
+	}
+	d.off += copy(dst, d.buf[d.off:])
+}
+
+// readLength reads a nil aware length prefix. Every encoded element takes at
+// least one byte, so lengths larger than the remaining data are rejected
+// before any allocation happens.
+func (d *binaryDecoder) readLength() (int, bool) {
+	v := d.readUvarint()
+	if d.err != nil || v == 0 {
+		return 0, true
+	}
+	if v-1 > uint64(d.remaining()) {
+		d.fail(errInvalidLength)
+		return 0, true
+	}
+	return int(v - 1), false
+}
+
+func (d *binaryDecoder) readBytes() []byte {
+	n, isNil := d.readLength()
+	if isNil {
+		return nil
+	}
+	b := make([]byte, n)
+	d.readFixed(b)
+	return b
+}
+
+func (d *binaryDecoder) readString() string {
+	v := d.readUvarint()
+	if d.err != nil {
+		return ""
+	}
+	if v > uint64(d.remaining()) {
+		d.fail(errInvalidLength)
+		return ""
+	}
+	s := string(d.buf[d.off : d.off+int(v)])
+	d.off += int(v)
+	return s
+}
+
+func (d *binaryDecoder) readStrings() []string {
+	n, isNil := d.readLength()
+	if isNil {
+		return nil
+	}
+	values := make([]string, 0, n)
+	for i := 0; i < n && d.err == nil; i++ {
+		values = append(values, d.readString())
+	}
+	return values
+}
+
+func (d *binaryDecoder) readBigInt() *big.Int {
+	marker := d.readByte()
+	if marker == bigIntNil {
+		return nil
+	}
+	if marker != bigIntPositive && marker != bigIntNegative {
+		d.fail(fmt.Errorf("invalid big int marker %d", marker))
+		return nil
+	}
+	i := new(big.Int).SetBytes(d.readBytes())
+	if marker == bigIntNegative {
+		i.Neg(i)
+	}
+	return i
+}
+
+func (d *binaryDecoder) readTrigger() ocr2keepers.Trigger {
+	var t ocr2keepers.Trigger
+	t.BlockNumber = ocr2keepers.BlockNumber(d.readUvarint())
+	d.readFixed(t.BlockHash[:])
+	if d.readBool() {
+		ext := &ocr2keepers.LogTriggerExtension{}
+		d.readFixed(ext.TxHash[:])
+		index := d.readUvarint()
+		if index > uint64(^uint32(0)) {
+			d.fail(fmt.Errorf("log index %d out of range", index))
+		}
+		ext.Index = uint32(index)
+		d.readFixed(ext.BlockHash[:])
+		ext.BlockNumber = ocr2keepers.BlockNumber(d.readUvarint())
+		t.LogTriggerExtension = ext
+	}
+	return t
+}
+
+func (d *binaryDecoder) readCheckResult() ocr2keepers.CheckResult {
+	var r ocr2keepers.CheckResult
+	r.PipelineExecutionState = d.readByte()
+	r.Retryable = d.readBool()
+	r.Eligible = d.readBool()
+	r.IneligibilityReason = d.readByte()
+	d.readFixed(r.UpkeepID[:])
+	r.Trigger = d.readTrigger()
+	r.WorkID = d.readString()
+	r.GasAllocated = d.readUvarint()
+	r.PerformData = d.readBytes()
+	r.FastGasWei = d.readBigInt()
+	r.LinkNative = d.readBigInt()
+	return r
+}
+
+func (d *binaryDecoder) readCheckResults() []ocr2keepers.CheckResult {
+	n, isNil := d.readLength()
+	if isNil {
+		return nil
+	}
+	results := make([]ocr2keepers.CheckResult, 0, n)
+	for i := 0; i < n && d.err == nil; i++ {
+		results = append(results, d.readCheckResult())
+	}
+	return results
+}
+
+func (d *binaryDecoder) readProposal() ocr2keepers.CoordinatedBlockProposal {
+	var p ocr2keepers.CoordinatedBlockProposal
+	d.readFixed(p.UpkeepID[:])
+	p.Trigger = d.readTrigger()
+	p.WorkID = d.readString()
+	return p
+}
+
+func (d *binaryDecoder) readProposals() []ocr2keepers.CoordinatedBlockProposal {
+	n, isNil := d.readLength()
+	if isNil {
+		return nil
+	}
+	proposals := make([]ocr2keepers.CoordinatedBlockProposal, 0, n)
+	for i := 0; i < n && d.err == nil; i++ {
+		proposals = append(proposals, d.readProposal())
+	}
+	return proposals
+}
+
+func (d *binaryDecoder) readReportedWork() []ReportedWork {
+	n, isNil := d.readLength()
+	if isNil {
+		return nil
+	}
+	reported := make([]ReportedWork, 0, n)
+	for i := 0; i < n && d.err == nil; i++ {
+		var r ReportedWork
+		r.WorkID = d.readString()
+		r.CheckBlock = ocr2keepers.BlockNumber(d.readUvarint())
+		reported = append(reported, r)
+	}
+	return reported
+}
+
+func (d *binaryDecoder) readBlockHistory() ocr2keepers.BlockHistory {
+	n, isNil := d.readLength()
+	if isNil {
+		return nil
+	}
+	history := make(ocr2keepers.BlockHistory, 0, n)
+	for i := 0; i < n && d.err == nil; i++ {
+		history = append(history, d.readBlockKey())
+	}
+	return history
+}
+
+func (d *binaryDecoder) readCompactBlockHistory() ocr2keepers.BlockHistory {
+	n, isNil := d.readLength()
+	if isNil {
+		return nil
+	}
+	history := make(ocr2keepers.BlockHistory, 0, n)
+	for len(history) < n && d.err == nil {
+		number := ocr2keepers.BlockNumber(d.readUvarint())
+		runHeader := d.readUvarint()
+		if d.err != nil {
+			break
+		}
+		length, ascending := runHeader>>1, runHeader&1 == 1
+		if length == 0 || length > uint64(n-len(history)) {
+			d.fail(fmt.Errorf("invalid block history run length %d", length))
+			break
+		}
+		if (ascending && uint64(number)+length-1 < uint64(number)) || (!ascending && length-1 > uint64(number)) {
+			d.fail(fmt.Errorf("block history run of length %d from block %d overflows", length, number))
+			break
+		}
+		for i := uint64(0); i < length && d.err == nil; i++ {
+			block := ocr2keepers.BlockKey{Number: number}
+			d.readFixed(block.Hash[:])
+			history = append(history, block)
+			number = nextInRun(number, ascending)
+		}
+	}
+	return history
+}
+
+func (d *binaryDecoder) readBlockKey() ocr2keepers.BlockKey {
+	var block ocr2keepers.BlockKey
+	block.Number = ocr2keepers.BlockNumber(d.readUvarint())
+	d.readFixed(block.Hash[:])
+	return block
+}
@@ -1,6 +1,8 @@
 package config
 
 import (
+	"fmt"
+	"math/big"
 	"runtime"
 	"time"
 
@@ -17,6 +19,66 @@ const (
 	// DefaultServiceQueueLength is the default buffer size for the RPC worker
 	// queue.
 	DefaultServiceQueueLength = 1000
+	// DefaultStagedResultStarvationThreshold is the default number of check
+	// blocks in a window of staged results
+	DefaultStagedResultStarvationThreshold = 10
+)
+
+const (
+	// ProtocolLimitsVersionV0 identifies the protocol limits that were hard
+	// coded in the plugin before they became part of the offchain config
+	ProtocolLimitsVersionV0 uint8 = 0
+)
+
+const (
+	// CodecVersionJSON encodes observations and outcomes as json, the
+	// encoding understood by all releases
+	CodecVersionJSON uint8 = 0
+	// CodecVersionBinary encodes observations and outcomes with the length
+	// prefixed binary codec, which is only understood by releases that
+	// include it
+	CodecVersionBinary uint8 = 1
+)
+
+const (
+	// ReportPackingStrategySequential adds agreed performables to reports in
+	// the order of the outcome, starting a new report as soon as the next
+	// performable does not fit in the current one
+	ReportPackingStrategySequential = "sequential"
+	// ReportPackingStrategyFirstFitDecreasing sorts agreed performables by gas
+	// in decreasing order and adds each to the first report it fits in,
+	// producing fewer and fuller reports
+	ReportPackingStrategyFirstFitDecreasing = "firstFitDecreasing"
+)
+
+const (
+	// PerformablesPriorityNone orders agreed performables only by a shuffle
+	// that is random per round
+	PerformablesPriorityNone = "none"
+	// PerformablesPriorityOldestTriggerFirst prefers agreed performables with
+	// the oldest trigger, i.e. the ones that have waited longest
+	PerformablesPriorityOldestTriggerFirst = "oldestTriggerFirst"
+	// PerformablesPriorityLogTriggerFirst prefers log trigger upkeeps over
+	// conditional upkeeps
+	PerformablesPriorityLogTriggerFirst = "logTriggerFirst"
+	// PerformablesPriorityConditionalFirst prefers conditional upkeeps over
+	// log trigger upkeeps
+	PerformablesPriorityConditionalFirst = "conditionalFirst"
+)
+
+// MaxCoordinatedBlockConfirmations is the ceiling on the configured coordinated
+// block confirmations. Followers only keep blocks in their observed block
+// history that are close to the latest block of the leader, so a deeper block
+// would rarely reach quorum.
+const MaxCoordinatedBlockConfirmations = 32
+
+const (
+	// QuorumFPlusOne requires agreement of F+1 nodes, which guarantees that at
+	// least one honest node agrees
+	QuorumFPlusOne = "fPlusOne"
+	// QuorumTwoFPlusOne requires agreement of 2F+1 nodes, which guarantees
+	// that honest nodes are the majority of the agreeing nodes
+	QuorumTwoFPlusOne = "twoFPlusOne"
 )
 
 var (
@@ -24,6 +86,38 @@ var (
 	// simultaneous RPC calls. The default is based on the number of CPUs
 	// available to the current process.
 	DefaultMaxServiceWorkers = 10 * runtime.GOMAXPROCS(0)
+
+	// DefaultProtocolLimitsV0 are the protocol limits applied for version 0.
+	// These match the limits enforced by releases prior to the limits being
+	// configurable, such that a DON without configured limits behaves the same.
+	DefaultProtocolLimitsV0 = ProtocolLimits{
+		ObservationPerformablesLimit:              100,
+		ObservationLogRecoveryProposalsLimit:      5,
+		ObservationConditionalsProposalsLimit:     5,
+		OutcomeAgreedPerformablesLimit:            100,
+		OutcomeSurfacedProposalsLimit:             50,
+		OutcomeSurfacedProposalsRoundHistoryLimit: 20,
+	}
+
+	// MaxProtocolLimits are hard ceilings for configured protocol limits. Any
+	// configured value above a ceiling is reduced to the ceiling. The ceilings
+	// bound the number of items only; with large items a full observation or
+	// outcome would exceed the byte limits of the OCR protocol. Observations
+	// are kept within MaxObservationLength by the observation budget and
+	// outcomes within MaxOutcomeLength by leaving out the least important
+	// items, see EncodeOutcomeWithinLength in the ocr2keepers package.
+	MaxProtocolLimits = ProtocolLimits{
+		ObservationPerformablesLimit:              500,
+		ObservationLogRecoveryProposalsLimit:      50,
+		ObservationConditionalsProposalsLimit:     50,
+		OutcomeAgreedPerformablesLimit:            500,
+		OutcomeSurfacedProposalsLimit:             500,
+		OutcomeSurfacedProposalsRoundHistoryLimit: 100,
+	}
+
+	protocolLimitsDefaults = map[uint8]ProtocolLimits{
+		ProtocolLimitsVersionV0: DefaultProtocolLimitsV0,
+	}
 )
 
 type ReportingFactoryConfig struct {
@@ -65,8 +159,214 @@ type OffchainConfig struct {
 	// MaxUpkeepBatchSize is the max upkeep batch size of the OCR2 report.
 	MaxUpkeepBatchSize int `json:"maxUpkeepBatchSize"`
 
+	// ReportPackingStrategy defines how agreed performables are grouped into
+	// reports. Supported values are 'sequential' and 'firstFitDecreasing'.
+	// Defaults to 'sequential'.
+	ReportPackingStrategy string `json:"reportPackingStrategy"`
+
+	// StagedResultStarvationThreshold is the number of check blocks in a
+	// window of staged results. Staged results of an older window are added
+	// to observations ahead of results of a newer window, such that results
+	// that keep being left out are not starved by results checked after them.
+	// Defaults to 10.
+	StagedResultStarvationThreshold int `json:"stagedResultStarvationThreshold"`
+
+	// PerformablesPriority defines which agreed performables are preferred
+	// when more performables reach quorum than are allowed in an outcome
+	PerformablesPriority PerformablesPriorityConfig `json:"performablesPriority"`
+
+	// Quorum defines the number of nodes that need to agree on items of an
+	// outcome. Defaults to F+1 for all items.
+	Quorum QuorumConfig `json:"quorum"`
+
+	// CoordinatedBlockConfirmations is the number of blocks the block that
+	// proposals are coordinated on is kept behind the latest block that
+	// reaches quorum. The block itself also needs to reach quorum. Defaults to
+	// 0 and is capped at MaxCoordinatedBlockConfirmations.
+	CoordinatedBlockConfirmations int `json:"coordinatedBlockConfirmations"`
+
+	// MaxRetryAttempts is the number of times a payload with a retryable
+	// check failure is retried before it is marked ineligible. Defaults to 0,
+	// which retries until the retry queue expiration.
+	MaxRetryAttempts int `json:"maxRetryAttempts"`
+
+	// MaxRetryInterval enables exponential backoff of retries: the retry
+	// interval doubles with every attempt up to this interval. Units are in
+	// milliseconds. Defaults to 0, which retries at a fixed interval.
+	MaxRetryInterval int64 `json:"maxRetryInterval"`
+
+	// ResultStoreCapacity is the number of eligible results kept in the result
+	// store. Once full, new results are dropped until results are performed
+	// or expire. Defaults to 0, which uses the default of the store.
+	ResultStoreCapacity int `json:"resultStoreCapacity"`
+
+	// MetadataStore limits the number of proposals kept in the metadata
+	// store of every upkeep type
+	MetadataStore MetadataStoreConfig `json:"metadataStore"`
+
+	// PendingTransmits configures the detection of accepted reports that do
+	// not land on chain within the expected number of blocks
+	PendingTransmits PendingTransmitsConfig `json:"pendingTransmits"`
+
+	// Registries holds report settings for individual registries when a
+	// plugin instance serves multiple registries, keyed by registry name.
+	// Settings that are not configured for a registry fall back to the
+	// settings above.
+	Registries map[string]RegistryReportConfig `json:"registries"`
+
 	// LogProviderConfig holds configuration for the log provider
 	LogProviderConfig LogProviderConfig `json:"logProviderConfig"`
+
+	// CodecVersion selects the encoding of observations and outcomes. Every
+	// node computes and signs the outcome of a round, so all nodes have to
+	// encode it the same way and switch encodings together with a config
+	// change. Defaults to 0, the json encoding of previous releases, since a
+	// DON in a rolling upgrade still contains nodes that cannot decode the
+	// binary encoding.
+	//
+	// To switch a DON to the binary encoding, first upgrade all nodes to a
+	// release that decodes both encodings, then set codecVersion to 1 with a
+	// new setConfig. The new config digest starts a new OCR instance, so all
+	// nodes switch at the same round. Switching back works the same way.
+	CodecVersion uint8 `json:"codecVersion"`
+
+	// ReportedHistory enables the history of recently agreed performables in
+	// outcomes encoded with the json codec, see AutomationOutcome. Outcomes
+	// encoded with the binary codec always hold the history. It is disabled by
+	// default since nodes of previous releases don't add the history to their
+	// outcomes and would not agree with nodes that do. Only enable it once all
+	// nodes run a release that supports it.
+	ReportedHistory bool `json:"reportedHistory"`
+
+	// ProtocolLimitsVersion selects the set of defaults applied to any
+	// protocol limit that is not configured.
+	ProtocolLimitsVersion uint8 `json:"protocolLimitsVersion"`
+
+	// ProtocolLimits holds the limits on the number of items in observations
+	// and outcomes. All nodes in a DON validate each others' observations and
+	// outcomes against these limits.
+	ProtocolLimits ProtocolLimits `json:"protocolLimits"`
+}
+
+// ProtocolLimits defines the maximum number of items allowed in observations
+// and outcomes of a single round.
+type ProtocolLimits struct {
+	// ObservationPerformablesLimit is the max number of performables in a
+	// single observation.
+	ObservationPerformablesLimit int `json:"observationPerformablesLimit"`
+
+	// ObservationLogRecoveryProposalsLimit is the max number of log recovery
+	// proposals in a single observation.
+	ObservationLogRecoveryProposalsLimit int `json:"observationLogRecoveryProposalsLimit"`
+
+	// ObservationConditionalsProposalsLimit is the max number of conditional
+	// proposals in a single observation.
+	ObservationConditionalsProposalsLimit int `json:"observationConditionalsProposalsLimit"`
+
+	// OutcomeAgreedPerformablesLimit is the max number of agreed performables
+	// in a single outcome. This also limits the number of reports produced in
+	// a single round.
+	OutcomeAgreedPerformablesLimit int `json:"outcomeAgreedPerformablesLimit"`
+
+	// OutcomeSurfacedProposalsLimit is the max number of proposals surfaced
+	// in a single round of an outcome.
+	OutcomeSurfacedProposalsLimit int `json:"outcomeSurfacedProposalsLimit"`
+
+	// OutcomeSurfacedProposalsRoundHistoryLimit is the number of rounds of
+	// surfaced proposals kept in an outcome. This should be enough rounds to
+	// encompass ObservationProcessLimit so that proposals have enough time to
+	// be processed before getting coordinated on a new block.
+	OutcomeSurfacedProposalsRoundHistoryLimit int `json:"outcomeSurfacedProposalsRoundHistoryLimit"`
+}
+
+type PerformablesPriorityConfig struct {
+	// Strategy is the ordering applied to agreed performables after the
+	// upkeep tiers. Supported values are 'none', 'oldestTriggerFirst',
+	// 'logTriggerFirst' and 'conditionalFirst'. Defaults to 'none'.
+	Strategy string `json:"strategy"`
+
+	// UpkeepTiers maps decimal upkeep IDs to a priority tier. Upkeeps in a
+	// higher tier are preferred over all upkeeps in lower tiers. Upkeeps not
+	// listed are in tier 0.
+	UpkeepTiers map[string]int `json:"upkeepTiers"`
+}
+
+type QuorumConfig struct {
+	// Performables is the quorum a check result needs to become an agreed
+	// performable. Supported values are 'fPlusOne' and 'twoFPlusOne'.
+	// Defaults to 'fPlusOne'.
+	Performables string `json:"performables"`
+
+	// UpkeepPerformables maps decimal upkeep IDs to the quorum their check
+	// results need, overriding Performables for these upkeeps.
+	UpkeepPerformables map[string]string `json:"upkeepPerformables"`
+
+	// CoordinatedBlockMinimum is the minimum number of nodes that need to
+	// observe a block for it to be used to coordinate proposals. Values below
+	// F+1 are raised to F+1 and values above 2F+1 are reduced to 2F+1, as an
+	// outcome is only guaranteed to be built from 2F+1 observations.
+	CoordinatedBlockMinimum int `json:"coordinatedBlockMinimum"`
+}
+
+// MetadataStoreConfig limits the number of proposals kept in the metadata
+// store. The oldest proposals are evicted once a limit is reached, which is
+// logged and counted in the metadata store evictions metric. Zero values use
+// the defaults of the store, so proposals are evicted even when the limits are
+// not configured.
+type MetadataStoreConfig struct {
+	// MaxConditionalProposals is the number of conditional proposals kept.
+	// Defaults to 10,000.
+	MaxConditionalProposals int `json:"maxConditionalProposals"`
+	// MaxLogRecoveryProposals is the number of log recovery proposals kept.
+	// Defaults to 100,000.
+	MaxLogRecoveryProposals int `json:"maxLogRecoveryProposals"`
+}
+
+type PendingTransmitsConfig struct {
+	// InclusionBlocks is the number of blocks after the check block of an
+	// accepted report in which its transmit is expected to be included. The
+	// minimum confirmations are added on top, since transmit events are only
+	// registered once confirmed. Defaults to 0, which disables detection.
+	InclusionBlocks int `json:"inclusionBlocks"`
+
+	// ReleaseStuck releases the lock of a workID whose transmit did not land
+	// within the inclusion window, such that the work can be checked again
+	// before the perform lockout window expires.
+	ReleaseStuck bool `json:"releaseStuck"`
+}
+
+type RegistryReportConfig struct {
+	// GasLimitPerReport is the max gas that could be spent per one report of
+	// the registry.
+	GasLimitPerReport uint32 `json:"gasLimitPerReport"`
+
+	// GasOverheadPerUpkeep is gas overhead per upkeep in a report of the
+	// registry.
+	GasOverheadPerUpkeep uint32 `json:"gasOverheadPerUpkeep"`
+
+	// MaxUpkeepBatchSize is the max upkeep batch size of a report of the
+	// registry.
+	MaxUpkeepBatchSize int `json:"maxUpkeepBatchSize"`
+}
+
+// ForRegistry returns the config with the report settings of the provided
+// registry applied. Settings that are not configured for the registry keep
+// their value.
+func (c OffchainConfig) ForRegistry(name string) OffchainConfig {
+	registry, ok := c.Registries[name]
+	if !ok {
+		return c
+	}
+	if registry.GasLimitPerReport > 0 {
+		c.GasLimitPerReport = registry.GasLimitPerReport
+	}
+	if registry.GasOverheadPerUpkeep > 0 {
+		c.GasOverheadPerUpkeep = registry.GasOverheadPerUpkeep
+	}
+	if registry.MaxUpkeepBatchSize > 0 {
+		c.MaxUpkeepBatchSize = registry.MaxUpkeepBatchSize
+	}
+	return c
 }
 
 type LogProviderConfig struct {
@@ -90,6 +390,30 @@ func DecodeOffchainConfig(b []byte) (OffchainConfig, error) {
 	// ensure the defaults are applied at a minimum, for any values below the acceptable lower bound
 	ensureMinimumDefaults(&config)
 
+	switch config.ReportPackingStrategy {
+	case ReportPackingStrategySequential, ReportPackingStrategyFirstFitDecreasing:
+	default:
+		return config, fmt.Errorf("unsupported report packing strategy '%s'", config.ReportPackingStrategy)
+	}
+
+	switch config.CodecVersion {
+	case CodecVersionJSON, CodecVersionBinary:
+	default:
+		return config, fmt.Errorf("unsupported codec version %d", config.CodecVersion)
+	}
+
+	if err := ensurePerformablesPriority(&config.PerformablesPriority); err != nil {
+		return config, err
+	}
+
+	if err := ensureQuorum(&config.Quorum); err != nil {
+		return config, err
+	}
+
+	if err := ensureProtocolLimits(&config); err != nil {
+		return config, err
+	}
+
 	return config, nil
 }
 
@@ -118,4 +442,139 @@ func ensureMinimumDefaults(conf *OffchainConfig) {
 	if conf.MaxUpkeepBatchSize <= 0 {
 		conf.MaxUpkeepBatchSize = 1
 	}
+	if conf.CoordinatedBlockConfirmations < 0 {
+		conf.CoordinatedBlockConfirmations = 0
+	}
+	if conf.CoordinatedBlockConfirmations > MaxCoordinatedBlockConfirmations {
+		conf.CoordinatedBlockConfirmations = MaxCoordinatedBlockConfirmations
+	}
+	if conf.MaxRetryAttempts < 0 {
+		conf.MaxRetryAttempts = 0
+	}
+	if conf.MaxRetryInterval < 0 {
+		conf.MaxRetryInterval = 0
+	}
+	if conf.ResultStoreCapacity < 0 {
+		conf.ResultStoreCapacity = 0
+	}
+	if conf.MetadataStore.MaxConditionalProposals < 0 {
+		conf.MetadataStore.MaxConditionalProposals = 0
+	}
+	if conf.MetadataStore.MaxLogRecoveryProposals < 0 {
+		conf.MetadataStore.MaxLogRecoveryProposals = 0
+	}
+	if conf.PendingTransmits.InclusionBlocks < 0 {
+		conf.PendingTransmits.InclusionBlocks = 0
+	}
+	if conf.StagedResultStarvationThreshold <= 0 {
+		conf.StagedResultStarvationThreshold = DefaultStagedResultStarvationThreshold
+	}
+	if len(conf.ReportPackingStrategy) == 0 {
+		conf.ReportPackingStrategy = ReportPackingStrategySequential
+	}
+}
+
+// ensurePerformablesPriority validates the priority strategy and normalizes
+// upkeep IDs in the tiers to their decimal representation without leading zeros
+func ensurePerformablesPriority(conf *PerformablesPriorityConfig) error {
+	switch conf.Strategy {
+	case "":
+		conf.Strategy = PerformablesPriorityNone
+	case PerformablesPriorityNone, PerformablesPriorityOldestTriggerFirst, PerformablesPriorityLogTriggerFirst, PerformablesPriorityConditionalFirst:
+	default:
+		return fmt.Errorf("unsupported performables priority strategy '%s'", conf.Strategy)
+	}
+
+	if len(conf.UpkeepTiers) == 0 {
+		return nil
+	}
+
+	tiers := make(map[string]int, len(conf.UpkeepTiers))
+	for id, tier := range conf.UpkeepTiers {
+		upkeepID, ok := normalizeUpkeepID(id)
+		if !ok {
+			return fmt.Errorf("invalid upkeep ID '%s' in performables priority tiers", id)
+		}
+		tiers[upkeepID] = tier
+	}
+	conf.UpkeepTiers = tiers
+
+	return nil
+}
+
+// ensureQuorum validates the quorum values and normalizes upkeep IDs in the
+// upkeep overrides to their decimal representation without leading zeros
+func ensureQuorum(conf *QuorumConfig) error {
+	if len(conf.Performables) == 0 {
+		conf.Performables = QuorumFPlusOne
+	}
+	if !isSupportedQuorum(conf.Performables) {
+		return fmt.Errorf("unsupported performables quorum '%s'", conf.Performables)
+	}
+
+	if conf.CoordinatedBlockMinimum < 0 {
+		conf.CoordinatedBlockMinimum = 0
+	}
+
+	if len(conf.UpkeepPerformables) == 0 {
+		return nil
+	}
+
+	quorums := make(map[string]string, len(conf.UpkeepPerformables))
+	for id, quorum := range conf.UpkeepPerformables {
+		upkeepID, ok := normalizeUpkeepID(id)
+		if !ok {
+			return fmt.Errorf("invalid upkeep ID '%s' in performables quorum", id)
+		}
+		if !isSupportedQuorum(quorum) {
+			return fmt.Errorf("unsupported performables quorum '%s' for upkeep '%s'", quorum, id)
+		}
+		quorums[upkeepID] = quorum
+	}
+	conf.UpkeepPerformables = quorums
+
+	return nil
+}
+
+func isSupportedQuorum(quorum string) bool {
+	return quorum == QuorumFPlusOne || quorum == QuorumTwoFPlusOne
+}
+
+// normalizeUpkeepID returns the decimal representation of an upkeep ID without
+// leading zeros
+func normalizeUpkeepID(id string) (string, bool) {
+	upkeepID, ok := new(big.Int).SetString(id, 10)
+	if !ok || upkeepID.Sign() < 0 {
+		return "", false
+	}
+	return upkeepID.String(), true
+}
+
+// ensureProtocolLimits applies the versioned defaults to any protocol limit
+// that is not set and reduces any limit above its ceiling to the ceiling.
+func ensureProtocolLimits(conf *OffchainConfig) error {
+	defaults, ok := protocolLimitsDefaults[conf.ProtocolLimitsVersion]
+	if !ok {
+		return fmt.Errorf("unsupported protocol limits version %d", conf.ProtocolLimitsVersion)
+	}
+
+	limits := &conf.ProtocolLimits
+	limits.ObservationPerformablesLimit = boundLimit(limits.ObservationPerformablesLimit, defaults.ObservationPerformablesLimit, MaxProtocolLimits.ObservationPerformablesLimit)
+	limits.ObservationLogRecoveryProposalsLimit = boundLimit(limits.ObservationLogRecoveryProposalsLimit, defaults.ObservationLogRecoveryProposalsLimit, MaxProtocolLimits.ObservationLogRecoveryProposalsLimit)
+	limits.ObservationConditionalsProposalsLimit = boundLimit(limits.ObservationConditionalsProposalsLimit, defaults.ObservationConditionalsProposalsLimit, MaxProtocolLimits.ObservationConditionalsProposalsLimit)
+	limits.OutcomeAgreedPerformablesLimit = boundLimit(limits.OutcomeAgreedPerformablesLimit, defaults.OutcomeAgreedPerformablesLimit, MaxProtocolLimits.OutcomeAgreedPerformablesLimit)
+	limits.OutcomeSurfacedProposalsLimit = boundLimit(limits.OutcomeSurfacedProposalsLimit, defaults.OutcomeSurfacedProposalsLimit, MaxProtocolLimits.OutcomeSurfacedProposalsLimit)
+	limits.OutcomeSurfacedProposalsRoundHistoryLimit = boundLimit(limits.OutcomeSurfacedProposalsRoundHistoryLimit, defaults.OutcomeSurfacedProposalsRoundHistoryLimit, MaxProtocolLimits.OutcomeSurfacedProposalsRoundHistoryLimit)
+
+	return nil
+}
+
+func boundLimit(value, defaultValue, ceiling int) int {
+	if value <= 0 {
+		return defaultValue
+	}
+	if value > ceiling {
+		return ceiling
+	}
+	return value
 }
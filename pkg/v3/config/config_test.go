@@ -35,17 +35,22 @@ func TestDecodeOffchainConfig(t *testing.T) {
 			`),
 			ExpectedErrString: "",
 			ExpectedConfig: OffchainConfig{
-				PerformLockoutWindow: 1000,
-				TargetProbability:    "0.999",
-				TargetInRounds:       1,
-				MinConfirmations:     10,
-				GasLimitPerReport:    10,
-				GasOverheadPerUpkeep: 100,
-				MaxUpkeepBatchSize:   100,
+				PerformLockoutWindow:            1000,
+				TargetProbability:               "0.999",
+				TargetInRounds:                  1,
+				MinConfirmations:                10,
+				GasLimitPerReport:               10,
+				GasOverheadPerUpkeep:            100,
+				MaxUpkeepBatchSize:              100,
+				ReportPackingStrategy:           ReportPackingStrategySequential,
+				StagedResultStarvationThreshold: DefaultStagedResultStarvationThreshold,
+				PerformablesPriority:            PerformablesPriorityConfig{Strategy: PerformablesPriorityNone},
+				Quorum:                          QuorumConfig{Performables: QuorumFPlusOne},
 				LogProviderConfig: LogProviderConfig{
 					BlockRate: 32,
 					LogLimit:  50,
 				},
+				ProtocolLimits: DefaultProtocolLimitsV0,
 			},
 		},
 		{
@@ -70,17 +75,22 @@ func TestDecodeOffchainConfig(t *testing.T) {
 			`),
 			ExpectedErrString: "",
 			ExpectedConfig: OffchainConfig{
-				PerformLockoutWindow: 1000,
-				TargetProbability:    "0.999",
-				TargetInRounds:       1,
-				MinConfirmations:     10,
-				GasLimitPerReport:    10,
-				GasOverheadPerUpkeep: 100,
-				MaxUpkeepBatchSize:   100,
+				PerformLockoutWindow:            1000,
+				TargetProbability:               "0.999",
+				TargetInRounds:                  1,
+				MinConfirmations:                10,
+				GasLimitPerReport:               10,
+				GasOverheadPerUpkeep:            100,
+				MaxUpkeepBatchSize:              100,
+				ReportPackingStrategy:           ReportPackingStrategySequential,
+				StagedResultStarvationThreshold: DefaultStagedResultStarvationThreshold,
+				PerformablesPriority:            PerformablesPriorityConfig{Strategy: PerformablesPriorityNone},
+				Quorum:                          QuorumConfig{Performables: QuorumFPlusOne},
 				LogProviderConfig: LogProviderConfig{
 					BlockRate: 10,
 					LogLimit:  20,
 				},
+				ProtocolLimits: DefaultProtocolLimitsV0,
 			},
 		},
 		{
@@ -88,17 +98,22 @@ func TestDecodeOffchainConfig(t *testing.T) {
 			EncodedData:       []byte(`{}`),
 			ExpectedErrString: "",
 			ExpectedConfig: OffchainConfig{
-				PerformLockoutWindow: 1200000,
-				TargetProbability:    "0.99999",
-				TargetInRounds:       1,
-				MinConfirmations:     0,
-				GasLimitPerReport:    5_300_000,
-				GasOverheadPerUpkeep: 300_000,
-				MaxUpkeepBatchSize:   1,
+				PerformLockoutWindow:            1200000,
+				TargetProbability:               "0.99999",
+				TargetInRounds:                  1,
+				MinConfirmations:                0,
+				GasLimitPerReport:               5_300_000,
+				GasOverheadPerUpkeep:            300_000,
+				MaxUpkeepBatchSize:              1,
+				ReportPackingStrategy:           ReportPackingStrategySequential,
+				StagedResultStarvationThreshold: DefaultStagedResultStarvationThreshold,
+				PerformablesPriority:            PerformablesPriorityConfig{Strategy: PerformablesPriorityNone},
+				Quorum:                          QuorumConfig{Performables: QuorumFPlusOne},
 				LogProviderConfig: LogProviderConfig{
 					BlockRate: 0,
 					LogLimit:  0,
 				},
+				ProtocolLimits: DefaultProtocolLimitsV0,
 			},
 		},
 		{
@@ -122,19 +137,311 @@ func TestDecodeOffchainConfig(t *testing.T) {
 			`),
 			ExpectedErrString: "",
 			ExpectedConfig: OffchainConfig{
-				PerformLockoutWindow: 1200000,
-				TargetProbability:    "0.999",
-				TargetInRounds:       1,
-				MinConfirmations:     0,
-				GasLimitPerReport:    5_300_000,
-				GasOverheadPerUpkeep: 300_000,
-				MaxUpkeepBatchSize:   1,
+				PerformLockoutWindow:            1200000,
+				TargetProbability:               "0.999",
+				TargetInRounds:                  1,
+				MinConfirmations:                0,
+				GasLimitPerReport:               5_300_000,
+				GasOverheadPerUpkeep:            300_000,
+				MaxUpkeepBatchSize:              1,
+				ReportPackingStrategy:           ReportPackingStrategySequential,
+				StagedResultStarvationThreshold: DefaultStagedResultStarvationThreshold,
+				PerformablesPriority:            PerformablesPriorityConfig{Strategy: PerformablesPriorityNone},
+				Quorum:                          QuorumConfig{Performables: QuorumFPlusOne},
 				LogProviderConfig: LogProviderConfig{
 					BlockRate: 0,
 					LogLimit:  0,
 				},
+				ProtocolLimits: DefaultProtocolLimitsV0,
 			},
 		},
+		{
+			Name: "Configured protocol limits",
+			EncodedData: []byte(`
+				{
+					"protocolLimitsVersion": 0,
+					"protocolLimits": {
+						"observationPerformablesLimit": 200,
+						"observationLogRecoveryProposalsLimit": 10,
+						"observationConditionalsProposalsLimit": -1,
+						"outcomeAgreedPerformablesLimit": 10000,
+						"outcomeSurfacedProposalsLimit": 75,
+						"outcomeSurfacedProposalsRoundHistoryLimit": 0
+					}
+				}
+			`),
+			ExpectedErrString: "",
+			ExpectedConfig: OffchainConfig{
+				PerformLockoutWindow:            1200000,
+				TargetProbability:               "0.99999",
+				TargetInRounds:                  1,
+				MinConfirmations:                0,
+				GasLimitPerReport:               5_300_000,
+				GasOverheadPerUpkeep:            300_000,
+				MaxUpkeepBatchSize:              1,
+				ReportPackingStrategy:           ReportPackingStrategySequential,
+				StagedResultStarvationThreshold: DefaultStagedResultStarvationThreshold,
+				PerformablesPriority:            PerformablesPriorityConfig{Strategy: PerformablesPriorityNone},
+				Quorum:                          QuorumConfig{Performables: QuorumFPlusOne},
+				ProtocolLimits: ProtocolLimits{
+					ObservationPerformablesLimit:              200,
+					ObservationLogRecoveryProposalsLimit:      10,
+					ObservationConditionalsProposalsLimit:     5,
+					OutcomeAgreedPerformablesLimit:            500,
+					OutcomeSurfacedProposalsLimit:             75,
+					OutcomeSurfacedProposalsRoundHistoryLimit: 20,
+				},
+			},
+		},
+		{
+			Name:        "First fit decreasing report packing",
+			EncodedData: []byte(`{"maxUpkeepBatchSize": 10, "reportPackingStrategy": "firstFitDecreasing", "stagedResultStarvationThreshold": 3}`),
+			ExpectedConfig: OffchainConfig{
+				PerformLockoutWindow:            1200000,
+				TargetProbability:               "0.99999",
+				TargetInRounds:                  1,
+				GasLimitPerReport:               5_300_000,
+				GasOverheadPerUpkeep:            300_000,
+				MaxUpkeepBatchSize:              10,
+				ReportPackingStrategy:           ReportPackingStrategyFirstFitDecreasing,
+				StagedResultStarvationThreshold: 3,
+				PerformablesPriority:            PerformablesPriorityConfig{Strategy: PerformablesPriorityNone},
+				Quorum:                          QuorumConfig{Performables: QuorumFPlusOne},
+				ProtocolLimits:                  DefaultProtocolLimitsV0,
+			},
+		},
+		{
+			Name: "Performables priority",
+			EncodedData: []byte(`
+				{
+					"performablesPriority": {
+						"strategy": "oldestTriggerFirst",
+						"upkeepTiers": {
+							"0012": 2,
+							"115792089237316195423570985008687907853269984665640564039457584007913129639935": 1
+						}
+					}
+				}
+			`),
+			ExpectedConfig: OffchainConfig{
+				PerformLockoutWindow:            1200000,
+				TargetProbability:               "0.99999",
+				TargetInRounds:                  1,
+				GasLimitPerReport:               5_300_000,
+				GasOverheadPerUpkeep:            300_000,
+				MaxUpkeepBatchSize:              1,
+				ReportPackingStrategy:           ReportPackingStrategySequential,
+				StagedResultStarvationThreshold: DefaultStagedResultStarvationThreshold,
+				PerformablesPriority: PerformablesPriorityConfig{
+					Strategy: PerformablesPriorityOldestTriggerFirst,
+					UpkeepTiers: map[string]int{
+						"12": 2,
+						"115792089237316195423570985008687907853269984665640564039457584007913129639935": 1,
+					},
+				},
+				Quorum:         QuorumConfig{Performables: QuorumFPlusOne},
+				ProtocolLimits: DefaultProtocolLimitsV0,
+			},
+		},
+		{
+			Name: "Quorum thresholds",
+			EncodedData: []byte(`
+				{
+					"quorum": {
+						"performables": "twoFPlusOne",
+						"upkeepPerformables": {
+							"007": "fPlusOne"
+						},
+						"coordinatedBlockMinimum": 5
+					}
+				}
+			`),
+			ExpectedConfig: OffchainConfig{
+				PerformLockoutWindow:            1200000,
+				TargetProbability:               "0.99999",
+				TargetInRounds:                  1,
+				GasLimitPerReport:               5_300_000,
+				GasOverheadPerUpkeep:            300_000,
+				MaxUpkeepBatchSize:              1,
+				ReportPackingStrategy:           ReportPackingStrategySequential,
+				StagedResultStarvationThreshold: DefaultStagedResultStarvationThreshold,
+				PerformablesPriority:            PerformablesPriorityConfig{Strategy: PerformablesPriorityNone},
+				Quorum: QuorumConfig{
+					Performables:            QuorumTwoFPlusOne,
+					UpkeepPerformables:      map[string]string{"7": QuorumFPlusOne},
+					CoordinatedBlockMinimum: 5,
+				},
+				ProtocolLimits: DefaultProtocolLimitsV0,
+			},
+		},
+		{
+			Name:        "Coordinated block confirmations are capped",
+			EncodedData: []byte(`{"coordinatedBlockConfirmations": 1000}`),
+			ExpectedConfig: OffchainConfig{
+				PerformLockoutWindow:            1200000,
+				TargetProbability:               "0.99999",
+				TargetInRounds:                  1,
+				GasLimitPerReport:               5_300_000,
+				GasOverheadPerUpkeep:            300_000,
+				MaxUpkeepBatchSize:              1,
+				ReportPackingStrategy:           ReportPackingStrategySequential,
+				StagedResultStarvationThreshold: DefaultStagedResultStarvationThreshold,
+				PerformablesPriority:            PerformablesPriorityConfig{Strategy: PerformablesPriorityNone},
+				Quorum:                          QuorumConfig{Performables: QuorumFPlusOne},
+				CoordinatedBlockConfirmations:   MaxCoordinatedBlockConfirmations,
+				ProtocolLimits:                  DefaultProtocolLimitsV0,
+			},
+		},
+		{
+			Name:        "Pending transmits are decoded",
+			EncodedData: []byte(`{"pendingTransmits": {"inclusionBlocks": -5, "releaseStuck": true}}`),
+			ExpectedConfig: OffchainConfig{
+				PerformLockoutWindow:            1200000,
+				TargetProbability:               "0.99999",
+				TargetInRounds:                  1,
+				GasLimitPerReport:               5_300_000,
+				GasOverheadPerUpkeep:            300_000,
+				MaxUpkeepBatchSize:              1,
+				ReportPackingStrategy:           ReportPackingStrategySequential,
+				StagedResultStarvationThreshold: DefaultStagedResultStarvationThreshold,
+				PerformablesPriority:            PerformablesPriorityConfig{Strategy: PerformablesPriorityNone},
+				Quorum:                          QuorumConfig{Performables: QuorumFPlusOne},
+				PendingTransmits:                PendingTransmitsConfig{InclusionBlocks: 0, ReleaseStuck: true},
+				ProtocolLimits:                  DefaultProtocolLimitsV0,
+			},
+		},
+		{
+			Name:        "Negative retry attempts are unlimited",
+			EncodedData: []byte(`{"maxRetryAttempts": -1, "maxRetryInterval": -1}`),
+			ExpectedConfig: OffchainConfig{
+				PerformLockoutWindow:            1200000,
+				TargetProbability:               "0.99999",
+				TargetInRounds:                  1,
+				GasLimitPerReport:               5_300_000,
+				GasOverheadPerUpkeep:            300_000,
+				MaxUpkeepBatchSize:              1,
+				ReportPackingStrategy:           ReportPackingStrategySequential,
+				StagedResultStarvationThreshold: DefaultStagedResultStarvationThreshold,
+				PerformablesPriority:            PerformablesPriorityConfig{Strategy: PerformablesPriorityNone},
+				Quorum:                          QuorumConfig{Performables: QuorumFPlusOne},
+				MaxRetryAttempts:                0,
+				ProtocolLimits:                  DefaultProtocolLimitsV0,
+			},
+		},
+		{
+			Name:        "Max retry interval enables backoff",
+			EncodedData: []byte(`{"maxRetryInterval": 600000}`),
+			ExpectedConfig: OffchainConfig{
+				PerformLockoutWindow:            1200000,
+				TargetProbability:               "0.99999",
+				TargetInRounds:                  1,
+				GasLimitPerReport:               5_300_000,
+				GasOverheadPerUpkeep:            300_000,
+				MaxUpkeepBatchSize:              1,
+				ReportPackingStrategy:           ReportPackingStrategySequential,
+				StagedResultStarvationThreshold: DefaultStagedResultStarvationThreshold,
+				PerformablesPriority:            PerformablesPriorityConfig{Strategy: PerformablesPriorityNone},
+				Quorum:                          QuorumConfig{Performables: QuorumFPlusOne},
+				MaxRetryInterval:                600000,
+				ProtocolLimits:                  DefaultProtocolLimitsV0,
+			},
+		},
+		{
+			Name:        "Metadata store limits are decoded",
+			EncodedData: []byte(`{"metadataStore": {"maxConditionalProposals": -1, "maxLogRecoveryProposals": 500}}`),
+			ExpectedConfig: OffchainConfig{
+				PerformLockoutWindow:            1200000,
+				TargetProbability:               "0.99999",
+				TargetInRounds:                  1,
+				GasLimitPerReport:               5_300_000,
+				GasOverheadPerUpkeep:            300_000,
+				MaxUpkeepBatchSize:              1,
+				ReportPackingStrategy:           ReportPackingStrategySequential,
+				StagedResultStarvationThreshold: DefaultStagedResultStarvationThreshold,
+				PerformablesPriority:            PerformablesPriorityConfig{Strategy: PerformablesPriorityNone},
+				Quorum:                          QuorumConfig{Performables: QuorumFPlusOne},
+				MetadataStore:                   MetadataStoreConfig{MaxConditionalProposals: 0, MaxLogRecoveryProposals: 500},
+				ProtocolLimits:                  DefaultProtocolLimitsV0,
+			},
+		},
+		{
+			Name:        "Result store capacity is decoded",
+			EncodedData: []byte(`{"resultStoreCapacity": 500}`),
+			ExpectedConfig: OffchainConfig{
+				PerformLockoutWindow:            1200000,
+				TargetProbability:               "0.99999",
+				TargetInRounds:                  1,
+				GasLimitPerReport:               5_300_000,
+				GasOverheadPerUpkeep:            300_000,
+				MaxUpkeepBatchSize:              1,
+				ReportPackingStrategy:           ReportPackingStrategySequential,
+				StagedResultStarvationThreshold: DefaultStagedResultStarvationThreshold,
+				PerformablesPriority:            PerformablesPriorityConfig{Strategy: PerformablesPriorityNone},
+				Quorum:                          QuorumConfig{Performables: QuorumFPlusOne},
+				ResultStoreCapacity:             500,
+				ProtocolLimits:                  DefaultProtocolLimitsV0,
+			},
+		},
+		{
+			Name:        "Binary codec version is decoded",
+			EncodedData: []byte(`{"codecVersion": 1}`),
+			ExpectedConfig: OffchainConfig{
+				PerformLockoutWindow:            1200000,
+				TargetProbability:               "0.99999",
+				TargetInRounds:                  1,
+				GasLimitPerReport:               5_300_000,
+				GasOverheadPerUpkeep:            300_000,
+				MaxUpkeepBatchSize:              1,
+				ReportPackingStrategy:           ReportPackingStrategySequential,
+				StagedResultStarvationThreshold: DefaultStagedResultStarvationThreshold,
+				PerformablesPriority:            PerformablesPriorityConfig{Strategy: PerformablesPriorityNone},
+				Quorum:                          QuorumConfig{Performables: QuorumFPlusOne},
+				CodecVersion:                    CodecVersionBinary,
+				ProtocolLimits:                  DefaultProtocolLimitsV0,
+			},
+		},
+		{
+			Name:              "Unsupported performables quorum",
+			EncodedData:       []byte(`{"quorum": {"performables": "all"}}`),
+			ExpectedErrString: "unsupported performables quorum 'all'",
+			ExpectedConfig:    OffchainConfig{},
+		},
+		{
+			Name:              "Unsupported upkeep performables quorum",
+			EncodedData:       []byte(`{"quorum": {"upkeepPerformables": {"1": "fPlusTwo"}}}`),
+			ExpectedErrString: "unsupported performables quorum 'fPlusTwo' for upkeep '1'",
+			ExpectedConfig:    OffchainConfig{},
+		},
+		{
+			Name:              "Unsupported performables priority strategy",
+			EncodedData:       []byte(`{"performablesPriority": {"strategy": "newestFirst"}}`),
+			ExpectedErrString: "unsupported performables priority strategy 'newestFirst'",
+			ExpectedConfig:    OffchainConfig{},
+		},
+		{
+			Name:              "Invalid upkeep ID in performables priority tiers",
+			EncodedData:       []byte(`{"performablesPriority": {"upkeepTiers": {"0xabc": 1}}}`),
+			ExpectedErrString: "invalid upkeep ID '0xabc' in performables priority tiers",
+			ExpectedConfig:    OffchainConfig{},
+		},
+		{
+			Name:              "Unsupported report packing strategy",
+			EncodedData:       []byte(`{"reportPackingStrategy": "random"}`),
+			ExpectedErrString: "unsupported report packing strategy 'random'",
+			ExpectedConfig:    OffchainConfig{},
+		},
+		{
+			Name:              "Unsupported protocol limits version",
+			EncodedData:       []byte(`{"protocolLimitsVersion": 7}`),
+			ExpectedErrString: "unsupported protocol limits version 7",
+			ExpectedConfig:    OffchainConfig{},
+		},
+		{
+			Name:              "Unsupported codec version",
+			EncodedData:       []byte(`{"codecVersion": 2}`),
+			ExpectedErrString: "unsupported codec version 2",
+			ExpectedConfig:    OffchainConfig{},
+		},
 		{
 			Name: "Unexpected type",
 			EncodedData: []byte(`
@@ -168,3 +475,24 @@ func TestDecodeOffchainConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestOffchainConfig_ForRegistry(t *testing.T) {
+	conf, err := DecodeOffchainConfig([]byte(`
+		{
+			"gasLimitPerReport": 1000,
+			"gasOverheadPerUpkeep": 10,
+			"maxUpkeepBatchSize": 5,
+			"registries": {
+				"a": {"gasLimitPerReport": 2000, "maxUpkeepBatchSize": 2}
+			}
+		}
+	`))
+	assert.NoError(t, err)
+
+	a := conf.ForRegistry("a")
+	assert.Equal(t, uint32(2000), a.GasLimitPerReport)
+	assert.Equal(t, uint32(10), a.GasOverheadPerUpkeep)
+	assert.Equal(t, 2, a.MaxUpkeepBatchSize)
+
+	assert.Equal(t, conf, conf.ForRegistry("b"))
+}
@@ -5,6 +5,8 @@ import (
 	"encoding/hex"
 	"fmt"
 	"log"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/smartcontractkit/chainlink-common/pkg/services"
@@ -12,12 +14,16 @@ import (
 
 	"github.com/smartcontractkit/chainlink-automation/pkg/util"
 	"github.com/smartcontractkit/chainlink-automation/pkg/v3/config"
+	"github.com/smartcontractkit/chainlink-automation/pkg/v3/prommetrics"
 	"github.com/smartcontractkit/chainlink-automation/pkg/v3/types"
 )
 
 const (
 	cadence           = time.Second
 	defaultCacheClean = time.Duration(30) * time.Second
+	// subscribedCadence is the polling cadence while transmit events are
+	// pushed by the provider; polling only catches events that were missed
+	subscribedCadence = time.Duration(30) * time.Second
 )
 
 type coordinator struct {
@@ -28,24 +34,56 @@ type coordinator struct {
 
 	eventsProvider   types.TransmitEventProvider
 	upkeepTypeGetter types.UpkeepTypeGetter
+	blocks           types.BlockHistoryGetter
 
 	cache   *util.Cache[record]
 	visited *util.Cache[bool]
+	store   Store
 
 	minimumConfirmations int
 	performLockoutWindow time.Duration
+
+	// inclusionBlocks is the number of blocks after the check block in which
+	// a transmit is expected to land; zero disables stuck transmit detection
+	inclusionBlocks int
+	releaseStuck    bool
+	// stuck holds the check block of workIDs reported as stuck, such that
+	// every stuck transmit is only reported once
+	stuck map[string]common.BlockNumber
+	// unchecked holds the transmit block of workIDs whose transmit block left
+	// the block history before its hash was known, such that every transmit
+	// that can't be checked for reorgs is only reported once
+	unchecked map[string]common.BlockNumber
 }
 
 var _ types.Coordinator = (*coordinator)(nil)
 
 type record struct {
+	upkeepID              common.UpkeepIdentifier
 	checkBlockNumber      common.BlockNumber
 	isTransmissionPending bool // false = transmitted
 	transmitType          common.TransmitEventType
 	transmitBlockNumber   common.BlockNumber
+	// transmitBlockHash is the hash of the transmit block at the time the
+	// transmit event was processed or, if the block was not in the block
+	// history yet, once it is; zero while unknown
+	transmitBlockHash [32]byte
 }
 
 func NewCoordinator(transmitEventProvider types.TransmitEventProvider, upkeepTypeGetter types.UpkeepTypeGetter, conf config.OffchainConfig, logger *log.Logger) *coordinator {
+	return NewCoordinatorWithStore(transmitEventProvider, upkeepTypeGetter, nil, conf, NewMemoryStore(), logger)
+}
+
+// NewCoordinatorWithStore creates a coordinator that persists its records and
+// visited events in the provided store and restores them on Start. A nil store
+// defaults to an in-memory store. The block history of blocks is used to detect
+// transmits that were reorged out; a nil value only handles reorgs reported by
+// the transmit event provider.
+func NewCoordinatorWithStore(transmitEventProvider types.TransmitEventProvider, upkeepTypeGetter types.UpkeepTypeGetter, blocks types.BlockHistoryGetter, conf config.OffchainConfig, store Store, logger *log.Logger) *coordinator {
+	if store == nil {
+		store = NewMemoryStore()
+	}
+
 	performLockoutWindow := time.Duration(conf.PerformLockoutWindow) * time.Millisecond
 	return &coordinator{
 		stopCh:               make(chan struct{}),
@@ -53,25 +91,33 @@ func NewCoordinator(transmitEventProvider types.TransmitEventProvider, upkeepTyp
 		logger:               logger,
 		eventsProvider:       transmitEventProvider,
 		upkeepTypeGetter:     upkeepTypeGetter,
+		blocks:               blocks,
 		cache:                util.NewCache[record](performLockoutWindow),
 		visited:              util.NewCache[bool](performLockoutWindow),
+		store:                store,
 		minimumConfirmations: conf.MinConfirmations,
 		performLockoutWindow: performLockoutWindow,
+		inclusionBlocks:      conf.PendingTransmits.InclusionBlocks,
+		releaseStuck:         conf.PendingTransmits.ReleaseStuck,
+		stuck:                make(map[string]common.BlockNumber),
+		unchecked:            make(map[string]common.BlockNumber),
 	}
 }
 
 func (c *coordinator) Accept(reportedUpkeep common.ReportedUpkeep) bool {
 	if v, ok := c.cache.Get(reportedUpkeep.WorkID); !ok {
-		c.cache.Set(reportedUpkeep.WorkID, record{
+		c.setRecord(reportedUpkeep.WorkID, record{
+			upkeepID:              reportedUpkeep.UpkeepID,
 			checkBlockNumber:      reportedUpkeep.Trigger.BlockNumber,
 			isTransmissionPending: true,
-		}, util.DefaultCacheExpiration)
+		})
 		return true
 	} else if v.checkBlockNumber < reportedUpkeep.Trigger.BlockNumber {
-		c.cache.Set(reportedUpkeep.WorkID, record{
+		c.setRecord(reportedUpkeep.WorkID, record{
+			upkeepID:              reportedUpkeep.UpkeepID,
 			checkBlockNumber:      reportedUpkeep.Trigger.BlockNumber,
 			isTransmissionPending: true,
-		}, util.DefaultCacheExpiration)
+		})
 		return true
 	}
 	// We are already waiting on a higher checkBlockNumber so no need to accept this report
@@ -82,6 +128,7 @@ func (c *coordinator) ShouldTransmit(reportedUpkeep common.ReportedUpkeep) bool
 	if v, ok := c.cache.Get(reportedUpkeep.WorkID); !ok {
 		// We never saw this report, so don't try to transmit
 		// Can happen in edge cases when plugin restarts after shouldAccept was called
+		// and the coordinator state was not persisted
 		return false
 	} else if reportedUpkeep.Trigger.BlockNumber < v.checkBlockNumber {
 		// We already accepted a report for a higher checkBlockNumber, so don't try to transmit
@@ -91,6 +138,7 @@ func (c *coordinator) ShouldTransmit(reportedUpkeep common.ReportedUpkeep) bool
 	} else {
 		// We never saw this report for such a high block number, so don't try to transmit
 		// Can happen in edge cases when plugin restarts after shouldAccept was called
+		// and the coordinator state was not persisted
 		return false
 	}
 }
@@ -133,6 +181,17 @@ func (c *coordinator) FilterProposals(proposals []common.CoordinatedBlockProposa
 	return res, nil
 }
 
+func (c *coordinator) InFlightWorkIDs() []string {
+	workIDs := make([]string, 0)
+	for _, workID := range c.cache.Keys() {
+		if v, ok := c.cache.Get(workID); ok && v.isTransmissionPending {
+			workIDs = append(workIDs, workID)
+		}
+	}
+	sort.Strings(workIDs)
+	return workIDs
+}
+
 func (c *coordinator) ShouldProcess(workID string, upkeepID common.UpkeepIdentifier, trigger common.Trigger) bool {
 	if v, ok := c.cache.Get(workID); ok {
 		if v.isTransmissionPending {
@@ -171,6 +230,16 @@ func (c *coordinator) checkEvents(ctx context.Context) error {
 		return err
 	}
 
+	c.processEvents(events)
+
+	return nil
+}
+
+// processEvents updates the records of the workIDs of transmit events that
+// reached the minimum confirmations, whether polled or pushed by the provider
+func (c *coordinator) processEvents(events []common.TransmitEvent) {
+	history := c.blockHashes()
+
 	skipped := 0
 	for _, event := range events {
 		if event.Confirmations < int64(c.minimumConfirmations) {
@@ -190,39 +259,151 @@ func (c *coordinator) checkEvents(ctx context.Context) error {
 			c.logger.Printf("Ignoring event in transaction %s of type %d for upkeepID %s, workID %s as it was not found in cache", hex.EncodeToString(event.TransactionHash[:]), event.Type, event.UpkeepID.String(), event.WorkID)
 			continue
 		}
-		c.visited.Set(visitedID, true, c.performLockoutWindow)
+		c.setVisited(visitedID)
 		r := record{
+			upkeepID:              event.UpkeepID,
 			isTransmissionPending: false,
 			transmitType:          event.Type,
 			transmitBlockNumber:   event.TransmitBlock,
+			transmitBlockHash:     history[event.TransmitBlock],
+		}
+		if event.Type == common.ReorgReportEvent {
+			c.logger.Printf("Got reorg event in transaction %s for upkeepID %s, workID %s and check block %v; workID can be processed again", hex.EncodeToString(event.TransactionHash[:]), event.UpkeepID.String(), event.WorkID, event.CheckBlock)
 		}
 		if event.CheckBlock == v.checkBlockNumber {
 			c.logger.Printf("Got event in transaction %s of type %d for upkeepID %s, workID %s and check block %v", hex.EncodeToString(event.TransactionHash[:]), event.Type, event.UpkeepID.String(), event.WorkID, event.CheckBlock)
 			r.checkBlockNumber = v.checkBlockNumber
-			c.cache.Set(event.WorkID, r, util.DefaultCacheExpiration)
+			c.setRecord(event.WorkID, r)
 		} else if event.CheckBlock > v.checkBlockNumber {
 			c.logger.Printf("Got event in transaction %s of type %d for upkeepID %s, workID %s from newer report (block %v) while waiting for (block %v)", hex.EncodeToString(event.TransactionHash[:]), event.Type, event.UpkeepID.String(), event.WorkID, event.CheckBlock, v.checkBlockNumber)
 			r.checkBlockNumber = event.CheckBlock
-			c.cache.Set(event.WorkID, r, util.DefaultCacheExpiration)
+			c.setRecord(event.WorkID, r)
 		}
 		// otherwise this is an old event, ignore it
 	}
 	c.logger.Printf("Skipped %d events as confirmations are less than minimum confirmations (%d)", skipped, c.minimumConfirmations)
 
-	return nil
+	c.checkReorgs(history)
+	c.checkStuckTransmits(history)
+}
+
+// blockHashes returns the hashes of the latest block history by block number
+func (c *coordinator) blockHashes() map[common.BlockNumber][32]byte {
+	hashes := make(map[common.BlockNumber][32]byte)
+	if c.blocks == nil {
+		return hashes
+	}
+
+	for _, block := range c.blocks.GetBlockHistory() {
+		hashes[block.Number] = block.Hash
+	}
+
+	return hashes
+}
+
+// checkReorgs makes workIDs processable again when the block of their transmit
+// is no longer part of the canonical chain, i.e. the hash of the block in the
+// latest block history differs from the hash at the time the transmit event
+// was processed. Transmit blocks older than the block history are final.
+func (c *coordinator) checkReorgs(history map[common.BlockNumber][32]byte) {
+	for _, workID := range c.cache.Keys() {
+		v, ok := c.cache.Get(workID)
+		if !ok || v.isTransmissionPending || v.transmitBlockNumber == 0 {
+			continue
+		}
+
+		if v.transmitBlockHash == [32]byte{} {
+			c.resolveTransmitBlockHash(workID, v, history)
+			continue
+		}
+
+		hash, ok := history[v.transmitBlockNumber]
+		if !ok || hash == v.transmitBlockHash {
+			continue
+		}
+
+		c.logger.Printf("Transmit of workID %s in block %d was reorged out; workID can be processed again", workID, v.transmitBlockNumber)
+
+		// the transmit event can be reported again when the transaction is
+		// included in the new chain
+		c.removeVisited(workID)
+		c.setRecord(workID, record{
+			upkeepID:              v.upkeepID,
+			checkBlockNumber:      v.checkBlockNumber,
+			isTransmissionPending: false,
+			transmitType:          common.ReorgReportEvent,
+		})
+	}
+
+	// forget unchecked workIDs that no longer wait for their transmit block
+	for workID, transmitBlock := range c.unchecked {
+		if v, ok := c.cache.Get(workID); !ok || v.transmitBlockNumber != transmitBlock || v.transmitBlockHash != [32]byte{} {
+			delete(c.unchecked, workID)
+		}
+	}
+}
+
+// resolveTransmitBlockHash sets the hash of the transmit block of a record
+// once the block is in the block history. Transmit events can be processed
+// before the block history includes their block. If the block left the block
+// history before its hash was known, the transmit can't be checked for reorgs,
+// which is reported once.
+func (c *coordinator) resolveTransmitBlockHash(workID string, v record, history map[common.BlockNumber][32]byte) {
+	if hash, ok := history[v.transmitBlockNumber]; ok {
+		v.transmitBlockHash = hash
+		c.setRecord(workID, v)
+		return
+	}
+
+	if len(history) == 0 {
+		return
+	}
+	for number := range history {
+		if number <= v.transmitBlockNumber {
+			// the block history has not reached the transmit block yet
+			return
+		}
+	}
+
+	if reported, ok := c.unchecked[workID]; !ok || reported != v.transmitBlockNumber {
+		c.logger.Printf("Transmit of workID %s in block %d can't be checked for reorgs as the block left the block history before its hash was known", workID, v.transmitBlockNumber)
+		prommetrics.AutomationUncheckedTransmits.Inc()
+		c.unchecked[workID] = v.transmitBlockNumber
+	}
 }
 
 func (c *coordinator) run() {
 	defer close(c.done)
 
-	timer := time.NewTimer(cadence)
+	// events are pushed if the provider supports subscriptions and polled at
+	// a reduced cadence as a fallback
+	pollCadence := cadence
+	pushed, unsubscribe := c.subscribe()
+	defer unsubscribe()
+	if pushed != nil {
+		pollCadence = subscribedCadence
+	}
+
+	timer := time.NewTimer(pollCadence)
 	defer timer.Stop()
 
+	clean := time.NewTicker(defaultCacheClean)
+	defer clean.Stop()
+
 	ctx, cancel := c.stopCh.NewCtx()
 	defer cancel()
 
 	for {
 		select {
+		case events, ok := <-pushed:
+			if !ok {
+				c.logger.Printf("transmit event subscription closed; falling back to polling every %dms", cadence/time.Millisecond)
+				pushed = nil
+				pollCadence = cadence
+				timer.Reset(time.Microsecond)
+				continue
+			}
+			c.processEvents(events)
 		case <-timer.C:
 			startTime := time.Now()
 
@@ -233,16 +414,20 @@ func (c *coordinator) run() {
 				c.logger.Printf("failed to check for transmit events: %s", err)
 			}
 
-			// attempt to adhere to a cadence of at least every second
+			// attempt to adhere to the polling cadence
 			// a slow DB will cause the cadence to increase. these cases are logged
 			diff := time.Since(startTime)
-			if diff > cadence {
-				c.logger.Printf("check transmit events took %dms to complete; expected cadence is %dms; check database indexes and other performance improvements", diff/time.Millisecond, cadence/time.Millisecond)
+			if diff > pollCadence {
+				c.logger.Printf("check transmit events took %dms to complete; expected cadence is %dms; check database indexes and other performance improvements", diff/time.Millisecond, pollCadence/time.Millisecond)
 				// start again immediately
 				timer.Reset(time.Microsecond)
 			} else {
 				// wait the difference between the cadence and the time taken
-				timer.Reset(cadence - diff)
+				timer.Reset(pollCadence - diff)
+			}
+		case <-clean.C:
+			if err := c.store.ClearExpired(time.Now()); err != nil {
+				c.logger.Printf("failed to clear expired coordinator state: %s", err)
 			}
 		case <-ctx.Done():
 			return
@@ -250,9 +435,30 @@ func (c *coordinator) run() {
 	}
 }
 
+// subscribe subscribes to pushed transmit events if the events provider
+// supports it. The returned channel is nil if events can only be polled.
+func (c *coordinator) subscribe() (chan []common.TransmitEvent, func()) {
+	subscriber, ok := c.eventsProvider.(types.TransmitEventSubscriber)
+	if !ok {
+		return nil, func() {}
+	}
+
+	subscriptionID, ch, err := subscriber.SubscribeTransmitEvents()
+	if err != nil {
+		c.logger.Printf("failed to subscribe to transmit events; falling back to polling: %s", err)
+		return nil, func() {}
+	}
+
+	return ch, func() {
+		if err := subscriber.UnsubscribeTransmitEvents(subscriptionID); err != nil {
+			c.logger.Printf("failed to unsubscribe from transmit events: %s", err)
+		}
+	}
+}
+
 // Start starts all subprocesses
 func (c *coordinator) Start(_ context.Context) error {
-	if err := c.StateMachine.StartOnce("Coordinator", func() error { return nil }); err != nil {
+	if err := c.StateMachine.StartOnce("Coordinator", c.restore); err != nil {
 		return err
 	}
 
@@ -276,6 +482,159 @@ func (c *coordinator) Close() error {
 	})
 }
 
+// restore loads the records and visited events persisted by a previous
+// coordinator such that pending transmits are not dropped and performed log
+// upkeeps are not performed again after a restart
+func (c *coordinator) restore() error {
+	records, err := c.store.Records()
+	if err != nil {
+		return fmt.Errorf("failed to restore coordinator records: %w", err)
+	}
+
+	visited, err := c.store.Visited()
+	if err != nil {
+		return fmt.Errorf("failed to restore coordinator visited events: %w", err)
+	}
+
+	now := time.Now()
+	for _, r := range records {
+		if expire, ok := remaining(r.Expires, now); ok {
+			c.cache.Set(r.WorkID, record{
+				upkeepID:              r.UpkeepID,
+				checkBlockNumber:      r.CheckBlockNumber,
+				isTransmissionPending: r.IsTransmissionPending,
+				transmitType:          r.TransmitType,
+				transmitBlockNumber:   r.TransmitBlockNumber,
+				transmitBlockHash:     r.TransmitBlockHash,
+			}, expire)
+		}
+	}
+
+	for _, v := range visited {
+		if expire, ok := remaining(v.Expires, now); ok {
+			c.visited.Set(v.ID, true, expire)
+		}
+	}
+
+	c.logger.Printf("restored %d records and %d visited events", len(records), len(visited))
+
+	return nil
+}
+
+// remaining returns the cache expiration for a persisted expiry time and false
+// if the expiry time already passed
+func remaining(expires, now time.Time) (time.Duration, bool) {
+	if expires.IsZero() {
+		return util.DefaultCacheExpiration, true
+	}
+
+	expire := expires.Sub(now)
+	return expire, expire > 0
+}
+
+// expires returns the expiry time of a value cached now with the perform
+// lockout window
+func (c *coordinator) expires() time.Time {
+	if c.performLockoutWindow <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(c.performLockoutWindow)
+}
+
+// setRecord caches the record of a workID and persists it in the store
+func (c *coordinator) setRecord(workID string, r record) {
+	c.cache.Set(workID, r, util.DefaultCacheExpiration)
+
+	if err := c.store.SetRecord(Record{
+		WorkID:                workID,
+		UpkeepID:              r.upkeepID,
+		CheckBlockNumber:      r.checkBlockNumber,
+		IsTransmissionPending: r.isTransmissionPending,
+		TransmitType:          r.transmitType,
+		TransmitBlockNumber:   r.transmitBlockNumber,
+		TransmitBlockHash:     r.transmitBlockHash,
+		Expires:               c.expires(),
+	}); err != nil {
+		c.logger.Printf("failed to persist coordinator record for workID %s: %s", workID, err)
+	}
+}
+
+// setVisited caches a visited event and persists it in the store
+func (c *coordinator) setVisited(visitedID string) {
+	c.visited.Set(visitedID, true, c.performLockoutWindow)
+
+	if err := c.store.SetVisited(VisitedEvent{
+		ID:      visitedID,
+		Expires: c.expires(),
+	}); err != nil {
+		c.logger.Printf("failed to persist coordinator visited event %s: %s", visitedID, err)
+	}
+}
+
+// checkStuckTransmits reports workIDs whose transmit is pending for longer
+// than the inclusion window, measured in blocks from the check block to the
+// latest block in the block history, and releases them if configured
+func (c *coordinator) checkStuckTransmits(history map[common.BlockNumber][32]byte) {
+	if c.inclusionBlocks <= 0 || len(history) == 0 {
+		return
+	}
+
+	var latest common.BlockNumber
+	for number := range history {
+		if number > latest {
+			latest = number
+		}
+	}
+
+	window := common.BlockNumber(c.inclusionBlocks + c.minimumConfirmations)
+	for _, workID := range c.cache.Keys() {
+		v, ok := c.cache.Get(workID)
+		if !ok || !v.isTransmissionPending || latest <= v.checkBlockNumber+window {
+			continue
+		}
+
+		if reported, ok := c.stuck[workID]; !ok || reported != v.checkBlockNumber {
+			c.logger.Printf("Transmit of workID %s checked at block %d is pending for %d blocks; expected inclusion within %d blocks", workID, v.checkBlockNumber, latest-v.checkBlockNumber, window)
+			prommetrics.AutomationStuckTransmits.WithLabelValues(prommetrics.StuckTransmitStateDetected).Inc()
+			c.stuck[workID] = v.checkBlockNumber
+		}
+
+		if c.releaseStuck {
+			c.logger.Printf("Releasing stuck transmit of workID %s checked at block %d; workID can be processed again", workID, v.checkBlockNumber)
+			prommetrics.AutomationStuckTransmits.WithLabelValues(prommetrics.StuckTransmitStateReleased).Inc()
+			// a transmit event that arrives later still updates the record
+			c.setRecord(workID, record{
+				upkeepID:              v.upkeepID,
+				checkBlockNumber:      v.checkBlockNumber,
+				isTransmissionPending: false,
+				transmitType:          common.UnknownEvent,
+			})
+		}
+	}
+
+	// forget stuck workIDs that are no longer pending on the reported block
+	for workID, checkBlock := range c.stuck {
+		if v, ok := c.cache.Get(workID); !ok || !v.isTransmissionPending || v.checkBlockNumber != checkBlock {
+			delete(c.stuck, workID)
+		}
+	}
+}
+
+// removeVisited removes the visited events of a workID
+func (c *coordinator) removeVisited(workID string) {
+	prefix := workID + "_"
+	for _, visitedID := range c.visited.Keys() {
+		if !strings.HasPrefix(visitedID, prefix) {
+			continue
+		}
+
+		c.visited.Delete(visitedID)
+		if err := c.store.DeleteVisited(visitedID); err != nil {
+			c.logger.Printf("failed to remove persisted coordinator visited event %s: %s", visitedID, err)
+		}
+	}
+}
+
 func (c *coordinator) visitedID(e common.TransmitEvent) string {
 	return fmt.Sprintf("%s_%x_%d", e.WorkID, e.TransactionHash, e.TransmitBlock)
 }
@@ -12,12 +12,14 @@ import (
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 
 	common "github.com/smartcontractkit/chainlink-common/pkg/types/automation"
 
 	"github.com/smartcontractkit/chainlink-automation/pkg/util"
 	"github.com/smartcontractkit/chainlink-automation/pkg/v3/config"
+	"github.com/smartcontractkit/chainlink-automation/pkg/v3/prommetrics"
 	"github.com/smartcontractkit/chainlink-automation/pkg/v3/types"
 )
 
@@ -555,6 +557,17 @@ func TestCoordinator_ShouldTransmit(t *testing.T) {
 	}
 }
 
+func TestCoordinator_InFlightWorkIDs(t *testing.T) {
+	c := NewCoordinator(nil, nil, config.OffchainConfig{}, log.New(io.Discard, "coordinator_test", 0))
+	assert.Empty(t, c.InFlightWorkIDs())
+
+	c.cache.Set("workID3", record{isTransmissionPending: true}, util.DefaultCacheExpiration)
+	c.cache.Set("workID2", record{isTransmissionPending: false}, util.DefaultCacheExpiration)
+	c.cache.Set("workID1", record{isTransmissionPending: true}, util.DefaultCacheExpiration)
+
+	assert.Equal(t, []string{"workID1", "workID3"}, c.InFlightWorkIDs())
+}
+
 func TestCoordinator_ShouldProcess(t *testing.T) {
 	for _, tc := range []struct {
 		name             string
@@ -1136,6 +1149,344 @@ func TestCoordinator_FilterProposals(t *testing.T) {
 	}
 }
 
+func TestCoordinator_Reorgs(t *testing.T) {
+	logTrigger := func(uid common.UpkeepIdentifier) types.UpkeepType {
+		return types.LogTrigger
+	}
+	conf := config.OffchainConfig{PerformLockoutWindow: 3600 * 1000, MinConfirmations: 2}
+
+	t.Run("a transmit in a block that is reorged out makes the workID processable again", func(t *testing.T) {
+		history := common.BlockHistory{{Number: 102, Hash: [32]byte{2}}, {Number: 101, Hash: [32]byte{1}}}
+		blocks := &mockBlockHistoryGetter{GetBlockHistoryFn: func() common.BlockHistory { return history }}
+
+		performed := common.TransmitEvent{Type: common.PerformEvent, WorkID: "workID1", TransactionHash: [32]byte{9}, CheckBlock: 100, TransmitBlock: 101, Confirmations: 2}
+		events := []common.TransmitEvent{performed}
+		eventProvider := &mockEventProvider{GetLatestEventsFn: func(ctx context.Context) ([]common.TransmitEvent, error) {
+			return events, nil
+		}}
+
+		c := NewCoordinatorWithStore(eventProvider, logTrigger, blocks, conf, nil, log.New(io.Discard, "", 0))
+		assert.True(t, c.Accept(common.ReportedUpkeep{WorkID: "workID1", Trigger: common.Trigger{BlockNumber: 100}}))
+
+		assert.NoError(t, c.checkEvents(context.Background()))
+		assert.False(t, c.ShouldProcess("workID1", common.UpkeepIdentifier{}, common.Trigger{}))
+
+		// the same chain does not change anything
+		assert.NoError(t, c.checkEvents(context.Background()))
+		assert.False(t, c.ShouldProcess("workID1", common.UpkeepIdentifier{}, common.Trigger{}))
+
+		// block 101 is replaced and the perform is no longer part of the chain
+		history = common.BlockHistory{{Number: 102, Hash: [32]byte{22}}, {Number: 101, Hash: [32]byte{11}}}
+		events = nil
+
+		assert.NoError(t, c.checkEvents(context.Background()))
+		assert.True(t, c.ShouldProcess("workID1", common.UpkeepIdentifier{}, common.Trigger{}))
+
+		v, ok := c.cache.Get("workID1")
+		assert.True(t, ok)
+		assert.Equal(t, record{checkBlockNumber: 100, transmitType: common.ReorgReportEvent}, v)
+
+		// the transaction is included again in the same block number
+		events = []common.TransmitEvent{performed}
+
+		assert.NoError(t, c.checkEvents(context.Background()))
+		assert.False(t, c.ShouldProcess("workID1", common.UpkeepIdentifier{}, common.Trigger{}))
+	})
+
+	t.Run("transmit blocks older than the block history are final", func(t *testing.T) {
+		history := common.BlockHistory{{Number: 101, Hash: [32]byte{1}}}
+		blocks := &mockBlockHistoryGetter{GetBlockHistoryFn: func() common.BlockHistory { return history }}
+		eventProvider := &mockEventProvider{GetLatestEventsFn: func(ctx context.Context) ([]common.TransmitEvent, error) {
+			return []common.TransmitEvent{{Type: common.PerformEvent, WorkID: "workID1", CheckBlock: 100, TransmitBlock: 101, Confirmations: 2}}, nil
+		}}
+
+		c := NewCoordinatorWithStore(eventProvider, logTrigger, blocks, conf, nil, log.New(io.Discard, "", 0))
+		c.Accept(common.ReportedUpkeep{WorkID: "workID1", Trigger: common.Trigger{BlockNumber: 100}})
+		assert.NoError(t, c.checkEvents(context.Background()))
+
+		history = common.BlockHistory{{Number: 300, Hash: [32]byte{3}}}
+
+		assert.NoError(t, c.checkEvents(context.Background()))
+		assert.False(t, c.ShouldProcess("workID1", common.UpkeepIdentifier{}, common.Trigger{}))
+	})
+
+	t.Run("the hash of a transmit block that is not in the block history yet is resolved on a later tick", func(t *testing.T) {
+		history := common.BlockHistory{{Number: 101, Hash: [32]byte{1}}}
+		blocks := &mockBlockHistoryGetter{GetBlockHistoryFn: func() common.BlockHistory { return history }}
+		eventProvider := &mockEventProvider{GetLatestEventsFn: func(ctx context.Context) ([]common.TransmitEvent, error) {
+			return []common.TransmitEvent{{Type: common.PerformEvent, WorkID: "workID1", CheckBlock: 100, TransmitBlock: 102, Confirmations: 2}}, nil
+		}}
+
+		c := NewCoordinatorWithStore(eventProvider, logTrigger, blocks, conf, nil, log.New(io.Discard, "", 0))
+		c.Accept(common.ReportedUpkeep{WorkID: "workID1", Trigger: common.Trigger{BlockNumber: 100}})
+		assert.NoError(t, c.checkEvents(context.Background()))
+
+		v, _ := c.cache.Get("workID1")
+		assert.Equal(t, [32]byte{}, v.transmitBlockHash)
+
+		history = common.BlockHistory{{Number: 102, Hash: [32]byte{2}}, {Number: 101, Hash: [32]byte{1}}}
+		assert.NoError(t, c.checkEvents(context.Background()))
+
+		v, _ = c.cache.Get("workID1")
+		assert.Equal(t, [32]byte{2}, v.transmitBlockHash)
+		assert.False(t, c.ShouldProcess("workID1", common.UpkeepIdentifier{}, common.Trigger{}))
+
+		// the resolved hash is used to detect a reorg
+		history = common.BlockHistory{{Number: 102, Hash: [32]byte{22}}, {Number: 101, Hash: [32]byte{1}}}
+		assert.NoError(t, c.checkEvents(context.Background()))
+		assert.True(t, c.ShouldProcess("workID1", common.UpkeepIdentifier{}, common.Trigger{}))
+	})
+
+	t.Run("transmits whose block left the block history before its hash was known are reported once", func(t *testing.T) {
+		history := common.BlockHistory{{Number: 101, Hash: [32]byte{1}}}
+		blocks := &mockBlockHistoryGetter{GetBlockHistoryFn: func() common.BlockHistory { return history }}
+		eventProvider := &mockEventProvider{GetLatestEventsFn: func(ctx context.Context) ([]common.TransmitEvent, error) {
+			return []common.TransmitEvent{{Type: common.PerformEvent, WorkID: "workID1", CheckBlock: 100, TransmitBlock: 102, Confirmations: 2}}, nil
+		}}
+
+		var logBuf bytes.Buffer
+		c := NewCoordinatorWithStore(eventProvider, logTrigger, blocks, conf, nil, log.New(&logBuf, "", 0))
+		c.Accept(common.ReportedUpkeep{WorkID: "workID1", Trigger: common.Trigger{BlockNumber: 100}})
+		assert.NoError(t, c.checkEvents(context.Background()))
+
+		before := testutil.ToFloat64(prommetrics.AutomationUncheckedTransmits)
+
+		history = common.BlockHistory{{Number: 300, Hash: [32]byte{3}}}
+		assert.NoError(t, c.checkEvents(context.Background()))
+		assert.NoError(t, c.checkEvents(context.Background()))
+
+		assert.Equal(t, before+1, testutil.ToFloat64(prommetrics.AutomationUncheckedTransmits))
+		assert.Equal(t, 1, strings.Count(logBuf.String(), "Transmit of workID workID1 in block 102 can't be checked for reorgs"))
+		assert.False(t, c.ShouldProcess("workID1", common.UpkeepIdentifier{}, common.Trigger{}))
+	})
+
+	t.Run("a reorg event from the provider makes a performed workID processable again", func(t *testing.T) {
+		events := []common.TransmitEvent{{Type: common.PerformEvent, WorkID: "workID1", TransactionHash: [32]byte{1}, CheckBlock: 100, TransmitBlock: 101, Confirmations: 2}}
+		eventProvider := &mockEventProvider{GetLatestEventsFn: func(ctx context.Context) ([]common.TransmitEvent, error) {
+			return events, nil
+		}}
+
+		c := NewCoordinator(eventProvider, logTrigger, conf, log.New(io.Discard, "", 0))
+		c.Accept(common.ReportedUpkeep{WorkID: "workID1", Trigger: common.Trigger{BlockNumber: 100}})
+		assert.NoError(t, c.checkEvents(context.Background()))
+		assert.False(t, c.ShouldProcess("workID1", common.UpkeepIdentifier{}, common.Trigger{}))
+
+		events = append(events, common.TransmitEvent{Type: common.ReorgReportEvent, WorkID: "workID1", TransactionHash: [32]byte{2}, CheckBlock: 100, TransmitBlock: 105, Confirmations: 2})
+
+		assert.NoError(t, c.checkEvents(context.Background()))
+		assert.True(t, c.ShouldProcess("workID1", common.UpkeepIdentifier{}, common.Trigger{}))
+	})
+}
+
+func TestCoordinator_StuckTransmits(t *testing.T) {
+	logTrigger := func(uid common.UpkeepIdentifier) types.UpkeepType {
+		return types.LogTrigger
+	}
+	noEvents := &mockEventProvider{GetLatestEventsFn: func(ctx context.Context) ([]common.TransmitEvent, error) {
+		return nil, nil
+	}}
+
+	for _, tc := range []struct {
+		name         string
+		conf         config.OffchainConfig
+		latest       common.BlockNumber
+		wantPending  bool
+		wantMessages []string
+	}{
+		{
+			name:        "detection is disabled by default",
+			conf:        config.OffchainConfig{MinConfirmations: 2},
+			latest:      1000,
+			wantPending: true,
+		},
+		{
+			name:        "a transmit within the inclusion window is not stuck",
+			conf:        config.OffchainConfig{MinConfirmations: 2, PendingTransmits: config.PendingTransmitsConfig{InclusionBlocks: 10}},
+			latest:      112,
+			wantPending: true,
+		},
+		{
+			name:         "a transmit outside the inclusion window is reported",
+			conf:         config.OffchainConfig{MinConfirmations: 2, PendingTransmits: config.PendingTransmitsConfig{InclusionBlocks: 10}},
+			latest:       113,
+			wantPending:  true,
+			wantMessages: []string{"Transmit of workID workID1 checked at block 100 is pending for 13 blocks; expected inclusion within 12 blocks"},
+		},
+		{
+			name:         "a stuck transmit is released if configured",
+			conf:         config.OffchainConfig{MinConfirmations: 2, PendingTransmits: config.PendingTransmitsConfig{InclusionBlocks: 10, ReleaseStuck: true}},
+			latest:       113,
+			wantPending:  false,
+			wantMessages: []string{"is pending for 13 blocks", "Releasing stuck transmit of workID workID1 checked at block 100"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var logBuf bytes.Buffer
+			blocks := &mockBlockHistoryGetter{GetBlockHistoryFn: func() common.BlockHistory {
+				return common.BlockHistory{{Number: tc.latest}, {Number: tc.latest - 1}}
+			}}
+
+			tc.conf.PerformLockoutWindow = 3600 * 1000
+			c := NewCoordinatorWithStore(noEvents, logTrigger, blocks, tc.conf, nil, log.New(&logBuf, "", 0))
+			c.Accept(common.ReportedUpkeep{WorkID: "workID1", Trigger: common.Trigger{BlockNumber: 100}})
+
+			// every stuck transmit is only reported once
+			assert.NoError(t, c.checkEvents(context.Background()))
+			assert.NoError(t, c.checkEvents(context.Background()))
+
+			assert.Equal(t, tc.wantPending, len(c.InFlightWorkIDs()) == 1)
+			assert.Equal(t, !tc.wantPending, c.ShouldProcess("workID1", common.UpkeepIdentifier{}, common.Trigger{}))
+			for _, message := range tc.wantMessages {
+				assert.Equal(t, 1, strings.Count(logBuf.String(), message))
+			}
+			if len(tc.wantMessages) == 0 {
+				assert.NotContains(t, logBuf.String(), "is pending for")
+			}
+		})
+	}
+}
+
+func TestCoordinator_Inspect(t *testing.T) {
+	upkeep1, upkeep2 := common.UpkeepIdentifier{1}, common.UpkeepIdentifier{2}
+	conditional := func(uid common.UpkeepIdentifier) types.UpkeepType {
+		return types.ConditionTrigger
+	}
+	eventProvider := &mockEventProvider{GetLatestEventsFn: func(ctx context.Context) ([]common.TransmitEvent, error) {
+		return []common.TransmitEvent{{Type: common.PerformEvent, UpkeepID: upkeep1, WorkID: "workID1", CheckBlock: 100, TransmitBlock: 102, Confirmations: 2}}, nil
+	}}
+
+	c := NewCoordinator(eventProvider, conditional, config.OffchainConfig{PerformLockoutWindow: 3600 * 1000, MinConfirmations: 2}, log.New(io.Discard, "", 0))
+	c.Accept(common.ReportedUpkeep{UpkeepID: upkeep1, WorkID: "workID1", Trigger: common.Trigger{BlockNumber: 100}})
+	c.Accept(common.ReportedUpkeep{UpkeepID: upkeep1, WorkID: "workID2", Trigger: common.Trigger{BlockNumber: 101}})
+	c.Accept(common.ReportedUpkeep{UpkeepID: upkeep2, WorkID: "workID3", Trigger: common.Trigger{BlockNumber: 101}})
+	assert.NoError(t, c.checkEvents(context.Background()))
+
+	state, ok := c.InspectWorkID("workID1")
+	assert.True(t, ok)
+	assert.Greater(t, state.RemainingLockout, 59*time.Minute)
+	state.RemainingLockout = 0
+	assert.Equal(t, WorkState{
+		WorkID:              "workID1",
+		UpkeepID:            upkeep1,
+		CheckBlockNumber:    100,
+		TransmitType:        common.PerformEvent,
+		TransmitBlockNumber: 102,
+	}, state)
+
+	_, ok = c.InspectWorkID("unknown")
+	assert.False(t, ok)
+
+	states := c.InspectUpkeep(upkeep1)
+	assert.Len(t, states, 2)
+	assert.Equal(t, "workID1", states[0].WorkID)
+	assert.Equal(t, "workID2", states[1].WorkID)
+	assert.True(t, states[1].IsTransmissionPending)
+
+	// conditional work is processed again from the perform block onwards
+	assert.False(t, c.ShouldProcess("workID1", upkeep1, common.Trigger{BlockNumber: 101}))
+	assert.True(t, c.ShouldProcess("workID1", upkeep1, common.Trigger{BlockNumber: 102}))
+	assert.False(t, c.ShouldProcess("workID2", upkeep1, common.Trigger{BlockNumber: 200}))
+}
+
+type mockBlockHistoryGetter struct {
+	GetBlockHistoryFn func() common.BlockHistory
+}
+
+func (g *mockBlockHistoryGetter) GetBlockHistory() common.BlockHistory {
+	return g.GetBlockHistoryFn()
+}
+
+func TestCoordinator_PushedEvents(t *testing.T) {
+	conf := config.OffchainConfig{PerformLockoutWindow: 3600 * 1000, MinConfirmations: 2}
+	conditional := func(uid common.UpkeepIdentifier) types.UpkeepType {
+		return types.ConditionTrigger
+	}
+	event := common.TransmitEvent{Type: common.PerformEvent, WorkID: "workID1", CheckBlock: 100, TransmitBlock: 101, Confirmations: 2}
+
+	t.Run("pushed events release the lockout without waiting for a poll", func(t *testing.T) {
+		ch := make(chan []common.TransmitEvent)
+		provider := &mockEventSubscriber{
+			mockEventProvider: mockEventProvider{GetLatestEventsFn: func(ctx context.Context) ([]common.TransmitEvent, error) {
+				return nil, nil
+			}},
+			ch: ch,
+		}
+
+		c := NewCoordinator(provider, conditional, conf, log.New(io.Discard, "", 0))
+		c.Accept(common.ReportedUpkeep{WorkID: "workID1", Trigger: common.Trigger{BlockNumber: 100}})
+
+		go func() {
+			assert.NoError(t, c.Start(context.Background()))
+		}()
+
+		ch <- []common.TransmitEvent{event}
+
+		assert.Eventually(t, func() bool {
+			return len(c.InFlightWorkIDs()) == 0
+		}, time.Second/2, 10*time.Millisecond)
+
+		assert.NoError(t, c.Close())
+		assert.Equal(t, 0, provider.polls())
+		assert.True(t, provider.unsubscribed)
+	})
+
+	t.Run("the coordinator falls back to polling when the subscription is closed", func(t *testing.T) {
+		ch := make(chan []common.TransmitEvent)
+		provider := &mockEventSubscriber{
+			mockEventProvider: mockEventProvider{GetLatestEventsFn: func(ctx context.Context) ([]common.TransmitEvent, error) {
+				return []common.TransmitEvent{event}, nil
+			}},
+			ch: ch,
+		}
+
+		c := NewCoordinator(provider, conditional, conf, log.New(io.Discard, "", 0))
+		c.Accept(common.ReportedUpkeep{WorkID: "workID1", Trigger: common.Trigger{BlockNumber: 100}})
+
+		go func() {
+			assert.NoError(t, c.Start(context.Background()))
+		}()
+
+		close(ch)
+
+		assert.Eventually(t, func() bool {
+			return len(c.InFlightWorkIDs()) == 0
+		}, time.Second/2, 10*time.Millisecond)
+
+		assert.NoError(t, c.Close())
+		assert.Positive(t, provider.polls())
+	})
+}
+
+type mockEventSubscriber struct {
+	mockEventProvider
+	mu           sync.Mutex
+	calls        int
+	ch           chan []common.TransmitEvent
+	unsubscribed bool
+}
+
+func (s *mockEventSubscriber) GetLatestEvents(ctx context.Context) ([]common.TransmitEvent, error) {
+	s.mu.Lock()
+	s.calls++
+	s.mu.Unlock()
+	return s.mockEventProvider.GetLatestEvents(ctx)
+}
+
+func (s *mockEventSubscriber) polls() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls
+}
+
+func (s *mockEventSubscriber) SubscribeTransmitEvents() (int, chan []common.TransmitEvent, error) {
+	return 1, s.ch, nil
+}
+
+func (s *mockEventSubscriber) UnsubscribeTransmitEvents(int) error {
+	s.unsubscribed = true
+	return nil
+}
+
 type mockEventProvider struct {
 	GetLatestEventsFn func(context.Context) ([]common.TransmitEvent, error)
 }
@@ -0,0 +1,88 @@
+package coordinator
+
+import (
+	"sort"
+	"time"
+
+	common "github.com/smartcontractkit/chainlink-common/pkg/types/automation"
+)
+
+// WorkState is a read-only view of the coordinator record of a workID
+type WorkState struct {
+	WorkID   string
+	UpkeepID common.UpkeepIdentifier
+	// IsTransmissionPending is true while a report with the workID was
+	// accepted and no transmit event was registered yet
+	IsTransmissionPending bool
+	// CheckBlockNumber is the check block of the latest accepted report or
+	// registered transmit event
+	CheckBlockNumber common.BlockNumber
+	// TransmitType and TransmitBlockNumber are set once a transmit event is
+	// registered
+	TransmitType        common.TransmitEventType
+	TransmitBlockNumber common.BlockNumber
+	// RemainingLockout is the time until the record expires and the workID is
+	// processed as if it was never seen. Zero if the record never expires.
+	RemainingLockout time.Duration
+}
+
+// Inspector provides read-only access to the coordinator state, such that
+// operators can find out why a workID is not processed
+type Inspector interface {
+	// InspectWorkID returns the state of a workID and false if the
+	// coordinator has no record of it
+	InspectWorkID(workID string) (WorkState, bool)
+	// InspectUpkeep returns the states of all workIDs of an upkeep ordered by
+	// workID
+	InspectUpkeep(upkeepID common.UpkeepIdentifier) []WorkState
+	// ShouldProcess returns true if work for the workID and trigger would be
+	// processed
+	ShouldProcess(workID string, upkeepID common.UpkeepIdentifier, trigger common.Trigger) bool
+}
+
+var _ Inspector = (*coordinator)(nil)
+
+func (c *coordinator) InspectWorkID(workID string) (WorkState, bool) {
+	v, expires, ok := c.cache.GetWithExpiration(workID)
+	if !ok {
+		return WorkState{}, false
+	}
+
+	return newWorkState(workID, v, expires), true
+}
+
+func (c *coordinator) InspectUpkeep(upkeepID common.UpkeepIdentifier) []WorkState {
+	var states []WorkState
+	for _, workID := range c.cache.Keys() {
+		v, expires, ok := c.cache.GetWithExpiration(workID)
+		if !ok || v.upkeepID != upkeepID {
+			continue
+		}
+		states = append(states, newWorkState(workID, v, expires))
+	}
+
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].WorkID < states[j].WorkID
+	})
+
+	return states
+}
+
+func newWorkState(workID string, v record, expires time.Time) WorkState {
+	state := WorkState{
+		WorkID:                workID,
+		UpkeepID:              v.upkeepID,
+		IsTransmissionPending: v.isTransmissionPending,
+		CheckBlockNumber:      v.checkBlockNumber,
+		TransmitType:          v.transmitType,
+		TransmitBlockNumber:   v.transmitBlockNumber,
+	}
+
+	if !expires.IsZero() {
+		if remaining := time.Until(expires); remaining > 0 {
+			state.RemainingLockout = remaining
+		}
+	}
+
+	return state
+}
@@ -0,0 +1,264 @@
+package coordinator
+
+import (
+	"sync"
+	"time"
+
+	common "github.com/smartcontractkit/chainlink-common/pkg/types/automation"
+
+	"github.com/smartcontractkit/chainlink-automation/pkg/util"
+)
+
+// Record is the persisted coordinator state of a single workID
+type Record struct {
+	WorkID                string                   `json:"workID"`
+	UpkeepID              common.UpkeepIdentifier  `json:"upkeepID"`
+	CheckBlockNumber      common.BlockNumber       `json:"checkBlockNumber"`
+	IsTransmissionPending bool                     `json:"isTransmissionPending"`
+	TransmitType          common.TransmitEventType `json:"transmitType"`
+	TransmitBlockNumber   common.BlockNumber       `json:"transmitBlockNumber"`
+	TransmitBlockHash     [32]byte                 `json:"transmitBlockHash"`
+	// Expires is the time after which the record is no longer relevant. A zero
+	// value never expires.
+	Expires time.Time `json:"expires"`
+}
+
+// VisitedEvent is the persisted marker of a transmit event that was already
+// processed by the coordinator
+type VisitedEvent struct {
+	ID string `json:"id"`
+	// Expires is the time after which the event marker is no longer relevant.
+	// A zero value never expires.
+	Expires time.Time `json:"expires"`
+}
+
+// Store persists the coordinator state such that a restarted coordinator can
+// restore pending transmits and performed workIDs. A store can be shared by
+// consecutive coordinators, i.e. across plugin instances, but not by
+// coordinators running at the same time.
+type Store interface {
+	// SetRecord adds or replaces the record of a workID
+	SetRecord(Record) error
+	// SetVisited adds or replaces a visited event marker
+	SetVisited(VisitedEvent) error
+	// DeleteVisited removes a visited event marker
+	DeleteVisited(id string) error
+	// Records returns all records that are not expired
+	Records() ([]Record, error)
+	// Visited returns all visited event markers that are not expired
+	Visited() ([]VisitedEvent, error)
+	// ClearExpired removes all records and visited event markers that expired
+	// before the provided time
+	ClearExpired(now time.Time) error
+}
+
+func isExpired(expires, now time.Time) bool {
+	return !expires.IsZero() && now.After(expires)
+}
+
+type memoryStore struct {
+	mu      sync.RWMutex
+	records map[string]Record
+	visited map[string]VisitedEvent
+}
+
+var _ Store = (*memoryStore)(nil)
+
+// NewMemoryStore returns a Store that keeps the coordinator state in memory.
+// The state survives a restart of the coordinator, but not of the process.
+func NewMemoryStore() *memoryStore {
+	return &memoryStore{
+		records: make(map[string]Record),
+		visited: make(map[string]VisitedEvent),
+	}
+}
+
+func (s *memoryStore) SetRecord(r Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.records[r.WorkID] = r
+	return nil
+}
+
+func (s *memoryStore) SetVisited(v VisitedEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.visited[v.ID] = v
+	return nil
+}
+
+func (s *memoryStore) DeleteVisited(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.visited, id)
+	return nil
+}
+
+func (s *memoryStore) Records() ([]Record, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	records := make([]Record, 0, len(s.records))
+	for _, r := range s.records {
+		if !isExpired(r.Expires, now) {
+			records = append(records, r)
+		}
+	}
+	return records, nil
+}
+
+func (s *memoryStore) Visited() ([]VisitedEvent, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	visited := make([]VisitedEvent, 0, len(s.visited))
+	for _, v := range s.visited {
+		if !isExpired(v.Expires, now) {
+			visited = append(visited, v)
+		}
+	}
+	return visited, nil
+}
+
+func (s *memoryStore) ClearExpired(now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for workID, r := range s.records {
+		if isExpired(r.Expires, now) {
+			delete(s.records, workID)
+		}
+	}
+	for id, v := range s.visited {
+		if isExpired(v.Expires, now) {
+			delete(s.visited, id)
+		}
+	}
+	return nil
+}
+
+func (s *memoryStore) size() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.records) + len(s.visited)
+}
+
+// FileStoreVersion is the version of the log written by a FileStore. A change
+// to the format of the log entries requires a new version.
+const FileStoreVersion = 1
+
+// fileStoreEntry is a single line in the append only log of a file store
+type fileStoreEntry struct {
+	Record         *Record       `json:"record,omitempty"`
+	Visited        *VisitedEvent `json:"visited,omitempty"`
+	DeletedVisited string        `json:"deletedVisited,omitempty"`
+}
+
+// FileStore is a Store that persists the coordinator state in an append only
+// log of JSON lines. The log is compacted when the store is opened and when
+// outdated lines outnumber the live entries.
+type FileStore struct {
+	log    *util.JSONLog[fileStoreEntry]
+	memory *memoryStore
+}
+
+var _ Store = (*FileStore)(nil)
+
+// NewFileStore opens or creates the store at the provided path and loads the
+// persisted state. Lines that cannot be decoded, e.g. a partially written last
+// line after a crash, are dropped. A log of another version is an error.
+func NewFileStore(path string) (*FileStore, error) {
+	s := &FileStore{
+		memory: NewMemoryStore(),
+	}
+	s.log = util.NewJSONLog[fileStoreEntry](path, "coordinator store", FileStoreVersion, s.entries, s.memory.size)
+
+	if err := s.log.Load(s.apply); err != nil {
+		return nil, err
+	}
+
+	if err := s.log.Compact(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// apply applies a line of the log to the loaded state
+func (s *FileStore) apply(entry fileStoreEntry) {
+	if entry.Record != nil {
+		_ = s.memory.SetRecord(*entry.Record)
+	}
+	if entry.Visited != nil {
+		_ = s.memory.SetVisited(*entry.Visited)
+	}
+	if entry.DeletedVisited != "" {
+		_ = s.memory.DeleteVisited(entry.DeletedVisited)
+	}
+}
+
+// entries returns the lines that describe the state that is not expired
+func (s *FileStore) entries() []fileStoreEntry {
+	_ = s.memory.ClearExpired(time.Now())
+
+	records, _ := s.memory.Records()
+	visited, _ := s.memory.Visited()
+
+	entries := make([]fileStoreEntry, 0, len(records)+len(visited))
+	for i := range records {
+		entries = append(entries, fileStoreEntry{Record: &records[i]})
+	}
+	for i := range visited {
+		entries = append(entries, fileStoreEntry{Visited: &visited[i]})
+	}
+
+	return entries
+}
+
+func (s *FileStore) SetRecord(r Record) error {
+	return s.log.Write(fileStoreEntry{Record: &r}, func() error {
+		return s.memory.SetRecord(r)
+	})
+}
+
+func (s *FileStore) SetVisited(v VisitedEvent) error {
+	return s.log.Write(fileStoreEntry{Visited: &v}, func() error {
+		return s.memory.SetVisited(v)
+	})
+}
+
+func (s *FileStore) DeleteVisited(id string) error {
+	return s.log.Write(fileStoreEntry{DeletedVisited: id}, func() error {
+		return s.memory.DeleteVisited(id)
+	})
+}
+
+func (s *FileStore) Records() ([]Record, error) {
+	return s.memory.Records()
+}
+
+func (s *FileStore) Visited() ([]VisitedEvent, error) {
+	return s.memory.Visited()
+}
+
+// ClearExpired removes the expired state from the loaded state. The expired
+// lines are only removed from the log once they outnumber the live entries.
+func (s *FileStore) ClearExpired(now time.Time) error {
+	if err := s.memory.ClearExpired(now); err != nil {
+		return err
+	}
+
+	return s.log.CompactIfOutdated()
+}
+
+// Close closes the underlying file. The store cannot be written after it is
+// closed.
+func (s *FileStore) Close() error {
+	return s.log.Close()
+}
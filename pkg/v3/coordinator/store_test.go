@@ -0,0 +1,174 @@
+package coordinator
+
+import (
+	"context"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	common "github.com/smartcontractkit/chainlink-common/pkg/types/automation"
+
+	"github.com/smartcontractkit/chainlink-automation/pkg/util"
+	"github.com/smartcontractkit/chainlink-automation/pkg/v3/config"
+	"github.com/smartcontractkit/chainlink-automation/pkg/v3/types"
+)
+
+func TestMemoryStore_ClearExpired(t *testing.T) {
+	now := time.Now()
+	s := NewMemoryStore()
+
+	require.NoError(t, s.SetRecord(Record{WorkID: "expired", Expires: now.Add(-time.Second)}))
+	require.NoError(t, s.SetRecord(Record{WorkID: "live", Expires: now.Add(time.Hour)}))
+	require.NoError(t, s.SetRecord(Record{WorkID: "forever"}))
+	require.NoError(t, s.SetVisited(VisitedEvent{ID: "expired", Expires: now.Add(-time.Second)}))
+	require.NoError(t, s.SetVisited(VisitedEvent{ID: "live", Expires: now.Add(time.Hour)}))
+
+	records, err := s.Records()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"live", "forever"}, recordIDs(records))
+
+	require.NoError(t, s.ClearExpired(now))
+	assert.Len(t, s.records, 2)
+	assert.Len(t, s.visited, 1)
+}
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "coordinator.jsonl")
+	expires := time.Now().Add(time.Hour).UTC()
+
+	s, err := NewFileStore(path)
+	require.NoError(t, err)
+
+	require.NoError(t, s.SetRecord(Record{WorkID: "workID1", CheckBlockNumber: 100, IsTransmissionPending: true, Expires: expires}))
+	require.NoError(t, s.SetRecord(Record{WorkID: "workID1", CheckBlockNumber: 100, TransmitType: common.PerformEvent, TransmitBlockNumber: 101, Expires: expires}))
+	require.NoError(t, s.SetRecord(Record{WorkID: "workID2", Expires: time.Now().Add(-time.Second)}))
+	require.NoError(t, s.SetVisited(VisitedEvent{ID: "event1", Expires: expires}))
+	require.NoError(t, s.Close())
+
+	// simulate a crash in the middle of writing a line
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"record":{"workID":"work`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	s, err = NewFileStore(path)
+	require.NoError(t, err)
+	defer s.Close()
+
+	records, err := s.Records()
+	require.NoError(t, err)
+	assert.Equal(t, []Record{{WorkID: "workID1", CheckBlockNumber: 100, TransmitType: common.PerformEvent, TransmitBlockNumber: 101, Expires: expires}}, records)
+
+	visited, err := s.Visited()
+	require.NoError(t, err)
+	assert.Equal(t, []VisitedEvent{{ID: "event1", Expires: expires}}, visited)
+
+	// the log is compacted when opened, the first line holds the version
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 3, countLines(b))
+
+	// writes after compaction are appended to the compacted log
+	require.NoError(t, s.SetVisited(VisitedEvent{ID: "event2"}))
+	b, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 4, countLines(b))
+}
+
+func TestFileStore_CompactsOutdatedLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "coordinator.jsonl")
+	now := time.Now()
+
+	s, err := NewFileStore(path)
+	require.NoError(t, err)
+	defer s.Close()
+
+	require.NoError(t, s.SetRecord(Record{WorkID: "workID1", Expires: now.Add(time.Hour)}))
+	require.NoError(t, s.SetRecord(Record{WorkID: "workID2", Expires: now.Add(time.Second)}))
+
+	// clearing expired state does not rewrite a small log
+	require.NoError(t, s.ClearExpired(now.Add(time.Minute)))
+	records, err := s.Records()
+	require.NoError(t, err)
+	assert.Len(t, records, 1)
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 3, countLines(b))
+
+	// the log is compacted once outdated lines outnumber the live entries
+	for i := 0; i < util.MinCompactionLines+2; i++ {
+		require.NoError(t, s.SetRecord(Record{WorkID: "workID1", CheckBlockNumber: common.BlockNumber(i), Expires: now.Add(time.Hour)}))
+	}
+
+	b, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Less(t, countLines(b), util.MinCompactionLines)
+}
+
+func TestCoordinator_RestoresStateOnStart(t *testing.T) {
+	logger := log.New(io.Discard, "coordinator_test", 0)
+	conf := config.OffchainConfig{PerformLockoutWindow: 3600 * 1000, MinConfirmations: 2}
+	upkeepTypeGetter := func(uid common.UpkeepIdentifier) types.UpkeepType {
+		return types.LogTrigger
+	}
+
+	store, err := NewFileStore(filepath.Join(t.TempDir(), "coordinator.jsonl"))
+	require.NoError(t, err)
+	defer store.Close()
+
+	pending := common.ReportedUpkeep{WorkID: "pending", Trigger: common.Trigger{BlockNumber: 100}}
+	performed := common.ReportedUpkeep{WorkID: "performed", Trigger: common.Trigger{BlockNumber: 100}}
+	performedEvent := common.TransmitEvent{Type: common.PerformEvent, WorkID: "performed", CheckBlock: 100, TransmitBlock: 101, Confirmations: 2}
+
+	c := NewCoordinatorWithStore(&mockEventProvider{
+		GetLatestEventsFn: func(ctx context.Context) ([]common.TransmitEvent, error) {
+			return []common.TransmitEvent{performedEvent}, nil
+		},
+	}, upkeepTypeGetter, nil, conf, store, logger)
+
+	assert.True(t, c.Accept(pending))
+	assert.True(t, c.Accept(performed))
+	require.NoError(t, c.checkEvents(context.Background()))
+
+	// a new coordinator with the same store continues where the previous left
+	restarted := NewCoordinatorWithStore(&mockEventProvider{
+		GetLatestEventsFn: func(ctx context.Context) ([]common.TransmitEvent, error) {
+			return []common.TransmitEvent{performedEvent}, nil
+		},
+	}, upkeepTypeGetter, nil, conf, store, logger)
+	require.NoError(t, restarted.restore())
+
+	assert.True(t, restarted.ShouldTransmit(pending))
+	assert.Equal(t, []string{"pending"}, restarted.InFlightWorkIDs())
+	assert.False(t, restarted.ShouldProcess("performed", common.UpkeepIdentifier{}, common.Trigger{BlockNumber: 100}))
+
+	// the visited event is not processed again
+	_, ok := restarted.visited.Get(restarted.visitedID(performedEvent))
+	assert.True(t, ok)
+}
+
+func recordIDs(records []Record) []string {
+	ids := make([]string, len(records))
+	for i, r := range records {
+		ids[i] = r.WorkID
+	}
+	return ids
+}
+
+func countLines(b []byte) int {
+	var n int
+	for _, c := range b {
+		if c == '\n' {
+			n++
+		}
+	}
+	return n
+}
@@ -26,7 +26,7 @@ const (
 	// This is the ticker interval for final conditional flow
 	FinalConditionalInterval = 1 * time.Second
 	// These are the maximum number of conditional upkeeps dequeued on every tick from proposal queue in FinalConditionalFlow
-	// This is kept same as OutcomeSurfacedProposalsLimit as those many can get enqueued by plugin in every round
+	// This is kept same as the default OutcomeSurfacedProposalsLimit as those many can get enqueued by plugin in every round
 	FinalConditionalBatchSize = 50
 )
 
@@ -112,6 +112,7 @@ func newFinalConditionalFlow(
 	runner ocr2keepersv3.Runner,
 	interval time.Duration,
 	proposalQ types.ProposalQueue,
+	batchSize int,
 	builder common.PayloadBuilder,
 	retryQ types.RetryQueue,
 	stateUpdater common.UpkeepStateUpdater,
@@ -138,7 +139,7 @@ func newFinalConditionalFlow(
 			builder:   builder,
 			q:         proposalQ,
 			utype:     types.ConditionTrigger,
-			batchSize: FinalConditionalBatchSize,
+			batchSize: batchSize,
 		}, nil
 	}, log.New(logger.Writer(), fmt.Sprintf("[%s | conditional-final-ticker]", telemetry.ServiceName), telemetry.LogPkgStdFlags))
 
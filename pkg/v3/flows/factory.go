@@ -10,6 +10,9 @@ import (
 	common "github.com/smartcontractkit/chainlink-common/pkg/types/automation"
 )
 
+// ConditionalTriggerFlows creates the flows that sample conditional upkeeps
+// and check the agreed conditional proposals. Up to FinalConditionalBatchSize
+// proposals are dequeued on every tick of the final flow.
 func ConditionalTriggerFlows(
 	coord ocr2keepersv3.PreProcessor[common.UpkeepPayload],
 	ratio types.Ratio,
@@ -23,11 +26,31 @@ func ConditionalTriggerFlows(
 	retryQ types.RetryQueue,
 	stateUpdater common.UpkeepStateUpdater,
 	logger *log.Logger,
+) []service.Recoverable {
+	return ConditionalTriggerFlowsWithBatchSize(coord, ratio, getter, subscriber, builder, resultStore, metadataStore, runner, proposalQ, FinalConditionalBatchSize, retryQ, stateUpdater, logger)
+}
+
+// ConditionalTriggerFlowsWithBatchSize is the same as ConditionalTriggerFlows
+// but dequeues up to finalBatchSize proposals on every tick of the final flow.
+func ConditionalTriggerFlowsWithBatchSize(
+	coord ocr2keepersv3.PreProcessor[common.UpkeepPayload],
+	ratio types.Ratio,
+	getter common.ConditionalUpkeepProvider,
+	subscriber common.BlockSubscriber,
+	builder common.PayloadBuilder,
+	resultStore types.ResultStore,
+	metadataStore types.MetadataStore,
+	runner ocr2keepersv3.Runner,
+	proposalQ types.ProposalQueue,
+	finalBatchSize int,
+	retryQ types.RetryQueue,
+	stateUpdater common.UpkeepStateUpdater,
+	logger *log.Logger,
 ) []service.Recoverable {
 	preprocessors := []ocr2keepersv3.PreProcessor[common.UpkeepPayload]{coord}
 
 	// runs full check pipeline on a coordinated block with coordinated upkeeps
-	conditionalFinal := newFinalConditionalFlow(preprocessors, resultStore, runner, FinalConditionalInterval, proposalQ, builder, retryQ, stateUpdater, logger)
+	conditionalFinal := newFinalConditionalFlow(preprocessors, resultStore, runner, FinalConditionalInterval, proposalQ, finalBatchSize, builder, retryQ, stateUpdater, logger)
 
 	// the sampling proposal flow takes random samples of active upkeeps, checks
 	// them and surfaces the ids if the items are eligible
@@ -36,6 +59,9 @@ func ConditionalTriggerFlows(
 	return []service.Recoverable{conditionalFinal, conditionalProposal}
 }
 
+// LogTriggerFlows creates the log trigger and recovery flows. Up to
+// FinalRecoveryBatchSize recovery proposals are dequeued on every tick of the
+// final recovery flow.
 func LogTriggerFlows(
 	coord ocr2keepersv3.PreProcessor[common.UpkeepPayload],
 	resultStore types.ResultStore,
@@ -51,6 +77,29 @@ func LogTriggerFlows(
 	proposals types.ProposalQueue,
 	stateUpdater common.UpkeepStateUpdater,
 	logger *log.Logger,
+) []service.Recoverable {
+	return LogTriggerFlowsWithBatchSize(coord, resultStore, metadataStore, runner, logProvider, rp, builder, logInterval, recoveryProposalInterval, recoveryFinalInterval, retryQ, proposals, FinalRecoveryBatchSize, stateUpdater, logger)
+}
+
+// LogTriggerFlowsWithBatchSize is the same as LogTriggerFlows but dequeues up
+// to finalBatchSize recovery proposals on every tick of the final recovery
+// flow.
+func LogTriggerFlowsWithBatchSize(
+	coord ocr2keepersv3.PreProcessor[common.UpkeepPayload],
+	resultStore types.ResultStore,
+	metadataStore types.MetadataStore,
+	runner ocr2keepersv3.Runner,
+	logProvider common.LogEventProvider,
+	rp common.RecoverableProvider,
+	builder common.PayloadBuilder,
+	logInterval time.Duration,
+	recoveryProposalInterval time.Duration,
+	recoveryFinalInterval time.Duration,
+	retryQ types.RetryQueue,
+	proposals types.ProposalQueue,
+	finalBatchSize int,
+	stateUpdater common.UpkeepStateUpdater,
+	logger *log.Logger,
 ) []service.Recoverable {
 	// all flows use the same preprocessor based on the coordinator
 	// each flow can add preprocessors to this provided slice
@@ -65,7 +114,7 @@ func LogTriggerFlows(
 	// blocks and runs the pipeline for them. these values to run are derived
 	// from node coordination and it can be assumed that all values should be
 	// run.
-	rcvFinal := newFinalRecoveryFlow(preprocessors, resultStore, runner, retryQ, recoveryFinalInterval, proposals, builder, stateUpdater, logger)
+	rcvFinal := newFinalRecoveryFlow(preprocessors, resultStore, runner, retryQ, recoveryFinalInterval, proposals, finalBatchSize, builder, stateUpdater, logger)
 
 	// the log trigger flow is the happy path for log trigger payloads. all
 	// retryables that are encountered in this flow are elevated to the retry
@@ -11,6 +11,7 @@ import (
 	ocr2keepersv3 "github.com/smartcontractkit/chainlink-automation/pkg/v3"
 	"github.com/smartcontractkit/chainlink-automation/pkg/v3/postprocessors"
 	"github.com/smartcontractkit/chainlink-automation/pkg/v3/preprocessors"
+	"github.com/smartcontractkit/chainlink-automation/pkg/v3/prommetrics"
 	"github.com/smartcontractkit/chainlink-automation/pkg/v3/service"
 	"github.com/smartcontractkit/chainlink-automation/pkg/v3/telemetry"
 	"github.com/smartcontractkit/chainlink-automation/pkg/v3/tickers"
@@ -21,7 +22,7 @@ const (
 	// This is the ticker interval for recovery final flow
 	RecoveryFinalInterval = 1 * time.Second
 	// These are the maximum number of log upkeeps dequeued on every tick from proposal queue in FinalRecoveryFlow
-	// This is kept same as OutcomeSurfacedProposalsLimit as those many can get enqueued by plugin in every round
+	// This is kept same as the default OutcomeSurfacedProposalsLimit as those many can get enqueued by plugin in every round
 	FinalRecoveryBatchSize = 50
 	// This is the ticker interval for recovery proposal flow
 	RecoveryProposalInterval = 1 * time.Second
@@ -34,6 +35,7 @@ func newFinalRecoveryFlow(
 	retryQ types.RetryQueue,
 	recoveryFinalizationInterval time.Duration,
 	proposalQ types.ProposalQueue,
+	batchSize int,
 	builder common.PayloadBuilder,
 	stateUpdater common.UpkeepStateUpdater,
 	logger *log.Logger,
@@ -60,7 +62,7 @@ func newFinalRecoveryFlow(
 			builder:   builder,
 			q:         proposalQ,
 			utype:     types.LogTrigger,
-			batchSize: FinalRecoveryBatchSize,
+			batchSize: batchSize,
 		}, nil
 	}, log.New(logger.Writer(), fmt.Sprintf("[%s | recovery-final-ticker]", telemetry.ServiceName), telemetry.LogPkgStdFlags))
 
@@ -89,9 +91,11 @@ func (t coordinatedProposalsTick) Value(ctx context.Context) ([]common.UpkeepPay
 
 	builtPayloads, err := t.builder.BuildPayloads(ctx, proposals...)
 	if err != nil {
+		t.requeue(proposals)
 		return nil, fmt.Errorf("failed to build payloads from proposals: %w", err)
 	}
 	payloads := []common.UpkeepPayload{}
+	built := make(map[string]struct{}, len(builtPayloads))
 	filtered := 0
 	for _, p := range builtPayloads {
 		if p.IsEmpty() {
@@ -99,11 +103,44 @@ func (t coordinatedProposalsTick) Value(ctx context.Context) ([]common.UpkeepPay
 			continue
 		}
 		payloads = append(payloads, p)
+		built[p.WorkID] = struct{}{}
 	}
 	t.logger.Printf("%d payloads built from %d proposals, %d filtered", len(payloads), len(proposals), filtered)
+
+	var failed []common.CoordinatedBlockProposal
+	for _, proposal := range proposals {
+		if _, ok := built[proposal.WorkID]; !ok {
+			failed = append(failed, proposal)
+		}
+	}
+	t.requeue(failed)
+
 	return payloads, nil
 }
 
+// requeue returns proposals that failed to build to the proposal queue such
+// that they are built again on a later tick
+func (t coordinatedProposalsTick) requeue(proposals []common.CoordinatedBlockProposal) {
+	if len(proposals) == 0 {
+		return
+	}
+
+	prommetrics.AutomationProposalBuildFailures.WithLabelValues(upkeepTypeLabel(t.utype)).Add(float64(len(proposals)))
+
+	if err := t.q.Requeue(proposals...); err != nil {
+		t.logger.Printf("failed to requeue %d proposals: %s", len(proposals), err)
+		return
+	}
+	t.logger.Printf("%d proposals failed to build and were requeued", len(proposals))
+}
+
+func upkeepTypeLabel(t types.UpkeepType) string {
+	if t == types.LogTrigger {
+		return prommetrics.UpkeepTypeLogTrigger
+	}
+	return prommetrics.UpkeepTypeConditional
+}
+
 func newRecoveryProposalFlow(
 	preProcessors []ocr2keepersv3.PreProcessor[common.UpkeepPayload],
 	runner ocr2keepersv3.Runner,
@@ -2,16 +2,20 @@ package flows
 
 import (
 	"context"
+	"errors"
 	"io"
 	"log"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 
 	ocr2keepersv3 "github.com/smartcontractkit/chainlink-automation/pkg/v3"
+	"github.com/smartcontractkit/chainlink-automation/pkg/v3/prommetrics"
 	"github.com/smartcontractkit/chainlink-automation/pkg/v3/service"
 	"github.com/smartcontractkit/chainlink-automation/pkg/v3/stores"
 	"github.com/smartcontractkit/chainlink-automation/pkg/v3/types"
@@ -81,7 +85,7 @@ func TestRecoveryFinalization(t *testing.T) {
 	// set the ticker time lower to reduce the test time
 	recFinalInterval := 50 * time.Millisecond
 	pre := []ocr2keepersv3.PreProcessor[common.UpkeepPayload]{coord}
-	svc := newFinalRecoveryFlow(pre, rStore, runner, retryQ, recFinalInterval, proposalQ, payloadBuilder, upkeepStateUpdater, logger)
+	svc := newFinalRecoveryFlow(pre, rStore, runner, retryQ, recFinalInterval, proposalQ, FinalRecoveryBatchSize, payloadBuilder, upkeepStateUpdater, logger)
 
 	var wg sync.WaitGroup
 	wg.Add(1)
@@ -112,6 +116,55 @@ func TestRecoveryFinalization(t *testing.T) {
 	wg.Wait()
 }
 
+func TestCoordinatedProposalsTick_RequeuesFailedBuilds(t *testing.T) {
+	proposals := []common.CoordinatedBlockProposal{
+		{UpkeepID: common.UpkeepIdentifier([32]byte{1}), WorkID: "0x1"},
+		{UpkeepID: common.UpkeepIdentifier([32]byte{2}), WorkID: "0x2"},
+	}
+
+	payloadBuilder := new(mocks.MockPayloadBuilder)
+	proposalQ := stores.NewProposalQueue(func(ui common.UpkeepIdentifier) types.UpkeepType {
+		return types.LogTrigger
+	})
+	failures := prommetrics.AutomationProposalBuildFailures.WithLabelValues(prommetrics.UpkeepTypeLogTrigger)
+	initialFailures := testutil.ToFloat64(failures)
+
+	tick := coordinatedProposalsTick{
+		logger:    log.New(io.Discard, "", 0),
+		builder:   payloadBuilder,
+		q:         proposalQ,
+		utype:     types.LogTrigger,
+		batchSize: 10,
+	}
+
+	require.NoError(t, proposalQ.Enqueue(proposals...))
+
+	// all proposals go back to the queue when the builder fails
+	payloadBuilder.On("BuildPayloads", mock.Anything, mock.Anything, mock.Anything).Return(nil, errors.New("rpc down")).Once()
+	_, err := tick.Value(context.Background())
+	require.ErrorContains(t, err, "rpc down")
+	assert.Equal(t, 2, proposalQ.Size())
+
+	// proposals with empty payloads go back to the queue
+	payloadBuilder.On("BuildPayloads", mock.Anything, mock.Anything, mock.Anything).Return([]common.UpkeepPayload{
+		{UpkeepID: proposals[0].UpkeepID, WorkID: "0x1"},
+		{},
+	}, nil).Once()
+	payloads, err := tick.Value(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []common.UpkeepPayload{{UpkeepID: proposals[0].UpkeepID, WorkID: "0x1"}}, payloads)
+	assert.Equal(t, 1, proposalQ.Size())
+	assert.Equal(t, float64(3), testutil.ToFloat64(failures)-initialFailures)
+
+	// a proposal is dropped once it used up its requeues
+	payloadBuilder.On("BuildPayloads", mock.Anything, mock.Anything).Return([]common.UpkeepPayload{{}}, nil)
+	for i := 0; i < stores.MaxProposalRequeues; i++ {
+		_, err = tick.Value(context.Background())
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 0, proposalQ.Size())
+}
+
 func TestRecoveryProposal(t *testing.T) {
 	upkeepIDs := []common.UpkeepIdentifier{
 		common.UpkeepIdentifier([32]byte{1}),
@@ -1,22 +1,22 @@
 package ocr2keepers
 
 import (
+	"errors"
 	"fmt"
 	"math/big"
 
 	"github.com/goccy/go-json"
+	"github.com/smartcontractkit/chainlink-automation/pkg/v3/config"
 	"github.com/smartcontractkit/chainlink-automation/pkg/v3/types"
 	ocr2keepers "github.com/smartcontractkit/chainlink-common/pkg/types/automation"
 )
 
 // NOTE: Any change to these values should keep backwards compatibility in mind
 // as different nodes would upgrade at different times and would need to
-// adhere to each others' limits
+// adhere to each others' limits. Limits on the number of performables and
+// proposals are part of the offchain config, see config.ProtocolLimits
 const (
-	ObservationPerformablesLimit          = 100
-	ObservationLogRecoveryProposalsLimit  = 5
-	ObservationConditionalsProposalsLimit = 5
-	ObservationBlockHistoryLimit          = 256
+	ObservationBlockHistoryLimit = 256
 
 	// MaxObservationLength applies a limit to the total length of bytes in an
 	// observation. NOTE: This is derived from a limit of 10000 on performData
@@ -24,6 +24,28 @@ const (
 	MaxObservationLength = 1_000_000
 )
 
+// ErrInvalidObservation matches the errors returned for observations that
+// could be decoded but contain invalid values or exceed the protocol limits
+var ErrInvalidObservation = errors.New("invalid observation")
+
+// invalidObservationError keeps the message of a validation error while
+// allowing callers to tell it apart from decoding errors
+type invalidObservationError struct {
+	err error
+}
+
+func (e invalidObservationError) Error() string {
+	return e.err.Error()
+}
+
+func (e invalidObservationError) Unwrap() error {
+	return e.err
+}
+
+func (e invalidObservationError) Is(target error) bool {
+	return target == ErrInvalidObservation
+}
+
 var uint256Max, _ = big.NewInt(0).SetString("115792089237316195423570985008687907853269984665640564039457584007913129639935", 10)
 
 // AutomationObservation models the local automation view sent by a single node
@@ -42,24 +64,80 @@ type AutomationObservation struct {
 	BlockHistory ocr2keepers.BlockHistory
 }
 
+// Encode produces a json encoded array of bytes, the encoding understood by
+// all releases. Possible errors come from the encoding/json package
 func (observation AutomationObservation) Encode() ([]byte, error) {
-	return json.Marshal(observation)
+	return observation.EncodeWithCodec(config.CodecVersionJSON)
 }
 
-func DecodeAutomationObservation(data []byte, utg types.UpkeepTypeGetter, wg types.WorkIDGenerator) (AutomationObservation, error) {
-	ao := AutomationObservation{}
-	err := json.Unmarshal(data, &ao)
+// EncodeWithCodec encodes the observation with the codec selected by the
+// offchain config. The binary codec prefixes the encoding with the binary
+// codec version and delta compresses the block history, see
+// writeCompactBlockHistory.
+func (observation AutomationObservation) EncodeWithCodec(codecVersion uint8) ([]byte, error) {
+	switch codecVersion {
+	case config.CodecVersionJSON:
+		return json.Marshal(observation)
+	case config.CodecVersionBinary:
+		e := newBinaryEncoder(codecVersionV2)
+		e.writeCheckResults(observation.Performable)
+		e.writeProposals(observation.UpkeepProposals)
+		e.writeCompactBlockHistory(observation.BlockHistory)
+		return e.bytes(), nil
+	default:
+		return nil, fmt.Errorf("unsupported codec version %d", codecVersion)
+	}
+}
+
+// DecodeAutomationObservation decodes an AutomationObservation from an encoded
+// array of bytes. Both the binary encoding and the legacy json encoding are
+// accepted so that nodes running different releases understand each other.
+// The decoded observation is validated against the provided protocol limits,
+// validation failures match ErrInvalidObservation.
+func DecodeAutomationObservation(data []byte, utg types.UpkeepTypeGetter, wg types.WorkIDGenerator, limits config.ProtocolLimits) (AutomationObservation, error) {
+	ao, err := decodeAutomationObservation(data)
 	if err != nil {
 		return AutomationObservation{}, err
 	}
-	err = validateAutomationObservation(ao, utg, wg)
+	err = validateAutomationObservation(ao, utg, wg, limits)
 	if err != nil {
-		return AutomationObservation{}, err
+		return AutomationObservation{}, invalidObservationError{err: err}
+	}
+	return ao, nil
+}
+
+func decodeAutomationObservation(data []byte) (AutomationObservation, error) {
+	ao := AutomationObservation{}
+	if len(data) == 0 {
+		return ao, errEmptyEncoding
+	}
+	if isLegacyJSON(data) {
+		err := json.Unmarshal(data, &ao)
+		return ao, err
+	}
+
+	d := newBinaryDecoder(data)
+	switch version := d.readByte(); version {
+	case codecVersionV1:
+		ao.Performable = d.readCheckResults()
+		ao.UpkeepProposals = d.readProposals()
+		ao.BlockHistory = d.readBlockHistory()
+		d.finish()
+	case codecVersionV2:
+		ao.Performable = d.readCheckResults()
+		ao.UpkeepProposals = d.readProposals()
+		ao.BlockHistory = d.readCompactBlockHistory()
+		d.finish()
+	default:
+		return AutomationObservation{}, fmt.Errorf("unsupported observation encoding version %d", version)
+	}
+	if d.err != nil {
+		return AutomationObservation{}, d.err
 	}
 	return ao, nil
 }
 
-func validateAutomationObservation(o AutomationObservation, utg types.UpkeepTypeGetter, wg types.WorkIDGenerator) error {
+func validateAutomationObservation(o AutomationObservation, utg types.UpkeepTypeGetter, wg types.WorkIDGenerator, limits config.ProtocolLimits) error {
 	// Validate Block History
 	if len(o.BlockHistory) > ObservationBlockHistoryLimit {
 		return fmt.Errorf("block history length cannot be greater than %d", ObservationBlockHistoryLimit)
@@ -74,8 +152,8 @@ func validateAutomationObservation(o AutomationObservation, utg types.UpkeepType
 	}
 
 	// Validate Performables
-	if (len(o.Performable)) > ObservationPerformablesLimit {
-		return fmt.Errorf("performable length cannot be greater than %d", ObservationPerformablesLimit)
+	if (len(o.Performable)) > limits.ObservationPerformablesLimit {
+		return fmt.Errorf("performable length cannot be greater than %d", limits.ObservationPerformablesLimit)
 	}
 	seenPerformables := make(map[string]bool)
 	for _, res := range o.Performable {
@@ -90,8 +168,8 @@ func validateAutomationObservation(o AutomationObservation, utg types.UpkeepType
 
 	// Validate Proposals
 	if (len(o.UpkeepProposals)) >
-		(ObservationConditionalsProposalsLimit + ObservationLogRecoveryProposalsLimit) {
-		return fmt.Errorf("upkeep proposals length cannot be greater than %d", ObservationConditionalsProposalsLimit+ObservationLogRecoveryProposalsLimit)
+		(limits.ObservationConditionalsProposalsLimit + limits.ObservationLogRecoveryProposalsLimit) {
+		return fmt.Errorf("upkeep proposals length cannot be greater than %d", limits.ObservationConditionalsProposalsLimit+limits.ObservationLogRecoveryProposalsLimit)
 	}
 	conditionalProposalCount := 0
 	logProposalCount := 0
@@ -110,11 +188,11 @@ func validateAutomationObservation(o AutomationObservation, utg types.UpkeepType
 			logProposalCount++
 		}
 	}
-	if conditionalProposalCount > ObservationConditionalsProposalsLimit {
-		return fmt.Errorf("conditional upkeep proposals length cannot be greater than %d", ObservationConditionalsProposalsLimit)
+	if conditionalProposalCount > limits.ObservationConditionalsProposalsLimit {
+		return fmt.Errorf("conditional upkeep proposals length cannot be greater than %d", limits.ObservationConditionalsProposalsLimit)
 	}
-	if logProposalCount > ObservationLogRecoveryProposalsLimit {
-		return fmt.Errorf("log upkeep proposals length cannot be greater than %d", ObservationLogRecoveryProposalsLimit)
+	if logProposalCount > limits.ObservationLogRecoveryProposalsLimit {
+		return fmt.Errorf("log upkeep proposals length cannot be greater than %d", limits.ObservationLogRecoveryProposalsLimit)
 	}
 
 	return nil
@@ -0,0 +1,237 @@
+package ocr2keepers
+
+import (
+	"encoding/binary"
+	"math"
+	"strings"
+
+	"github.com/goccy/go-json"
+
+	"github.com/smartcontractkit/chainlink-automation/pkg/v3/config"
+	ocr2keepers "github.com/smartcontractkit/chainlink-common/pkg/types/automation"
+)
+
+// ObservationSection identifies a part of an observation that is given its own
+// share of the observation size budget. Sections are listed in the order in
+// which they are added to an observation.
+type ObservationSection int
+
+const (
+	ObservationSectionBlockHistory ObservationSection = iota
+	ObservationSectionLogProposals
+	ObservationSectionConditionalProposals
+	ObservationSectionPerformables
+	observationSectionCount
+)
+
+const (
+	// observationEncodingOverhead is the size of the codec version and the
+	// length prefixes of the performables, proposals and block history lists
+	observationEncodingOverhead = 1 + 3*binary.MaxVarintLen32
+	// reservedBlockKeySize is the largest encoded size of a block in the block
+	// history, which is that of a block starting a new run of consecutive blocks
+	reservedBlockKeySize = binary.MaxVarintLen64 + 1 + 32
+	// reservedProposalSize is the encoded size of a log trigger proposal with a
+	// 32 byte hex encoded workID. Proposals are sized individually when added,
+	// this size is only used to reserve budget for the proposal sections.
+	reservedProposalSize = 32 + 2*binary.MaxVarintLen64 + 1 + 3*32 + binary.MaxVarintLen32 + 1 + 64
+)
+
+var (
+	// jsonObservationEncodingOverhead is the size of a json encoded
+	// observation without any items
+	jsonObservationEncodingOverhead = jsonSize(AutomationObservation{})
+	// jsonReservedBlockKeySize is the largest json encoded size of a block in
+	// the block history, including the separator
+	jsonReservedBlockKeySize = jsonSize(ocr2keepers.BlockKey{Number: math.MaxUint64, Hash: maxHash}) + 1
+	// jsonReservedProposalSize is the json encoded size of a log trigger
+	// proposal with a 32 byte hex encoded workID, including the separator
+	jsonReservedProposalSize = jsonSize(ocr2keepers.CoordinatedBlockProposal{
+		UpkeepID: maxHash,
+		Trigger: ocr2keepers.Trigger{
+			BlockNumber: math.MaxUint64,
+			BlockHash:   maxHash,
+			LogTriggerExtension: &ocr2keepers.LogTriggerExtension{
+				TxHash:      maxHash,
+				Index:       math.MaxUint32,
+				BlockHash:   maxHash,
+				BlockNumber: math.MaxUint64,
+			},
+		},
+		WorkID: strings.Repeat("f", 64),
+	}) + 1
+
+	maxHash = [32]byte{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255}
+)
+
+// ObservationBudget allocates the maximum observation length over the sections
+// of an observation. Block history and proposals have part of the budget
+// reserved, derived from their limits, while performables get the rest. The
+// part of a reservation that is not used by a section is available to the
+// sections that follow it, so that items are only left out when the
+// observation as a whole would be too large.
+// Items are sized individually as they are added, without encoding the whole
+// observation, in the encoding of the configured codec. A nil budget places no
+// limit on the observation size.
+type ObservationBudget struct {
+	codecVersion uint8
+	reserved     [observationSectionCount]int
+	used         [observationSectionCount]int
+}
+
+// NewObservationBudget creates a budget for observations of at most maxLength
+// bytes given the configured protocol limits and codec version.
+func NewObservationBudget(maxLength int, limits config.ProtocolLimits, codecVersion uint8) *ObservationBudget {
+	b := &ObservationBudget{codecVersion: codecVersion}
+
+	overhead, blockKeySize, proposalSize := observationEncodingOverhead, reservedBlockKeySize, reservedProposalSize
+	if codecVersion == config.CodecVersionJSON {
+		overhead, blockKeySize, proposalSize = jsonObservationEncodingOverhead, jsonReservedBlockKeySize, jsonReservedProposalSize
+	}
+
+	remaining := maxLength - overhead
+	reserve := func(section ObservationSection, size int) {
+		if size > remaining {
+			size = remaining
+		}
+		if size < 0 {
+			size = 0
+		}
+		b.reserved[section] = size
+		remaining -= size
+	}
+
+	reserve(ObservationSectionBlockHistory, ObservationBlockHistoryLimit*blockKeySize)
+	reserve(ObservationSectionLogProposals, limits.ObservationLogRecoveryProposalsLimit*proposalSize)
+	reserve(ObservationSectionConditionalProposals, limits.ObservationConditionalsProposalsLimit*proposalSize)
+	reserve(ObservationSectionPerformables, remaining)
+
+	return b
+}
+
+// Available returns the number of bytes that can still be added to a section.
+// This is the reservation of the section and all sections before it, minus
+// what these sections already used.
+func (b *ObservationBudget) Available(section ObservationSection) int {
+	available := 0
+	for s := ObservationSection(0); s <= section && s < observationSectionCount; s++ {
+		available += b.reserved[s] - b.used[s]
+	}
+	return available
+}
+
+// Used returns the number of bytes used by items in all sections
+func (b *ObservationBudget) Used() int {
+	used := 0
+	for _, u := range b.used {
+		used += u
+	}
+	return used
+}
+
+// add reserves size bytes in the section if available
+func (b *ObservationBudget) add(section ObservationSection, size int) bool {
+	if b == nil {
+		return true
+	}
+	if size > b.Available(section) {
+		return false
+	}
+	b.used[section] += size
+	return true
+}
+
+// TakeBlockHistory returns the longest prefix of the block history that fits
+// in the block history section and accounts for its size. With the binary
+// codec, blocks that continue a run of consecutive block numbers only take the
+// size of their hash, as the block history is delta compressed when encoded.
+func (b *ObservationBudget) TakeBlockHistory(history ocr2keepers.BlockHistory) ocr2keepers.BlockHistory {
+	if b != nil && b.codecVersion == config.CodecVersionJSON {
+		for i, block := range history {
+			if !b.add(ObservationSectionBlockHistory, jsonSize(block)+1) {
+				return history[:i]
+			}
+		}
+		return history
+	}
+
+	runStart, ascending := 0, false
+	for i, block := range history {
+		size := len(block.Hash)
+		switch {
+		case i > 0 && i == runStart+1 && block.Number == history[runStart].Number+1:
+			ascending = true
+		case i > 0 && block.Number == nextInRun(history[i-1].Number, ascending):
+		default:
+			runStart, ascending = i, false
+			size += uvarintSize(uint64(block.Number))
+		}
+		// the run header holds the run length, so it grows as the run does
+		runLength := uint64(i - runStart + 1)
+		size += uvarintSize(runLength<<1) - uvarintSize((runLength-1)<<1)
+		if runLength == 1 {
+			size += uvarintSize(0)
+		}
+
+		if !b.add(ObservationSectionBlockHistory, size) {
+			return history[:i]
+		}
+	}
+	return history
+}
+
+// TakeProposals returns the longest prefix of the proposals that fits in the
+// provided proposals section and accounts for its size.
+func (b *ObservationBudget) TakeProposals(section ObservationSection, proposals []ocr2keepers.CoordinatedBlockProposal) []ocr2keepers.CoordinatedBlockProposal {
+	for i, proposal := range proposals {
+		if !b.add(section, b.itemSize(proposal, func(e *binaryEncoder) { e.writeProposal(proposal) })) {
+			return proposals[:i]
+		}
+	}
+	return proposals
+}
+
+// TakePerformables returns the longest prefix of the results that fits in the
+// performables section and accounts for its size.
+func (b *ObservationBudget) TakePerformables(results []ocr2keepers.CheckResult) []ocr2keepers.CheckResult {
+	for i, result := range results {
+		if !b.add(ObservationSectionPerformables, b.itemSize(result, func(e *binaryEncoder) { e.writeCheckResult(result) })) {
+			return results[:i]
+		}
+	}
+	return results
+}
+
+// itemSize returns the encoded size of an item of a list in the encoding of
+// the budget's codec. The json size includes the separator between items.
+func (b *ObservationBudget) itemSize(item any, write func(e *binaryEncoder)) int {
+	if b == nil {
+		return 0
+	}
+	if b.codecVersion == config.CodecVersionJSON {
+		return jsonSize(item) + 1
+	}
+	return encodedSize(write)
+}
+
+// jsonSize returns the json encoded size of v, or a size that exceeds any
+// budget if v cannot be encoded
+func jsonSize(v any) int {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return math.MaxInt32
+	}
+	return len(b)
+}
+
+// uvarintSize returns the encoded size of v
+func uvarintSize(v uint64) int {
+	return encodedSize(func(e *binaryEncoder) { e.writeUvarint(v) })
+}
+
+// encodedSize returns the number of bytes written by the provided function
+func encodedSize(write func(e *binaryEncoder)) int {
+	e := &binaryEncoder{}
+	write(e)
+	return len(e.buf)
+}
@@ -0,0 +1,89 @@
+package ocr2keepers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/smartcontractkit/chainlink-automation/pkg/v3/config"
+	commontypes "github.com/smartcontractkit/chainlink-common/pkg/types/automation"
+)
+
+func TestObservationBudget(t *testing.T) {
+	limits := config.DefaultProtocolLimitsV0
+
+	t.Run("items are sized as they are encoded", func(t *testing.T) {
+		budget := NewObservationBudget(MaxObservationLength, limits, config.CodecVersionBinary)
+
+		observation := AutomationObservation{
+			BlockHistory:    budget.TakeBlockHistory(validBlockHistory),
+			UpkeepProposals: budget.TakeProposals(ObservationSectionLogProposals, []commontypes.CoordinatedBlockProposal{validLogProposal}),
+			Performable:     budget.TakePerformables([]commontypes.CheckResult{validConditionalResult, validLogResult}),
+		}
+
+		encoded, err := observation.EncodeWithCodec(config.CodecVersionBinary)
+		assert.NoError(t, err)
+		// version byte and one byte length prefix for each list
+		assert.Equal(t, len(encoded), budget.Used()+4)
+	})
+
+	t.Run("items are sized as they are json encoded", func(t *testing.T) {
+		budget := NewObservationBudget(MaxObservationLength, limits, config.CodecVersionJSON)
+		assert.Equal(t, MaxObservationLength-jsonObservationEncodingOverhead, budget.Available(ObservationSectionPerformables))
+
+		observation := AutomationObservation{
+			BlockHistory:    budget.TakeBlockHistory(validBlockHistory),
+			UpkeepProposals: budget.TakeProposals(ObservationSectionLogProposals, []commontypes.CoordinatedBlockProposal{validLogProposal}),
+			Performable:     budget.TakePerformables([]commontypes.CheckResult{validConditionalResult, validLogResult}),
+		}
+
+		encoded, err := observation.Encode()
+		assert.NoError(t, err)
+		// the budget accounts for a separator after every item and the
+		// overhead for null lists, which is never less than the encoding
+		assert.LessOrEqual(t, len(encoded), budget.Used()+jsonObservationEncodingOverhead)
+		assert.Greater(t, len(encoded), budget.Used())
+	})
+
+	t.Run("unused reservations flow to later sections", func(t *testing.T) {
+		budget := NewObservationBudget(MaxObservationLength, limits, config.CodecVersionBinary)
+
+		assert.Equal(t, MaxObservationLength-observationEncodingOverhead, budget.Available(ObservationSectionPerformables))
+
+		budget.TakeBlockHistory(validBlockHistory)
+		assert.Equal(t, MaxObservationLength-observationEncodingOverhead-budget.Used(), budget.Available(ObservationSectionPerformables))
+	})
+
+	t.Run("reservations of later sections are not used by earlier sections", func(t *testing.T) {
+		budget := NewObservationBudget(MaxObservationLength, limits, config.CodecVersionBinary)
+
+		var history commontypes.BlockHistory
+		for i := 0; i < 2*ObservationBlockHistoryLimit; i++ {
+			history = append(history, commontypes.BlockKey{Number: commontypes.BlockNumber(^uint64(0)), Hash: [32]byte{1}})
+		}
+
+		taken := budget.TakeBlockHistory(history)
+		assert.Len(t, taken, ObservationBlockHistoryLimit)
+		assert.Equal(t, 0, budget.Available(ObservationSectionBlockHistory))
+		assert.Equal(t, limits.ObservationLogRecoveryProposalsLimit*reservedProposalSize, budget.Available(ObservationSectionLogProposals))
+	})
+
+	t.Run("items are left out once the budget is exhausted", func(t *testing.T) {
+		proposalSize := encodedSize(func(e *binaryEncoder) { e.writeProposal(validConditionalProposal) })
+		budget := NewObservationBudget(observationEncodingOverhead+proposalSize+10, limits, config.CodecVersionBinary)
+
+		results := []commontypes.CheckResult{validConditionalResult, validLogResult}
+		assert.Empty(t, budget.TakeBlockHistory(commontypes.BlockHistory{}))
+		taken := budget.TakeProposals(ObservationSectionLogProposals, []commontypes.CoordinatedBlockProposal{validConditionalProposal, validLogProposal, validLogProposal})
+		assert.Len(t, taken, 1)
+		assert.Empty(t, budget.TakePerformables(results))
+	})
+
+	t.Run("a nil budget places no limit", func(t *testing.T) {
+		var budget *ObservationBudget
+
+		results := []commontypes.CheckResult{validConditionalResult, validLogResult}
+		assert.Equal(t, results, budget.TakePerformables(results))
+		assert.Equal(t, validBlockHistory, budget.TakeBlockHistory(validBlockHistory))
+	})
+}
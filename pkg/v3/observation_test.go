@@ -9,6 +9,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/smartcontractkit/chainlink-automation/pkg/v3/config"
 	"github.com/smartcontractkit/chainlink-automation/pkg/v3/types"
 	commontypes "github.com/smartcontractkit/chainlink-common/pkg/types/automation"
 )
@@ -77,9 +78,11 @@ var validObservation = AutomationObservation{
 	BlockHistory:    validBlockHistory,
 }
 var expectedEncodedObservation []byte
+var v1EncodedObservation []byte
+var legacyEncodedObservation []byte
 
 func init() {
-	b, err := os.ReadFile("fixtures/expected_encoded_observation.txt")
+	b, err := os.ReadFile("fixtures/expected_encoded_observation_v2.txt")
 	if err != nil {
 		panic(err)
 	}
@@ -87,20 +90,36 @@ func init() {
 	if err != nil {
 		panic(err)
 	}
+	b, err = os.ReadFile("fixtures/expected_encoded_observation_v1.txt")
+	if err != nil {
+		panic(err)
+	}
+	v1EncodedObservation, err = hex.DecodeString(string(b))
+	if err != nil {
+		panic(err)
+	}
+	b, err = os.ReadFile("fixtures/expected_encoded_observation.txt")
+	if err != nil {
+		panic(err)
+	}
+	legacyEncodedObservation, err = hex.DecodeString(string(b))
+	if err != nil {
+		panic(err)
+	}
 }
 
 func TestValidAutomationObservation(t *testing.T) {
 	encoded, err := validObservation.Encode()
 	assert.NoError(t, err, "no error in encoding valid automation observation")
 
-	decoded, err := DecodeAutomationObservation(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator)
+	decoded, err := DecodeAutomationObservation(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator, config.DefaultProtocolLimitsV0)
 	assert.NoError(t, err, "no error in decoding valid automation observation")
 
 	assert.Equal(t, validObservation, decoded, "final result from encoding and decoding should match")
 }
 
 func TestAutomationObservationEncodeBackwardsCompatibility(t *testing.T) {
-	encoded, err := validObservation.Encode()
+	encoded, err := validObservation.EncodeWithCodec(config.CodecVersionBinary)
 	assert.NoError(t, err, "no error in encoding valid automation observation")
 
 	if !bytes.Equal(encoded, expectedEncodedObservation) {
@@ -112,6 +131,117 @@ func TestAutomationObservationEncodeBackwardsCompatibility(t *testing.T) {
 	}
 }
 
+func TestAutomationObservationDecodeV1(t *testing.T) {
+	decoded, err := DecodeAutomationObservation(v1EncodedObservation, mockUpkeepTypeGetter, mockWorkIDGenerator, config.DefaultProtocolLimitsV0)
+	assert.NoError(t, err, "no error in decoding v1 automation observation")
+
+	assert.Equal(t, validObservation, decoded, "v1 observation should decode to the same observation")
+}
+
+func TestAutomationObservationCompactBlockHistory(t *testing.T) {
+	sequential := func(from, to int) commontypes.BlockHistory {
+		history := commontypes.BlockHistory{}
+		for n := from; ; {
+			history = append(history, commontypes.BlockKey{Number: commontypes.BlockNumber(n), Hash: [32]byte{byte(n)}})
+			if n == to {
+				return history
+			}
+			if from < to {
+				n++
+			} else {
+				n--
+			}
+		}
+	}
+
+	for _, tc := range []struct {
+		name    string
+		history commontypes.BlockHistory
+	}{
+		{name: "nil", history: nil},
+		{name: "empty", history: commontypes.BlockHistory{}},
+		{name: "single block", history: sequential(5, 5)},
+		{name: "descending", history: sequential(300, 45)},
+		{name: "ascending", history: sequential(45, 300)},
+		{name: "gaps", history: append(append(sequential(100, 90), sequential(80, 70)...), sequential(7, 9)...)},
+		{name: "unordered", history: commontypes.BlockHistory{{Number: 3}, {Number: 9}, {Number: 1}, {Number: 2}}},
+		{name: "down to block zero", history: sequential(3, 0)},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ao := AutomationObservation{BlockHistory: tc.history}
+			encoded, err := ao.EncodeWithCodec(config.CodecVersionBinary)
+			assert.NoError(t, err)
+
+			decoded, err := decodeAutomationObservation(encoded)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.history, decoded.BlockHistory)
+
+			budget := NewObservationBudget(MaxObservationLength, config.DefaultProtocolLimitsV0, config.CodecVersionBinary)
+			assert.Equal(t, tc.history, budget.TakeBlockHistory(tc.history))
+			// version byte, empty performables and proposals and the history length prefix
+			overhead := 3 + encodedSize(func(e *binaryEncoder) { e.writeLength(len(tc.history), tc.history == nil) })
+			assert.Equal(t, len(encoded)-overhead, budget.Used(), "budget accounts for the compact encoding")
+		})
+	}
+
+	t.Run("sequential blocks carry a single block number", func(t *testing.T) {
+		ao := AutomationObservation{BlockHistory: sequential(1_000_000+ObservationBlockHistoryLimit-1, 1_000_000)}
+		encoded, err := ao.EncodeWithCodec(config.CodecVersionBinary)
+		assert.NoError(t, err)
+
+		// version, empty performables and proposals, history length, run header
+		assert.Equal(t, 1+1+1+2+encodedSize(func(e *binaryEncoder) { e.writeUvarint(1_000_000 + ObservationBlockHistoryLimit - 1) })+2+ObservationBlockHistoryLimit*32, len(encoded))
+	})
+
+	t.Run("invalid runs are rejected", func(t *testing.T) {
+		for _, runs := range [][]uint64{
+			{5, 0},                 // empty run
+			{5, 4 << 1},            // run longer than the history
+			{1, 3 << 1},            // descending run below block zero
+			{^uint64(0), 3<<1 | 1}, // ascending run overflows
+		} {
+			e := newBinaryEncoder(codecVersionV2)
+			e.writeCheckResults(nil)
+			e.writeProposals(nil)
+			e.writeLength(3, false)
+			for _, v := range runs {
+				e.writeUvarint(v)
+			}
+			e.writeFixed(make([]byte, 3*32))
+
+			_, err := decodeAutomationObservation(e.bytes())
+			assert.ErrorContains(t, err, "block history run")
+		}
+	})
+}
+
+func TestAutomationObservationDecodeLegacyJSON(t *testing.T) {
+	decoded, err := DecodeAutomationObservation(legacyEncodedObservation, mockUpkeepTypeGetter, mockWorkIDGenerator, config.DefaultProtocolLimitsV0)
+	assert.NoError(t, err, "no error in decoding legacy json automation observation")
+
+	assert.Equal(t, validObservation, decoded, "legacy json observation should decode to the same observation")
+}
+
+func TestAutomationObservationDecodeInvalidEncoding(t *testing.T) {
+	encoded, err := validObservation.EncodeWithCodec(config.CodecVersionBinary)
+	assert.NoError(t, err, "no error in encoding valid automation observation")
+
+	_, err = DecodeAutomationObservation(nil, mockUpkeepTypeGetter, mockWorkIDGenerator, config.DefaultProtocolLimitsV0)
+	assert.ErrorContains(t, err, "empty encoding")
+
+	_, err = DecodeAutomationObservation(encoded[:len(encoded)-10], mockUpkeepTypeGetter, mockWorkIDGenerator, config.DefaultProtocolLimitsV0)
+	assert.Error(t, err, "truncated encoding cannot be decoded")
+
+	unknownVersion := append([]byte{}, encoded...)
+	unknownVersion[0] = 255
+	_, err = DecodeAutomationObservation(unknownVersion, mockUpkeepTypeGetter, mockWorkIDGenerator, config.DefaultProtocolLimitsV0)
+	assert.ErrorContains(t, err, "unsupported observation encoding version 255")
+
+	// trailing data would give the same observation more than one encoding
+	_, err = DecodeAutomationObservation(append(encoded, 1, 2, 3), mockUpkeepTypeGetter, mockWorkIDGenerator, config.DefaultProtocolLimitsV0)
+	assert.ErrorContains(t, err, "unexpected trailing bytes")
+}
+
 func TestLargeBlockHistory(t *testing.T) {
 	ao := AutomationObservation{
 		Performable:     []commontypes.CheckResult{validConditionalResult, validLogResult},
@@ -127,7 +257,7 @@ func TestLargeBlockHistory(t *testing.T) {
 	encoded, err := ao.Encode()
 	assert.NoError(t, err, "no error in encoding valid automation observation")
 
-	_, err = DecodeAutomationObservation(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator)
+	_, err = DecodeAutomationObservation(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator, config.DefaultProtocolLimitsV0)
 	assert.Error(t, err)
 	assert.ErrorContains(t, err, "block history length cannot be greater than")
 }
@@ -147,7 +277,7 @@ func TestDuplicateBlockHistory(t *testing.T) {
 	encoded, err := ao.Encode()
 	assert.NoError(t, err, "no error in encoding valid automation observation")
 
-	_, err = DecodeAutomationObservation(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator)
+	_, err = DecodeAutomationObservation(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator, config.DefaultProtocolLimitsV0)
 	assert.Error(t, err)
 	assert.ErrorContains(t, err, "block history cannot have duplicate block numbers")
 }
@@ -158,7 +288,7 @@ func TestLargePerformable(t *testing.T) {
 		UpkeepProposals: []commontypes.CoordinatedBlockProposal{validConditionalProposal, validLogProposal},
 		BlockHistory:    validBlockHistory,
 	}
-	for i := 0; i < ObservationPerformablesLimit+1; i++ {
+	for i := 0; i < config.DefaultProtocolLimitsV0.ObservationPerformablesLimit+1; i++ {
 		newConditionalResult := validConditionalResult
 		uid := commontypes.UpkeepIdentifier{}
 		uid.FromBigInt(big.NewInt(int64(i + 1)))
@@ -169,9 +299,15 @@ func TestLargePerformable(t *testing.T) {
 	encoded, err := ao.Encode()
 	assert.NoError(t, err, "no error in encoding valid automation observation")
 
-	_, err = DecodeAutomationObservation(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator)
+	_, err = DecodeAutomationObservation(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator, config.DefaultProtocolLimitsV0)
 	assert.Error(t, err)
 	assert.ErrorContains(t, err, "performable length cannot be greater than")
+
+	limits := config.DefaultProtocolLimitsV0
+	limits.ObservationPerformablesLimit = len(ao.Performable)
+	decoded, err := DecodeAutomationObservation(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator, limits)
+	assert.NoError(t, err, "configured limits are applied")
+	assert.Len(t, decoded.Performable, len(ao.Performable))
 }
 
 func TestDuplicatePerformable(t *testing.T) {
@@ -186,7 +322,7 @@ func TestDuplicatePerformable(t *testing.T) {
 	encoded, err := ao.Encode()
 	assert.NoError(t, err, "no error in encoding valid automation observation")
 
-	_, err = DecodeAutomationObservation(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator)
+	_, err = DecodeAutomationObservation(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator, config.DefaultProtocolLimitsV0)
 	assert.Error(t, err)
 	assert.ErrorContains(t, err, "performable cannot have duplicate workIDs")
 }
@@ -197,7 +333,7 @@ func TestLargeProposal(t *testing.T) {
 		UpkeepProposals: []commontypes.CoordinatedBlockProposal{},
 		BlockHistory:    validBlockHistory,
 	}
-	for i := 0; i < ObservationConditionalsProposalsLimit+ObservationLogRecoveryProposalsLimit+1; i++ {
+	for i := 0; i < config.DefaultProtocolLimitsV0.ObservationConditionalsProposalsLimit+config.DefaultProtocolLimitsV0.ObservationLogRecoveryProposalsLimit+1; i++ {
 		newProposal := validConditionalProposal
 		uid := commontypes.UpkeepIdentifier{}
 		uid.FromBigInt(big.NewInt(int64(i + 1)))
@@ -208,7 +344,7 @@ func TestLargeProposal(t *testing.T) {
 	encoded, err := ao.Encode()
 	assert.NoError(t, err, "no error in encoding valid automation observation")
 
-	_, err = DecodeAutomationObservation(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator)
+	_, err = DecodeAutomationObservation(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator, config.DefaultProtocolLimitsV0)
 	assert.Error(t, err)
 	assert.ErrorContains(t, err, "upkeep proposals length cannot be greater than")
 }
@@ -219,7 +355,7 @@ func TestLargeConditionalProposal(t *testing.T) {
 		UpkeepProposals: []commontypes.CoordinatedBlockProposal{},
 		BlockHistory:    validBlockHistory,
 	}
-	for i := 0; i < ObservationConditionalsProposalsLimit+1; i++ {
+	for i := 0; i < config.DefaultProtocolLimitsV0.ObservationConditionalsProposalsLimit+1; i++ {
 		newProposal := validConditionalProposal
 		uid := commontypes.UpkeepIdentifier{}
 		uid.FromBigInt(big.NewInt(int64(i + 1)))
@@ -230,7 +366,7 @@ func TestLargeConditionalProposal(t *testing.T) {
 	encoded, err := ao.Encode()
 	assert.NoError(t, err, "no error in encoding valid automation observation")
 
-	_, err = DecodeAutomationObservation(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator)
+	_, err = DecodeAutomationObservation(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator, config.DefaultProtocolLimitsV0)
 	assert.Error(t, err)
 	assert.ErrorContains(t, err, "conditional upkeep proposals length cannot be greater than")
 }
@@ -241,7 +377,7 @@ func TestLargeLogProposal(t *testing.T) {
 		UpkeepProposals: []commontypes.CoordinatedBlockProposal{},
 		BlockHistory:    validBlockHistory,
 	}
-	for i := 0; i < ObservationLogRecoveryProposalsLimit+1; i++ {
+	for i := 0; i < config.DefaultProtocolLimitsV0.ObservationLogRecoveryProposalsLimit+1; i++ {
 		newProposal := validLogProposal
 		uid := commontypes.UpkeepIdentifier{}
 		uid.FromBigInt(big.NewInt(int64(i + 1001)))
@@ -252,7 +388,7 @@ func TestLargeLogProposal(t *testing.T) {
 	encoded, err := ao.Encode()
 	assert.NoError(t, err, "no error in encoding valid automation observation")
 
-	_, err = DecodeAutomationObservation(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator)
+	_, err = DecodeAutomationObservation(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator, config.DefaultProtocolLimitsV0)
 	assert.Error(t, err)
 	assert.ErrorContains(t, err, "log upkeep proposals length cannot be greater than")
 }
@@ -270,7 +406,7 @@ func TestDuplicateProposal(t *testing.T) {
 	encoded, err := ao.Encode()
 	assert.NoError(t, err, "no error in encoding valid automation observation")
 
-	_, err = DecodeAutomationObservation(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator)
+	_, err = DecodeAutomationObservation(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator, config.DefaultProtocolLimitsV0)
 	assert.Error(t, err)
 	assert.ErrorContains(t, err, "proposals cannot have duplicate workIDs")
 }
@@ -287,7 +423,7 @@ func TestInvalidPipelineExecutionState(t *testing.T) {
 	encoded, err := ao.Encode()
 	assert.NoError(t, err, "no error in encoding valid automation observation")
 
-	_, err = DecodeAutomationObservation(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator)
+	_, err = DecodeAutomationObservation(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator, config.DefaultProtocolLimitsV0)
 	assert.Error(t, err)
 	assert.ErrorContains(t, err, "check result cannot have failed execution state")
 }
@@ -304,7 +440,7 @@ func TestInvalidRetryable(t *testing.T) {
 	encoded, err := ao.Encode()
 	assert.NoError(t, err, "no error in encoding valid automation observation")
 
-	_, err = DecodeAutomationObservation(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator)
+	_, err = DecodeAutomationObservation(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator, config.DefaultProtocolLimitsV0)
 	assert.Error(t, err)
 	assert.ErrorContains(t, err, "check result cannot have failed execution state")
 }
@@ -321,7 +457,7 @@ func TestInvalidEligibility(t *testing.T) {
 	encoded, err := ao.Encode()
 	assert.NoError(t, err, "no error in encoding valid automation observation")
 
-	_, err = DecodeAutomationObservation(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator)
+	_, err = DecodeAutomationObservation(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator, config.DefaultProtocolLimitsV0)
 	assert.Error(t, err)
 	assert.ErrorContains(t, err, "check result cannot be ineligible")
 }
@@ -338,7 +474,7 @@ func TestInvalidIneligibilityReason(t *testing.T) {
 	encoded, err := ao.Encode()
 	assert.NoError(t, err, "no error in encoding valid automation observation")
 
-	_, err = DecodeAutomationObservation(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator)
+	_, err = DecodeAutomationObservation(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator, config.DefaultProtocolLimitsV0)
 	assert.Error(t, err)
 	assert.ErrorContains(t, err, "check result cannot be ineligible")
 }
@@ -355,7 +491,7 @@ func TestInvalidTriggerTypeConditional(t *testing.T) {
 	encoded, err := ao.Encode()
 	assert.NoError(t, err, "no error in encoding valid automation observation")
 
-	_, err = DecodeAutomationObservation(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator)
+	_, err = DecodeAutomationObservation(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator, config.DefaultProtocolLimitsV0)
 	assert.Error(t, err)
 	assert.ErrorContains(t, err, "invalid trigger")
 }
@@ -372,7 +508,7 @@ func TestInvalidTriggerTypeLog(t *testing.T) {
 	encoded, err := ao.Encode()
 	assert.NoError(t, err, "no error in encoding valid automation observation")
 
-	_, err = DecodeAutomationObservation(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator)
+	_, err = DecodeAutomationObservation(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator, config.DefaultProtocolLimitsV0)
 	assert.Error(t, err)
 	assert.ErrorContains(t, err, "invalid trigger")
 }
@@ -389,7 +525,7 @@ func TestInvalidWorkID(t *testing.T) {
 	encoded, err := ao.Encode()
 	assert.NoError(t, err, "no error in encoding valid automation observation")
 
-	_, err = DecodeAutomationObservation(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator)
+	_, err = DecodeAutomationObservation(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator, config.DefaultProtocolLimitsV0)
 	assert.Error(t, err)
 	assert.ErrorContains(t, err, "incorrect workID within result")
 }
@@ -406,7 +542,7 @@ func TestInvalidGasAllocated(t *testing.T) {
 	encoded, err := ao.Encode()
 	assert.NoError(t, err, "no error in encoding valid automation observation")
 
-	_, err = DecodeAutomationObservation(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator)
+	_, err = DecodeAutomationObservation(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator, config.DefaultProtocolLimitsV0)
 	assert.Error(t, err)
 	assert.ErrorContains(t, err, "gas allocated cannot be zero")
 }
@@ -423,7 +559,7 @@ func TestNilFastGas(t *testing.T) {
 	encoded, err := ao.Encode()
 	assert.NoError(t, err, "no error in encoding valid automation observation")
 
-	_, err = DecodeAutomationObservation(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator)
+	_, err = DecodeAutomationObservation(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator, config.DefaultProtocolLimitsV0)
 	assert.Error(t, err)
 	assert.ErrorContains(t, err, "fast gas wei must be present")
 }
@@ -440,7 +576,7 @@ func TestInvalidFastGasNegative(t *testing.T) {
 	encoded, err := ao.Encode()
 	assert.NoError(t, err, "no error in encoding valid automation observation")
 
-	_, err = DecodeAutomationObservation(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator)
+	_, err = DecodeAutomationObservation(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator, config.DefaultProtocolLimitsV0)
 	assert.Error(t, err)
 	assert.ErrorContains(t, err, "fast gas wei must be in uint256 range")
 }
@@ -457,7 +593,7 @@ func TestInvalidFastGasTooBig(t *testing.T) {
 	encoded, err := ao.Encode()
 	assert.NoError(t, err, "no error in encoding valid automation observation")
 
-	_, err = DecodeAutomationObservation(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator)
+	_, err = DecodeAutomationObservation(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator, config.DefaultProtocolLimitsV0)
 	assert.Error(t, err)
 	assert.ErrorContains(t, err, "fast gas wei must be in uint256 range")
 }
@@ -474,7 +610,7 @@ func TestNilLinkNative(t *testing.T) {
 	encoded, err := ao.Encode()
 	assert.NoError(t, err, "no error in encoding valid automation observation")
 
-	_, err = DecodeAutomationObservation(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator)
+	_, err = DecodeAutomationObservation(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator, config.DefaultProtocolLimitsV0)
 	assert.Error(t, err)
 	assert.ErrorContains(t, err, "link native must be present")
 }
@@ -491,7 +627,7 @@ func TestInvalidLinkNativeNegative(t *testing.T) {
 	encoded, err := ao.Encode()
 	assert.NoError(t, err, "no error in encoding valid automation observation")
 
-	_, err = DecodeAutomationObservation(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator)
+	_, err = DecodeAutomationObservation(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator, config.DefaultProtocolLimitsV0)
 	assert.Error(t, err)
 	assert.ErrorContains(t, err, "link native must be in uint256 range")
 }
@@ -508,7 +644,7 @@ func TestInvalidLinkNativeTooBig(t *testing.T) {
 	encoded, err := ao.Encode()
 	assert.NoError(t, err, "no error in encoding valid automation observation")
 
-	_, err = DecodeAutomationObservation(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator)
+	_, err = DecodeAutomationObservation(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator, config.DefaultProtocolLimitsV0)
 	assert.Error(t, err)
 	assert.ErrorContains(t, err, "link native must be in uint256 range")
 }
@@ -525,7 +661,7 @@ func TestInvalidWorkIDProposal(t *testing.T) {
 	encoded, err := ao.Encode()
 	assert.NoError(t, err, "no error in encoding valid automation observation")
 
-	_, err = DecodeAutomationObservation(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator)
+	_, err = DecodeAutomationObservation(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator, config.DefaultProtocolLimitsV0)
 	assert.Error(t, err)
 	assert.ErrorContains(t, err, "incorrect workID within proposal")
 }
@@ -542,7 +678,7 @@ func TestInvalidConditionalProposal(t *testing.T) {
 	encoded, err := ao.Encode()
 	assert.NoError(t, err, "no error in encoding valid automation observation")
 
-	_, err = DecodeAutomationObservation(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator)
+	_, err = DecodeAutomationObservation(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator, config.DefaultProtocolLimitsV0)
 	assert.Error(t, err)
 	assert.ErrorContains(t, err, "log trigger extension cannot be present for condition upkeep")
 }
@@ -559,7 +695,7 @@ func TestInvalidLogProposal(t *testing.T) {
 	encoded, err := ao.Encode()
 	assert.NoError(t, err, "no error in encoding valid automation observation")
 
-	_, err = DecodeAutomationObservation(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator)
+	_, err = DecodeAutomationObservation(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator, config.DefaultProtocolLimitsV0)
 	assert.Error(t, err)
 	assert.ErrorContains(t, err, "log trigger extension cannot be empty for log upkeep")
 }
@@ -576,7 +712,7 @@ func TestObservationSizeWithEmptyPeformables(t *testing.T) {
 			Hash:   [32]byte{1},
 		})
 	}
-	for i := 0; i < ObservationConditionalsProposalsLimit; i++ {
+	for i := 0; i < config.DefaultProtocolLimitsV0.ObservationConditionalsProposalsLimit; i++ {
 		newProposal := validConditionalProposal
 		uid := commontypes.UpkeepIdentifier{}
 		uid.FromBigInt(big.NewInt(int64(i + 1)))
@@ -584,7 +720,7 @@ func TestObservationSizeWithEmptyPeformables(t *testing.T) {
 		newProposal.WorkID = mockWorkIDGenerator(newProposal.UpkeepID, newProposal.Trigger)
 		ao.UpkeepProposals = append(ao.UpkeepProposals, newProposal)
 	}
-	for i := 0; i < ObservationLogRecoveryProposalsLimit; i++ {
+	for i := 0; i < config.DefaultProtocolLimitsV0.ObservationLogRecoveryProposalsLimit; i++ {
 		newProposal := validLogProposal
 		uid := commontypes.UpkeepIdentifier{}
 		uid.FromBigInt(big.NewInt(int64(i + 1001)))
@@ -595,12 +731,16 @@ func TestObservationSizeWithEmptyPeformables(t *testing.T) {
 	encoded, err := ao.Encode()
 	assert.NoError(t, err, "no error in encoding valid automation observation")
 
-	decoded, err := DecodeAutomationObservation(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator)
+	decoded, err := DecodeAutomationObservation(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator, config.DefaultProtocolLimitsV0)
 	assert.NoError(t, err, "no error in decoding valid automation observation")
 
 	assert.Equal(t, ao, decoded, "final result from encoding and decoding should match")
 	assert.Less(t, len(encoded), MaxObservationLength, "encoded observation won't exceed maxObservationSize when perform data is moderately sized")
 	assert.Equal(t, 972320, MaxObservationLength-len(encoded), "we still have 972320 bytes of free space for performables")
+
+	encoded, err = ao.EncodeWithCodec(config.CodecVersionBinary)
+	assert.NoError(t, err, "no error in encoding valid automation observation")
+	assert.Equal(t, 990435, MaxObservationLength-len(encoded), "we still have 990435 bytes of free space for performables with the binary codec")
 }
 
 func mockUpkeepTypeGetter(id commontypes.UpkeepIdentifier) types.UpkeepType {
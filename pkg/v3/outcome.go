@@ -4,31 +4,25 @@ import (
 	"fmt"
 
 	"github.com/goccy/go-json"
+	"github.com/smartcontractkit/chainlink-automation/pkg/v3/config"
 	"github.com/smartcontractkit/chainlink-automation/pkg/v3/types"
 	ocr2keepers "github.com/smartcontractkit/chainlink-common/pkg/types/automation"
 )
 
 // NOTE: Any change to these values should keep backwards compatibility in mind
 // as different nodes would upgrade at different times and would need to
-// adhere to each others' limits
+// adhere to each others' limits. Limits on the number of performables and
+// proposals are part of the offchain config, see config.ProtocolLimits
 const (
-	OutcomeAgreedPerformablesLimit = 100
-	OutcomeSurfacedProposalsLimit  = 50
-	// NOTE: This should be enough number of rounds to encompass ObservationProcessLimit
-	// so that proposals have enough time to be processed before getting coordinated on
-	// a new block
-	OutcomeSurfacedProposalsRoundHistoryLimit = 20
-
 	// MaxOutcomeLength applies a limit to the total length of bytes in an outcome for
 	// a round. NOTE: This is derived from a limit of 10000 on performData
 	// which is guaranteed onchain
 	MaxOutcomeLength = 2_500_000
 	// MaxReportLength limits the total length of bytes for a single report.
 	MaxReportLength = 1_000_000
-	// MaxReportCount limits the total number of reports allowed to be produced
-	// by the OCR protocol in a single round. This should be at least the number
-	// of allowed agreed performables in a single round.
-	MaxReportCount = OutcomeAgreedPerformablesLimit
+	// OutcomeReportedHistoryRoundLimit is the number of rounds for which
+	// agreed performables are kept in the reported history of an outcome
+	OutcomeReportedHistoryRoundLimit = 10
 )
 
 // AutomationOutcome represents agreed upon state by the network, derived from
@@ -49,13 +43,28 @@ type AutomationOutcome struct {
 	// Quorum of f+1 is only applied on the blockNumber and blockHash of the proposal
 	// rest of the fields can be manipulated by malicious nodes
 	SurfacedProposals [][]ocr2keepers.CoordinatedBlockProposal
+	// These are the workIDs and check blocks of the performables agreed in the
+	// most recent rounds, latest round first. Transmissions take a while to be
+	// seen by the coordinators of the nodes, this history prevents the same
+	// result from being agreed again in the meantime
+	// The history is derived from the previous outcome and is empty for
+	// outcomes produced by previous releases. It is left out of json encoded
+	// outcomes when empty, such that outcomes without history are encoded the
+	// same as by previous releases, which ignore the field when decoding.
+	ReportedHistory [][]ReportedWork `json:",omitempty"`
+}
+
+// ReportedWork identifies an agreed performable by its workID and check block
+type ReportedWork struct {
+	WorkID     string
+	CheckBlock ocr2keepers.BlockNumber
 }
 
 // ValidateAutomationOutcome validates individual values in an AutomationOutcome
-func validateAutomationOutcome(o AutomationOutcome, utg types.UpkeepTypeGetter, wg types.WorkIDGenerator) error {
+func validateAutomationOutcome(o AutomationOutcome, utg types.UpkeepTypeGetter, wg types.WorkIDGenerator, limits config.ProtocolLimits) error {
 	// Validate AgreedPerformables
-	if (len(o.AgreedPerformables)) > OutcomeAgreedPerformablesLimit {
-		return fmt.Errorf("outcome performable length cannot be greater than %d", OutcomeAgreedPerformablesLimit)
+	if (len(o.AgreedPerformables)) > limits.OutcomeAgreedPerformablesLimit {
+		return fmt.Errorf("outcome performable length cannot be greater than %d", limits.OutcomeAgreedPerformablesLimit)
 	}
 	seenPerformables := make(map[string]bool)
 	for _, res := range o.AgreedPerformables {
@@ -70,13 +79,13 @@ func validateAutomationOutcome(o AutomationOutcome, utg types.UpkeepTypeGetter,
 
 	// Validate SurfacedProposals
 	if len(o.SurfacedProposals) >
-		OutcomeSurfacedProposalsRoundHistoryLimit {
-		return fmt.Errorf("number of rounds for surfaced proposals cannot be greater than %d", OutcomeSurfacedProposalsRoundHistoryLimit)
+		limits.OutcomeSurfacedProposalsRoundHistoryLimit {
+		return fmt.Errorf("number of rounds for surfaced proposals cannot be greater than %d", limits.OutcomeSurfacedProposalsRoundHistoryLimit)
 	}
 	seenProposals := make(map[string]bool)
 	for _, round := range o.SurfacedProposals {
-		if len(round) > OutcomeSurfacedProposalsLimit {
-			return fmt.Errorf("number of surfaced proposals in a round cannot be greater than %d", OutcomeSurfacedProposalsLimit)
+		if len(round) > limits.OutcomeSurfacedProposalsLimit {
+			return fmt.Errorf("number of surfaced proposals in a round cannot be greater than %d", limits.OutcomeSurfacedProposalsLimit)
 		}
 		for _, proposal := range round {
 			if err := validateUpkeepProposal(proposal, utg, wg); err != nil {
@@ -88,26 +97,113 @@ func validateAutomationOutcome(o AutomationOutcome, utg types.UpkeepTypeGetter,
 			seenProposals[proposal.WorkID] = true
 		}
 	}
+
+	// Validate ReportedHistory
+	if len(o.ReportedHistory) > OutcomeReportedHistoryRoundLimit {
+		return fmt.Errorf("number of rounds for reported history cannot be greater than %d", OutcomeReportedHistoryRoundLimit)
+	}
+	for _, round := range o.ReportedHistory {
+		if len(round) > limits.OutcomeAgreedPerformablesLimit {
+			return fmt.Errorf("number of reported workIDs in a round cannot be greater than %d", limits.OutcomeAgreedPerformablesLimit)
+		}
+		for _, reported := range round {
+			if reported.WorkID == "" {
+				return fmt.Errorf("reported workID cannot be empty")
+			}
+		}
+	}
 	return nil
 }
 
-// Encode produces a json encoded array of bytes. Possible errors come from the
-// encoding/json package
+// Encode produces a json encoded array of bytes, the encoding understood by
+// all releases. Possible errors come from the encoding/json package
 func (outcome AutomationOutcome) Encode() ([]byte, error) {
-	return json.Marshal(outcome)
+	return outcome.EncodeWithCodec(config.CodecVersionJSON)
+}
+
+// EncodeWithCodec encodes the outcome with the codec selected by the offchain
+// config. The json encoding leaves out the reported history, such that the
+// outcome matches the outcome of nodes running previous releases byte for
+// byte.
+func (outcome AutomationOutcome) EncodeWithCodec(codecVersion uint8) ([]byte, error) {
+	switch codecVersion {
+	case config.CodecVersionJSON:
+		return json.Marshal(outcome)
+	case config.CodecVersionBinary:
+		e := newBinaryEncoder(codecVersionV1)
+		e.writeCheckResults(outcome.AgreedPerformables)
+		e.writeLength(len(outcome.SurfacedProposals), outcome.SurfacedProposals == nil)
+		for _, round := range outcome.SurfacedProposals {
+			e.writeProposals(round)
+		}
+		// The reported history is a trailing section that decoders of previous
+		// releases ignore. It is left out entirely when there is no history.
+		if outcome.ReportedHistory != nil {
+			e.writeLength(len(outcome.ReportedHistory), false)
+			for _, round := range outcome.ReportedHistory {
+				e.writeReportedWork(round)
+			}
+		}
+		return e.bytes(), nil
+	default:
+		return nil, fmt.Errorf("unsupported codec version %d", codecVersion)
+	}
 }
 
 // DecodeAutomationOutcome decodes an AutomationOutcome from an encoded array
-// of bytes. Possible errors come from the encoding/json package
-func DecodeAutomationOutcome(data []byte, utg types.UpkeepTypeGetter, wg types.WorkIDGenerator) (AutomationOutcome, error) {
-	ao := AutomationOutcome{}
-	err := json.Unmarshal(data, &ao)
+// of bytes. Both the binary encoding and the legacy json encoding are accepted
+// so that nodes running different releases understand each other. The decoded
+// outcome is validated against the provided protocol limits.
+func DecodeAutomationOutcome(data []byte, utg types.UpkeepTypeGetter, wg types.WorkIDGenerator, limits config.ProtocolLimits) (AutomationOutcome, error) {
+	ao, err := decodeAutomationOutcome(data)
 	if err != nil {
 		return AutomationOutcome{}, err
 	}
-	err = validateAutomationOutcome(ao, utg, wg)
+	err = validateAutomationOutcome(ao, utg, wg, limits)
 	if err != nil {
 		return AutomationOutcome{}, err
 	}
 	return ao, err
 }
+
+func decodeAutomationOutcome(data []byte) (AutomationOutcome, error) {
+	ao := AutomationOutcome{}
+	if len(data) == 0 {
+		return ao, errEmptyEncoding
+	}
+	if isLegacyJSON(data) {
+		err := json.Unmarshal(data, &ao)
+		return ao, err
+	}
+
+	d := newBinaryDecoder(data)
+	switch version := d.readByte(); version {
+	case codecVersionV1:
+		ao.AgreedPerformables = d.readCheckResults()
+		if n, isNil := d.readLength(); !isNil {
+			ao.SurfacedProposals = make([][]ocr2keepers.CoordinatedBlockProposal, 0, n)
+			for i := 0; i < n && d.err == nil; i++ {
+				ao.SurfacedProposals = append(ao.SurfacedProposals, d.readProposals())
+			}
+		}
+		// the reported history section is optional but, when present, may not
+		// encode a nil history since that is already encoded by its absence
+		if d.err == nil && d.remaining() > 0 {
+			n, isNil := d.readLength()
+			if isNil {
+				d.fail(errNilSection)
+			}
+			ao.ReportedHistory = make([][]ReportedWork, 0, n)
+			for i := 0; i < n && d.err == nil; i++ {
+				ao.ReportedHistory = append(ao.ReportedHistory, d.readReportedWork())
+			}
+		}
+		d.finish()
+	default:
+		return AutomationOutcome{}, fmt.Errorf("unsupported outcome encoding version %d", version)
+	}
+	if d.err != nil {
+		return AutomationOutcome{}, d.err
+	}
+	return ao, nil
+}
@@ -0,0 +1,72 @@
+package ocr2keepers
+
+import (
+	"fmt"
+
+	"github.com/smartcontractkit/chainlink-automation/pkg/v3/config"
+)
+
+// EncodeOutcomeWithinLength encodes the outcome with the provided codec and
+// leaves out items until the encoding is at most maxLength bytes. The count
+// limits of the protocol bound the number of items, but not the size of an
+// outcome when items are large, e.g. performables with large perform data.
+// Items are left out in order of least importance: the oldest rounds of
+// surfaced proposals first, then the lowest priority agreed performables and
+// finally the new proposals of the latest round. Agreed performables that are
+// left out are removed from the latest round of the reported history too, such
+// that they can be agreed again in a later round. Every node leaves out the
+// same items, so the outcome remains deterministic.
+func EncodeOutcomeWithinLength(outcome *AutomationOutcome, maxLength int, codecVersion uint8) ([]byte, error) {
+	for {
+		encoded, err := outcome.EncodeWithCodec(codecVersion)
+		if err != nil || len(encoded) <= maxLength {
+			return encoded, err
+		}
+
+		excess := len(encoded) - maxLength
+		removed := false
+
+		for excess > 0 && len(outcome.SurfacedProposals) > 1 {
+			last := len(outcome.SurfacedProposals) - 1
+			round := outcome.SurfacedProposals[last]
+			excess -= outcomeItemSize(codecVersion, round, func(e *binaryEncoder) { e.writeProposals(round) })
+			outcome.SurfacedProposals = outcome.SurfacedProposals[:last]
+			removed = true
+		}
+
+		for excess > 0 && len(outcome.AgreedPerformables) > 0 {
+			last := len(outcome.AgreedPerformables) - 1
+			result := outcome.AgreedPerformables[last]
+			excess -= outcomeItemSize(codecVersion, result, func(e *binaryEncoder) { e.writeCheckResult(result) })
+			// the latest round of the reported history holds the agreed
+			// performables of this outcome in the same order
+			if len(outcome.ReportedHistory) > 0 && len(outcome.ReportedHistory[0]) == len(outcome.AgreedPerformables) {
+				outcome.ReportedHistory[0] = outcome.ReportedHistory[0][:last]
+			}
+			outcome.AgreedPerformables = outcome.AgreedPerformables[:last]
+			removed = true
+		}
+
+		for excess > 0 && len(outcome.SurfacedProposals) == 1 && len(outcome.SurfacedProposals[0]) > 0 {
+			last := len(outcome.SurfacedProposals[0]) - 1
+			proposal := outcome.SurfacedProposals[0][last]
+			excess -= outcomeItemSize(codecVersion, proposal, func(e *binaryEncoder) { e.writeProposal(proposal) })
+			outcome.SurfacedProposals[0] = outcome.SurfacedProposals[0][:last]
+			removed = true
+		}
+
+		if !removed {
+			return nil, fmt.Errorf("outcome of %d bytes exceeds the maximum length of %d bytes", len(encoded), maxLength)
+		}
+	}
+}
+
+// outcomeItemSize returns the encoded size of an item of an outcome list in
+// the encoding of the provided codec. The json size includes the separator
+// between items.
+func outcomeItemSize(codecVersion uint8, item any, write func(e *binaryEncoder)) int {
+	if codecVersion == config.CodecVersionJSON {
+		return jsonSize(item) + 1
+	}
+	return encodedSize(write)
+}
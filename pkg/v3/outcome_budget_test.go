@@ -0,0 +1,71 @@
+package ocr2keepers
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smartcontractkit/chainlink-automation/pkg/v3/config"
+	commontypes "github.com/smartcontractkit/chainlink-common/pkg/types/automation"
+)
+
+func TestEncodeOutcomeWithinLength(t *testing.T) {
+	largeOutcome := func() AutomationOutcome {
+		outcome := AutomationOutcome{ReportedHistory: [][]ReportedWork{{}, {{WorkID: "older"}}}}
+		for i := 0; i < 500; i++ {
+			result := validConditionalResult
+			result.WorkID = fmt.Sprintf("%064d", i)
+			result.PerformData = make([]byte, 10_000)
+			outcome.AgreedPerformables = append(outcome.AgreedPerformables, result)
+			outcome.ReportedHistory[0] = append(outcome.ReportedHistory[0], ReportedWork{WorkID: result.WorkID})
+		}
+		for i := 0; i < 3; i++ {
+			outcome.SurfacedProposals = append(outcome.SurfacedProposals, []commontypes.CoordinatedBlockProposal{validConditionalProposal, validLogProposal})
+		}
+		return outcome
+	}
+
+	for _, codecVersion := range []uint8{config.CodecVersionJSON, config.CodecVersionBinary} {
+		t.Run(fmt.Sprintf("codec version %d", codecVersion), func(t *testing.T) {
+			outcome := validOutcome
+			encoded, err := EncodeOutcomeWithinLength(&outcome, MaxOutcomeLength, codecVersion)
+			require.NoError(t, err)
+			expected, err := validOutcome.EncodeWithCodec(codecVersion)
+			require.NoError(t, err)
+			assert.Equal(t, expected, encoded, "outcomes within the length are not changed")
+
+			outcome = largeOutcome()
+			encoded, err = EncodeOutcomeWithinLength(&outcome, MaxOutcomeLength, codecVersion)
+			require.NoError(t, err)
+			assert.LessOrEqual(t, len(encoded), MaxOutcomeLength)
+
+			// older proposal rounds are left out before any performable
+			assert.Len(t, outcome.SurfacedProposals, 1)
+			assert.Len(t, outcome.SurfacedProposals[0], 2)
+			assert.Greater(t, len(outcome.AgreedPerformables), 100)
+			assert.Less(t, len(outcome.AgreedPerformables), 500)
+
+			// the kept performables are the highest priority ones
+			for i, result := range outcome.AgreedPerformables {
+				assert.Equal(t, fmt.Sprintf("%064d", i), result.WorkID)
+			}
+			// performables that are left out can be agreed again
+			if codecVersion == config.CodecVersionBinary {
+				assert.Len(t, outcome.ReportedHistory[0], len(outcome.AgreedPerformables))
+				assert.Len(t, outcome.ReportedHistory[1], 1)
+			}
+
+			decoded, err := decodeAutomationOutcome(encoded)
+			require.NoError(t, err)
+			assert.Len(t, decoded.AgreedPerformables, len(outcome.AgreedPerformables))
+		})
+	}
+
+	t.Run("outcomes that cannot be reduced are an error", func(t *testing.T) {
+		outcome := AutomationOutcome{}
+		_, err := EncodeOutcomeWithinLength(&outcome, 1, config.CodecVersionJSON)
+		assert.ErrorContains(t, err, "exceeds the maximum length")
+	})
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/smartcontractkit/chainlink-automation/pkg/v3/config"
 	types "github.com/smartcontractkit/chainlink-common/pkg/types/automation"
 )
 
@@ -17,9 +18,10 @@ var validOutcome = AutomationOutcome{
 	SurfacedProposals:  [][]types.CoordinatedBlockProposal{{validConditionalProposal, validLogProposal}},
 }
 var expectedEncodedOutcome []byte
+var legacyEncodedOutcome []byte
 
 func init() {
-	b, err := os.ReadFile("fixtures/expected_encoded_outcome.txt")
+	b, err := os.ReadFile("fixtures/expected_encoded_outcome_v1.txt")
 	if err != nil {
 		panic(err)
 	}
@@ -27,20 +29,28 @@ func init() {
 	if err != nil {
 		panic(err)
 	}
+	b, err = os.ReadFile("fixtures/expected_encoded_outcome.txt")
+	if err != nil {
+		panic(err)
+	}
+	legacyEncodedOutcome, err = hex.DecodeString(string(b))
+	if err != nil {
+		panic(err)
+	}
 }
 
 func TestValidAutomationOutcome(t *testing.T) {
 	encoded, err := validOutcome.Encode()
 	assert.NoError(t, err, "no error in encoding valid automation outcome")
 
-	decoded, err := DecodeAutomationOutcome(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator)
+	decoded, err := DecodeAutomationOutcome(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator, config.DefaultProtocolLimitsV0)
 	assert.NoError(t, err, "no error in decoding valid automation outcome")
 
 	assert.Equal(t, validOutcome, decoded, "final result from encoding and decoding should match")
 }
 
 func TestAutomationOutcomeEncodeBackwardsCompatibility(t *testing.T) {
-	encoded, err := validOutcome.Encode()
+	encoded, err := validOutcome.EncodeWithCodec(config.CodecVersionBinary)
 	assert.NoError(t, err, "no error in encoding valid automation outcome")
 
 	if !bytes.Equal(encoded, expectedEncodedOutcome) {
@@ -52,12 +62,50 @@ func TestAutomationOutcomeEncodeBackwardsCompatibility(t *testing.T) {
 	}
 }
 
+func TestAutomationOutcomeDecodeLegacyJSON(t *testing.T) {
+	decoded, err := DecodeAutomationOutcome(legacyEncodedOutcome, mockUpkeepTypeGetter, mockWorkIDGenerator, config.DefaultProtocolLimitsV0)
+	assert.NoError(t, err, "no error in decoding legacy json automation outcome")
+
+	assert.Equal(t, validOutcome, decoded, "legacy json outcome should decode to the same outcome")
+}
+
+func TestAutomationOutcomeDecodeInvalidEncoding(t *testing.T) {
+	encoded, err := validOutcome.EncodeWithCodec(config.CodecVersionBinary)
+	assert.NoError(t, err, "no error in encoding valid automation outcome")
+
+	_, err = DecodeAutomationOutcome([]byte{}, mockUpkeepTypeGetter, mockWorkIDGenerator, config.DefaultProtocolLimitsV0)
+	assert.ErrorContains(t, err, "empty encoding")
+
+	_, err = DecodeAutomationOutcome(encoded[:len(encoded)-10], mockUpkeepTypeGetter, mockWorkIDGenerator, config.DefaultProtocolLimitsV0)
+	assert.Error(t, err, "truncated encoding cannot be decoded")
+
+	unknownVersion := append([]byte{}, encoded...)
+	unknownVersion[0] = 255
+	_, err = DecodeAutomationOutcome(unknownVersion, mockUpkeepTypeGetter, mockWorkIDGenerator, config.DefaultProtocolLimitsV0)
+	assert.ErrorContains(t, err, "unsupported outcome encoding version 255")
+
+	// the reported history is the only optional section and an absent history
+	// cannot also be encoded as a nil section
+	_, err = DecodeAutomationOutcome(append(encoded, 0), mockUpkeepTypeGetter, mockWorkIDGenerator, config.DefaultProtocolLimitsV0)
+	assert.ErrorContains(t, err, "optional section cannot encode a nil value")
+
+	_, err = DecodeAutomationOutcome(append(encoded, 1, 0), mockUpkeepTypeGetter, mockWorkIDGenerator, config.DefaultProtocolLimitsV0)
+	assert.ErrorContains(t, err, "unexpected trailing bytes")
+}
+
+func TestAutomationOutcomeEncodingIsSmallerThanLegacyJSON(t *testing.T) {
+	encoded, err := validOutcome.EncodeWithCodec(config.CodecVersionBinary)
+	assert.NoError(t, err, "no error in encoding valid automation outcome")
+
+	assert.Less(t, len(encoded)*3, len(legacyEncodedOutcome))
+}
+
 func TestLargeAgreedPerformables(t *testing.T) {
 	ao := AutomationOutcome{
 		AgreedPerformables: []types.CheckResult{},
 		SurfacedProposals:  [][]types.CoordinatedBlockProposal{{validConditionalProposal, validLogProposal}},
 	}
-	for i := 0; i < OutcomeAgreedPerformablesLimit+1; i++ {
+	for i := 0; i < config.DefaultProtocolLimitsV0.OutcomeAgreedPerformablesLimit+1; i++ {
 		newConditionalResult := validConditionalResult
 		newConditionalResult.Trigger.BlockNumber = types.BlockNumber(i + 1)
 		newConditionalResult.WorkID = mockWorkIDGenerator(newConditionalResult.UpkeepID, newConditionalResult.Trigger)
@@ -66,7 +114,7 @@ func TestLargeAgreedPerformables(t *testing.T) {
 	encoded, err := ao.Encode()
 	assert.NoError(t, err, "no error in encoding valid automation outcome")
 
-	_, err = DecodeAutomationOutcome(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator)
+	_, err = DecodeAutomationOutcome(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator, config.DefaultProtocolLimitsV0)
 	assert.Error(t, err)
 	assert.ErrorContains(t, err, "outcome performable length cannot be greater than")
 }
@@ -82,7 +130,7 @@ func TestDuplicateAgreedPerformables(t *testing.T) {
 	encoded, err := ao.Encode()
 	assert.NoError(t, err, "no error in encoding valid automation outcome")
 
-	_, err = DecodeAutomationOutcome(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator)
+	_, err = DecodeAutomationOutcome(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator, config.DefaultProtocolLimitsV0)
 	assert.Error(t, err)
 	assert.ErrorContains(t, err, "agreed performable cannot have duplicate workIDs")
 }
@@ -92,7 +140,7 @@ func TestLargeProposalHistory(t *testing.T) {
 		AgreedPerformables: []types.CheckResult{validConditionalResult, validLogResult},
 		SurfacedProposals:  [][]types.CoordinatedBlockProposal{},
 	}
-	for i := 0; i < OutcomeSurfacedProposalsRoundHistoryLimit+1; i++ {
+	for i := 0; i < config.DefaultProtocolLimitsV0.OutcomeSurfacedProposalsRoundHistoryLimit+1; i++ {
 		newProposal := validConditionalProposal
 		uid := types.UpkeepIdentifier{}
 		uid.FromBigInt(big.NewInt(int64(i + 1)))
@@ -103,7 +151,7 @@ func TestLargeProposalHistory(t *testing.T) {
 	encoded, err := ao.Encode()
 	assert.NoError(t, err, "no error in encoding valid automation outcome")
 
-	_, err = DecodeAutomationOutcome(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator)
+	_, err = DecodeAutomationOutcome(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator, config.DefaultProtocolLimitsV0)
 	assert.Error(t, err)
 	assert.ErrorContains(t, err, "number of rounds for surfaced proposals cannot be greater than")
 }
@@ -113,7 +161,7 @@ func TestLargeSurfacedProposalInSingleRound(t *testing.T) {
 		AgreedPerformables: []types.CheckResult{validConditionalResult, validLogResult},
 		SurfacedProposals:  [][]types.CoordinatedBlockProposal{{}},
 	}
-	for i := 0; i < OutcomeSurfacedProposalsLimit+1; i++ {
+	for i := 0; i < config.DefaultProtocolLimitsV0.OutcomeSurfacedProposalsLimit+1; i++ {
 		newProposal := validConditionalProposal
 		uid := types.UpkeepIdentifier{}
 		uid.FromBigInt(big.NewInt(int64(i + 1)))
@@ -124,7 +172,7 @@ func TestLargeSurfacedProposalInSingleRound(t *testing.T) {
 	encoded, err := ao.Encode()
 	assert.NoError(t, err, "no error in encoding valid automation outcome")
 
-	_, err = DecodeAutomationOutcome(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator)
+	_, err = DecodeAutomationOutcome(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator, config.DefaultProtocolLimitsV0)
 	assert.Error(t, err)
 	assert.ErrorContains(t, err, "number of surfaced proposals in a round cannot be greater than")
 }
@@ -140,7 +188,7 @@ func TestDuplicateSurfaced(t *testing.T) {
 	encoded, err := ao.Encode()
 	assert.NoError(t, err, "no error in encoding valid automation outcome")
 
-	_, err = DecodeAutomationOutcome(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator)
+	_, err = DecodeAutomationOutcome(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator, config.DefaultProtocolLimitsV0)
 	assert.Error(t, err)
 	assert.ErrorContains(t, err, "proposals cannot have duplicate workIDs")
 }
@@ -151,7 +199,7 @@ func TestLargeOutcomeSize(t *testing.T) {
 		SurfacedProposals:  [][]types.CoordinatedBlockProposal{},
 	}
 	largePerformData := [10001]byte{}
-	for i := 0; i < OutcomeAgreedPerformablesLimit; i++ {
+	for i := 0; i < config.DefaultProtocolLimitsV0.OutcomeAgreedPerformablesLimit; i++ {
 		newResult := validLogResult
 		uid := types.UpkeepIdentifier{}
 		uid.FromBigInt(big.NewInt(int64(i + 10001)))
@@ -160,12 +208,12 @@ func TestLargeOutcomeSize(t *testing.T) {
 		newResult.PerformData = largePerformData[:]
 		ao.AgreedPerformables = append(ao.AgreedPerformables, newResult)
 	}
-	for i := 0; i < OutcomeSurfacedProposalsRoundHistoryLimit; i++ {
+	for i := 0; i < config.DefaultProtocolLimitsV0.OutcomeSurfacedProposalsRoundHistoryLimit; i++ {
 		round := []types.CoordinatedBlockProposal{}
-		for j := 0; j < OutcomeSurfacedProposalsLimit; j++ {
+		for j := 0; j < config.DefaultProtocolLimitsV0.OutcomeSurfacedProposalsLimit; j++ {
 			newProposal := validLogProposal
 			uid := types.UpkeepIdentifier{}
-			uid.FromBigInt(big.NewInt(int64(i*OutcomeSurfacedProposalsLimit + j + 1001)))
+			uid.FromBigInt(big.NewInt(int64(i*config.DefaultProtocolLimitsV0.OutcomeSurfacedProposalsLimit + j + 1001)))
 			newProposal.UpkeepID = uid
 			newProposal.WorkID = mockWorkIDGenerator(newProposal.UpkeepID, newProposal.Trigger)
 			round = append(round, newProposal)
@@ -175,7 +223,7 @@ func TestLargeOutcomeSize(t *testing.T) {
 	encoded, err := ao.Encode()
 	assert.NoError(t, err, "no error in encoding valid automation outcome")
 
-	decoded, err := DecodeAutomationOutcome(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator)
+	decoded, err := DecodeAutomationOutcome(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator, config.DefaultProtocolLimitsV0)
 	assert.NoError(t, err, "no error in decoding valid automation outcome")
 
 	assert.Equal(t, ao, decoded, "final result from encoding and decoding should match")
@@ -183,3 +231,60 @@ func TestLargeOutcomeSize(t *testing.T) {
 	assert.Equal(t, ao, decoded, "final result from encoding and decoding should match")
 	assert.Less(t, len(encoded), MaxOutcomeLength, "encoded outcome should be less than maxoutcomeSize")
 }
+
+func TestAutomationOutcomeReportedHistory(t *testing.T) {
+	withHistory := validOutcome
+	withHistory.ReportedHistory = [][]ReportedWork{
+		{{WorkID: validConditionalResult.WorkID, CheckBlock: 10}},
+		{},
+	}
+
+	encoded, err := withHistory.EncodeWithCodec(config.CodecVersionBinary)
+	assert.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(encoded, expectedEncodedOutcome), "the reported history is appended to the existing encoding")
+
+	decoded, err := DecodeAutomationOutcome(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator, config.DefaultProtocolLimitsV0)
+	assert.NoError(t, err)
+	assert.Equal(t, withHistory, decoded)
+
+	// outcomes without a reported history decode without one
+	decoded, err = DecodeAutomationOutcome(expectedEncodedOutcome, mockUpkeepTypeGetter, mockWorkIDGenerator, config.DefaultProtocolLimitsV0)
+	assert.NoError(t, err)
+	assert.Nil(t, decoded.ReportedHistory)
+
+	_, err = DecodeAutomationOutcome(encoded[:len(encoded)-2], mockUpkeepTypeGetter, mockWorkIDGenerator, config.DefaultProtocolLimitsV0)
+	assert.Error(t, err, "truncated reported history cannot be decoded")
+
+	// the json encoding only holds the history when there is one
+	encoded, err = withHistory.Encode()
+	assert.NoError(t, err)
+	decoded, err = DecodeAutomationOutcome(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator, config.DefaultProtocolLimitsV0)
+	assert.NoError(t, err)
+	assert.Equal(t, withHistory, decoded)
+
+	encoded, err = validOutcome.Encode()
+	assert.NoError(t, err)
+	assert.Equal(t, legacyEncodedOutcome, encoded)
+}
+
+func TestLargeReportedHistory(t *testing.T) {
+	ao := validOutcome
+	for i := 0; i < OutcomeReportedHistoryRoundLimit+1; i++ {
+		ao.ReportedHistory = append(ao.ReportedHistory, []ReportedWork{})
+	}
+	encoded, err := ao.EncodeWithCodec(config.CodecVersionBinary)
+	assert.NoError(t, err)
+	_, err = DecodeAutomationOutcome(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator, config.DefaultProtocolLimitsV0)
+	assert.ErrorContains(t, err, "number of rounds for reported history cannot be greater than")
+
+	ao = validOutcome
+	round := []ReportedWork{}
+	for i := 0; i < config.DefaultProtocolLimitsV0.OutcomeAgreedPerformablesLimit+1; i++ {
+		round = append(round, ReportedWork{WorkID: "workID", CheckBlock: types.BlockNumber(i)})
+	}
+	ao.ReportedHistory = [][]ReportedWork{round}
+	encoded, err = ao.EncodeWithCodec(config.CodecVersionBinary)
+	assert.NoError(t, err)
+	_, err = DecodeAutomationOutcome(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator, config.DefaultProtocolLimitsV0)
+	assert.ErrorContains(t, err, "number of reported workIDs in a round cannot be greater than")
+}
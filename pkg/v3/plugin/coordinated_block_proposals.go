@@ -2,6 +2,7 @@ package plugin
 
 import (
 	"log"
+	"math"
 	"sort"
 
 	ocr2keepersv3 "github.com/smartcontractkit/chainlink-automation/pkg/v3"
@@ -11,6 +12,7 @@ import (
 
 type coordinatedBlockProposals struct {
 	quorumBlockthreshold int
+	confirmations        int
 	roundHistoryLimit    int
 	perRoundLimit        int
 	keyRandSource        [16]byte
@@ -19,9 +21,10 @@ type coordinatedBlockProposals struct {
 	allNewProposals      []ocr2keepers.CoordinatedBlockProposal
 }
 
-func newCoordinatedBlockProposals(quorumBlockthreshold int, roundHistoryLimit int, perRoundLimit int, rSrc [16]byte, logger *log.Logger) *coordinatedBlockProposals {
+func newCoordinatedBlockProposals(quorumBlockthreshold int, confirmations int, roundHistoryLimit int, perRoundLimit int, rSrc [16]byte, logger *log.Logger) *coordinatedBlockProposals {
 	return &coordinatedBlockProposals{
 		quorumBlockthreshold: quorumBlockthreshold,
+		confirmations:        confirmations,
 		roundHistoryLimit:    roundHistoryLimit,
 		perRoundLimit:        perRoundLimit,
 		keyRandSource:        rSrc,
@@ -35,6 +38,8 @@ func newCoordinatedBlockProposals(quorumBlockthreshold int, roundHistoryLimit in
 //  1. History of proposals from previous outcome is carried over.
 //  2. Those proposals which got an agreed performable are removed.
 //  3. A latest quorum block is determined from the recent blocks in observations.
+//     With confirmations configured, the latest quorum block that is at least
+//     that many blocks behind the latest quorum block is used instead.
 //  4. If no block achieves quorum then no new proposals are surfaced and we exit.
 //  5. Oldest round's proposals are dropped if over limit to make room for new
 //     surfaced proposals.
@@ -75,7 +80,7 @@ func (c *coordinatedBlockProposals) set(outcome *ocr2keepersv3.AutomationOutcome
 		}
 		outcome.SurfacedProposals = append(outcome.SurfacedProposals, roundProposals)
 	}
-	latestQuorumBlock, ok := c.getLatestQuorumBlock()
+	latestQuorumBlock, ok := c.getCoordinatedBlock()
 	if !ok {
 		c.logger.Printf("Could not find a quorum coordinated block, not adding new proposals")
 		// Can't coordinate new proposals without a quorum block, return with existing proposals
@@ -130,14 +135,36 @@ func (c *coordinatedBlockProposals) set(outcome *ocr2keepersv3.AutomationOutcome
 	outcome.SurfacedProposals = append([][]ocr2keepers.CoordinatedBlockProposal{latestProposals}, outcome.SurfacedProposals...)
 }
 
+// getLatestQuorumBlock returns the highest block that reached quorum
 func (c *coordinatedBlockProposals) getLatestQuorumBlock() (ocr2keepers.BlockKey, bool) {
+	return c.getLatestQuorumBlockUpTo(math.MaxUint64)
+}
+
+// getCoordinatedBlock returns the block new proposals are coordinated on. This
+// is the highest block that reached quorum and is at least the configured
+// number of confirmations behind the latest quorum block, which makes it less
+// likely to be reorged.
+func (c *coordinatedBlockProposals) getCoordinatedBlock() (ocr2keepers.BlockKey, bool) {
+	tip, ok := c.getLatestQuorumBlock()
+	if !ok || c.confirmations <= 0 {
+		return tip, ok
+	}
+	if uint64(tip.Number) < uint64(c.confirmations) {
+		return ocr2keepers.BlockKey{}, false
+	}
+	return c.getLatestQuorumBlockUpTo(tip.Number - ocr2keepers.BlockNumber(c.confirmations))
+}
+
+// getLatestQuorumBlockUpTo returns the highest block that reached quorum with
+// a number no higher than maxNumber
+func (c *coordinatedBlockProposals) getLatestQuorumBlockUpTo(maxNumber ocr2keepers.BlockNumber) (ocr2keepers.BlockKey, bool) {
 	var (
 		mostRecent ocr2keepers.BlockKey
 		zeroHash   [32]byte
 	)
 
 	for block, count := range c.recentBlocks {
-		if count >= int(c.quorumBlockthreshold) {
+		if count >= int(c.quorumBlockthreshold) && block.Number <= maxNumber {
 			if (mostRecent.Hash == zeroHash) || // First consensus hash
 				(block.Number > mostRecent.Number) || // later height
 				(block.Number == mostRecent.Number && // Matching heights
@@ -336,7 +336,7 @@ func Test_newCoordinatedBlockProposals_add(t *testing.T) {
 		},
 	} {
 		t.Run(tc.name, func(t *testing.T) {
-			proposals := newCoordinatedBlockProposals(tc.quorumBlockthreshold, 2, 3, [16]byte{1}, log.New(io.Discard, "", 1))
+			proposals := newCoordinatedBlockProposals(tc.quorumBlockthreshold, 0, 2, 3, [16]byte{1}, log.New(io.Discard, "", 1))
 			for _, ao := range tc.observations {
 				proposals.add(ao)
 			}
@@ -353,6 +353,71 @@ func Test_newCoordinatedBlockProposals_add(t *testing.T) {
 	}
 }
 
+func Test_newCoordinatedBlockProposals_getCoordinatedBlock(t *testing.T) {
+	history := func(from, to types.BlockNumber) types.BlockHistory {
+		var h types.BlockHistory
+		for n := to; n >= from; n-- {
+			h = append(h, types.BlockKey{Number: n, Hash: [32]byte{byte(n)}})
+		}
+		return h
+	}
+
+	for _, tc := range []struct {
+		name          string
+		confirmations int
+		histories     []types.BlockHistory
+		wantBlock     types.BlockKey
+		wantOk        bool
+	}{
+		{
+			name:          "no confirmations uses the latest quorum block",
+			confirmations: 0,
+			histories:     []types.BlockHistory{history(90, 100), history(90, 100)},
+			wantBlock:     types.BlockKey{Number: 100, Hash: [32]byte{100}},
+			wantOk:        true,
+		},
+		{
+			name:          "confirmations are counted from the latest quorum block",
+			confirmations: 5,
+			histories:     []types.BlockHistory{history(90, 100), history(90, 102)},
+			wantBlock:     types.BlockKey{Number: 95, Hash: [32]byte{95}},
+			wantOk:        true,
+		},
+		{
+			name:          "the confirmed block needs to reach quorum",
+			confirmations: 5,
+			histories:     []types.BlockHistory{history(99, 100), history(90, 100)},
+			wantOk:        false,
+		},
+		{
+			name:          "a lower quorum block is used when the confirmed block is missing",
+			confirmations: 5,
+			histories:     []types.BlockHistory{append(history(97, 100), history(90, 93)...), history(90, 100)},
+			wantBlock:     types.BlockKey{Number: 93, Hash: [32]byte{93}},
+			wantOk:        true,
+		},
+		{
+			name:          "confirmations beyond the chain start",
+			confirmations: 5,
+			histories:     []types.BlockHistory{history(1, 3), history(1, 3)},
+			wantOk:        false,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			proposals := newCoordinatedBlockProposals(2, tc.confirmations, 2, 3, [16]byte{1}, log.New(io.Discard, "", 1))
+			for _, h := range tc.histories {
+				proposals.add(ocr2keepers.AutomationObservation{BlockHistory: h})
+			}
+
+			block, ok := proposals.getCoordinatedBlock()
+			assert.Equal(t, tc.wantOk, ok)
+			if tc.wantOk {
+				assert.Equal(t, tc.wantBlock, block)
+			}
+		})
+	}
+}
+
 func Test_proposalExists(t *testing.T) {
 	for _, tc := range []struct {
 		name          string
@@ -511,7 +576,7 @@ func Test_performableExists(t *testing.T) {
 
 func Test_newCoordinatedBlockProposals_set(t *testing.T) {
 	t.Run("calling set on an empty outcome with an empty previous outcome updates the outcome based on the internal state", func(t *testing.T) {
-		proposals := newCoordinatedBlockProposals(1, 2, 3, [16]byte{1}, log.New(io.Discard, "", 1))
+		proposals := newCoordinatedBlockProposals(1, 0, 2, 3, [16]byte{1}, log.New(io.Discard, "", 1))
 
 		observations := []ocr2keepers.AutomationObservation{
 			{
@@ -597,7 +662,7 @@ func Test_newCoordinatedBlockProposals_set(t *testing.T) {
 	})
 
 	t.Run("new proposals that already exist on the surfaced proposals and agreed performables of the previous outcome are not re-added", func(t *testing.T) {
-		proposals := newCoordinatedBlockProposals(1, 2, 3, [16]byte{1}, log.New(io.Discard, "", 1))
+		proposals := newCoordinatedBlockProposals(1, 0, 2, 3, [16]byte{1}, log.New(io.Discard, "", 1))
 
 		observations := []ocr2keepers.AutomationObservation{
 			{
@@ -705,7 +770,7 @@ func Test_newCoordinatedBlockProposals_set(t *testing.T) {
 	})
 
 	t.Run("when the number of surfaced proposals in the previous outcome equals or exceeds the round history limit, the number of surfaced proposals is truncated to the limit", func(t *testing.T) {
-		proposals := newCoordinatedBlockProposals(1, 1, 3, [16]byte{1}, log.New(io.Discard, "", 1))
+		proposals := newCoordinatedBlockProposals(1, 0, 1, 3, [16]byte{1}, log.New(io.Discard, "", 1))
 
 		observations := []ocr2keepers.AutomationObservation{
 			{
@@ -821,7 +886,7 @@ func Test_newCoordinatedBlockProposals_set(t *testing.T) {
 	})
 
 	t.Run("when the number of latest proposals exceeds the per round limit, the number of surfaced proposals is truncated to the limit", func(t *testing.T) {
-		proposals := newCoordinatedBlockProposals(1, 1, 1, [16]byte{1}, log.New(io.Discard, "", 1))
+		proposals := newCoordinatedBlockProposals(1, 0, 1, 1, [16]byte{1}, log.New(io.Discard, "", 1))
 
 		observations := []ocr2keepers.AutomationObservation{
 			{
@@ -925,7 +990,7 @@ func Test_newCoordinatedBlockProposals_set(t *testing.T) {
 	})
 
 	t.Run("when the quorum block cannot be fetched, we return without adding new proposals", func(t *testing.T) {
-		proposals := newCoordinatedBlockProposals(3, 1, 3, [16]byte{1}, log.New(io.Discard, "", 1))
+		proposals := newCoordinatedBlockProposals(3, 0, 1, 3, [16]byte{1}, log.New(io.Discard, "", 1))
 
 		observations := []ocr2keepers.AutomationObservation{
 			{
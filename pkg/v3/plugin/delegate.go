@@ -13,7 +13,9 @@ import (
 	ocr2plustypes "github.com/smartcontractkit/libocr/offchainreporting2plus/types"
 
 	"github.com/smartcontractkit/chainlink-automation/pkg/v3/config"
+	"github.com/smartcontractkit/chainlink-automation/pkg/v3/coordinator"
 	"github.com/smartcontractkit/chainlink-automation/pkg/v3/runner"
+	"github.com/smartcontractkit/chainlink-automation/pkg/v3/stores"
 	"github.com/smartcontractkit/chainlink-automation/pkg/v3/telemetry"
 	"github.com/smartcontractkit/chainlink-automation/pkg/v3/types"
 	ocr2keepers "github.com/smartcontractkit/chainlink-common/pkg/types/automation"
@@ -78,6 +80,38 @@ type DelegateConfig struct {
 	UpkeepTypeGetter types.UpkeepTypeGetter
 	WorkIDGenerator  types.WorkIDGenerator
 
+	// Registries provides the providers of every registry when a single
+	// plugin instance serves multiple registries. The single registry
+	// providers above and the ContractTransmitter are ignored when set, except
+	// for the BlockSubscriber which is shared by all registries. Reports are
+	// transmitted by the transmitter of the registry they are for, which
+	// requires every registry to be configured with the OCR config tracked by
+	// the ContractConfigTracker, such that it verifies the config digest and
+	// the signatures of the OnchainKeyring.
+	Registries []RegistryProviders
+
+	// CoordinatorStore persists the state of the coordinator such that a
+	// restarted node does not drop pending transmits or perform log upkeeps
+	// again. The state is kept in memory if not set.
+	CoordinatorStore coordinator.Store
+
+	// QueueStore persists the retry and proposal queues such that a restarted
+	// node does not drop pending retries and coordinated proposals. The queues
+	// are kept in memory if not set.
+	QueueStore stores.QueueStore
+
+	// SnapshotStore keeps a snapshot of the plugin state when a plugin
+	// instance is closed, which is restored into the next plugin instance of
+	// the same config digest. If not set, the snapshot is kept in memory and
+	// restored into the next plugin instance of any config digest, such that
+	// in-flight work survives a config change but not a restart of the node.
+	SnapshotStore SnapshotStore
+
+	// MigrateSnapshots restores snapshots of the SnapshotStore of other config
+	// digests as well, such that in-flight work survives a config change. It
+	// is ignored if no SnapshotStore is set.
+	MigrateSnapshots bool
+
 	// CacheExpiration is the duration of time a cached key is available. Use
 	// this value to balance memory usage and RPC calls. A new set of keys is
 	// generated with every block so a good setting might come from block time
@@ -107,8 +141,10 @@ type DelegateConfig struct {
 // the ability to start and stop underlying services associated with the
 // plugin instance.
 type Delegate struct {
-	keeper oracle
-	logger *log.Logger
+	keeper    oracle
+	scores    OracleScoreInspector
+	inspector coordinator.Inspector
+	logger    *log.Logger
 }
 
 // NewDelegate provides a new Delegate from a provided config. A new logger
@@ -149,12 +185,60 @@ func NewDelegate(c DelegateConfig) (*Delegate, error) {
 
 	l.Printf("creating oracle with reporting factory config: %+v", conf)
 
+	runnerConf := runner.RunnerConfig{
+		Workers:           conf.MaxServiceWorkers,
+		WorkerQueueLength: conf.ServiceQueueLength,
+		CacheExpire:       conf.CacheExpiration,
+		CacheClean:        conf.CacheEvictionInterval,
+	}
+
+	var factory *pluginFactory
+	transmitter := c.ContractTransmitter
+	if len(c.Registries) > 0 {
+		var err error
+		if factory, err = newMultiRegistryReportingPluginFactory(c.Registries, c.BlockSubscriber, runnerConf, l); err != nil {
+			return nil, fmt.Errorf("%w: failed to create multi-registry plugin factory", err)
+		}
+		if transmitter, err = newRegistryTransmitter(c.Registries); err != nil {
+			return nil, fmt.Errorf("%w: failed to create multi-registry transmitter", err)
+		}
+	} else {
+		factory = newReportingPluginFactory(
+			c.LogProvider,
+			c.EventProvider,
+			c.BlockSubscriber,
+			c.RecoverableProvider,
+			c.PayloadBuilder,
+			c.UpkeepProvider,
+			c.Runnable,
+			runnerConf,
+			c.Encoder,
+			c.UpkeepTypeGetter,
+			c.WorkIDGenerator,
+			c.UpkeepStateUpdater,
+			l,
+		)
+	}
+
+	if c.CoordinatorStore != nil {
+		factory.coordinatorStore = c.CoordinatorStore
+	}
+
+	if c.QueueStore != nil {
+		factory.queueStore = c.QueueStore
+	}
+
+	if c.SnapshotStore != nil {
+		factory.snapshotStore = c.SnapshotStore
+		factory.migrateSnapshots = c.MigrateSnapshots
+	}
+
 	// create the oracle from config values
 	keeper, err := newOracleFn(offchainreporting.OCR3OracleArgs[AutomationReportInfo]{
 		BinaryNetworkEndpointFactory: c.BinaryNetworkEndpointFactory,
 		V2Bootstrappers:              c.V2Bootstrappers,
 		ContractConfigTracker:        c.ContractConfigTracker,
-		ContractTransmitter:          c.ContractTransmitter,
+		ContractTransmitter:          transmitter,
 		Database:                     c.KeepersDatabase,
 		LocalConfig:                  c.LocalConfig,
 		Logger:                       c.Logger,
@@ -164,26 +248,7 @@ func NewDelegate(c DelegateConfig) (*Delegate, error) {
 		OnchainKeyring:               c.OnchainKeyring,
 		MetricsRegisterer:            c.MetricsRegisterer,
 
-		ReportingPluginFactory: NewReportingPluginFactory(
-			c.LogProvider,
-			c.EventProvider,
-			c.BlockSubscriber,
-			c.RecoverableProvider,
-			c.PayloadBuilder,
-			c.UpkeepProvider,
-			c.Runnable,
-			runner.RunnerConfig{
-				Workers:           conf.MaxServiceWorkers,
-				WorkerQueueLength: conf.ServiceQueueLength,
-				CacheExpire:       conf.CacheExpiration,
-				CacheClean:        conf.CacheEvictionInterval,
-			},
-			c.Encoder,
-			c.UpkeepTypeGetter,
-			c.WorkIDGenerator,
-			c.UpkeepStateUpdater,
-			l,
-		),
+		ReportingPluginFactory: factory,
 	})
 
 	if err != nil {
@@ -191,8 +256,10 @@ func NewDelegate(c DelegateConfig) (*Delegate, error) {
 	}
 
 	return &Delegate{
-		keeper: keeper,
-		logger: l,
+		keeper:    keeper,
+		scores:    factory,
+		inspector: factory,
+		logger:    l,
 	}, nil
 }
 
@@ -218,6 +285,45 @@ func (d *Delegate) Close() error {
 	return nil
 }
 
+// OracleScores returns the observation quality scores of the oracles in the
+// DON as seen by the current plugin instance. Scores are reset when a new
+// plugin instance is created for a new configuration.
+func (d *Delegate) OracleScores() []OracleScore {
+	if d.scores == nil {
+		return nil
+	}
+	return d.scores.OracleScores()
+}
+
+// InspectWorkID returns the coordinator state of a workID in the current
+// plugin instance and false if the coordinator has no record of it. Operators
+// can use it to find out why a workID is not processed.
+func (d *Delegate) InspectWorkID(workID string) (coordinator.WorkState, bool) {
+	if d.inspector == nil {
+		return coordinator.WorkState{}, false
+	}
+	return d.inspector.InspectWorkID(workID)
+}
+
+// InspectUpkeep returns the coordinator states of all workIDs of an upkeep in
+// the current plugin instance ordered by workID.
+func (d *Delegate) InspectUpkeep(upkeepID ocr2keepers.UpkeepIdentifier) []coordinator.WorkState {
+	if d.inspector == nil {
+		return nil
+	}
+	return d.inspector.InspectUpkeep(upkeepID)
+}
+
+// ShouldProcess returns whether the current plugin instance would process
+// work for the workID and trigger. It returns false while no plugin instance
+// is running.
+func (d *Delegate) ShouldProcess(workID string, upkeepID ocr2keepers.UpkeepIdentifier, trigger ocr2keepers.Trigger) bool {
+	if d.inspector == nil {
+		return false
+	}
+	return d.inspector.ShouldProcess(workID, upkeepID, trigger)
+}
+
 type logWriter struct {
 	l commontypes.Logger
 }
@@ -7,12 +7,15 @@ import (
 	"math"
 	"math/cmplx"
 	"strconv"
+	"sync"
 
 	"github.com/smartcontractkit/libocr/offchainreporting2plus/ocr3types"
 
 	ocr2keepers "github.com/smartcontractkit/chainlink-automation/pkg/v3"
 	"github.com/smartcontractkit/chainlink-automation/pkg/v3/config"
+	"github.com/smartcontractkit/chainlink-automation/pkg/v3/coordinator"
 	"github.com/smartcontractkit/chainlink-automation/pkg/v3/runner"
+	"github.com/smartcontractkit/chainlink-automation/pkg/v3/stores"
 	"github.com/smartcontractkit/chainlink-automation/pkg/v3/types"
 	commontypes "github.com/smartcontractkit/chainlink-common/pkg/types/automation"
 )
@@ -30,7 +33,16 @@ type pluginFactory struct {
 	upkeepTypeGetter   types.UpkeepTypeGetter
 	workIDGenerator    types.WorkIDGenerator
 	upkeepStateUpdater commontypes.UpkeepStateUpdater
+	registries         *registryMux
+	coordinatorStore   coordinator.Store
+	queueStore         stores.QueueStore
+	snapshotStore      SnapshotStore
+	migrateSnapshots   bool
 	logger             *log.Logger
+
+	mu         sync.RWMutex
+	scoreboard *oracleScoreboard
+	inspector  coordinator.Inspector
 }
 
 func NewReportingPluginFactory(
@@ -48,6 +60,38 @@ func NewReportingPluginFactory(
 	upkeepStateUpdater commontypes.UpkeepStateUpdater,
 	logger *log.Logger,
 ) ocr3types.ReportingPluginFactory[AutomationReportInfo] {
+	return newReportingPluginFactory(
+		logProvider,
+		events,
+		blocks,
+		rp,
+		builder,
+		getter,
+		runnable,
+		runnerConf,
+		encoder,
+		upkeepTypeGetter,
+		workIDGenerator,
+		upkeepStateUpdater,
+		logger,
+	)
+}
+
+func newReportingPluginFactory(
+	logProvider commontypes.LogEventProvider,
+	events types.TransmitEventProvider,
+	blocks commontypes.BlockSubscriber,
+	rp commontypes.RecoverableProvider,
+	builder commontypes.PayloadBuilder,
+	getter commontypes.ConditionalUpkeepProvider,
+	runnable types.Runnable,
+	runnerConf runner.RunnerConfig,
+	encoder commontypes.Encoder,
+	upkeepTypeGetter types.UpkeepTypeGetter,
+	workIDGenerator types.WorkIDGenerator,
+	upkeepStateUpdater commontypes.UpkeepStateUpdater,
+	logger *log.Logger,
+) *pluginFactory {
 	return &pluginFactory{
 		logProvider:        logProvider,
 		events:             events,
@@ -61,20 +105,65 @@ func NewReportingPluginFactory(
 		upkeepTypeGetter:   upkeepTypeGetter,
 		workIDGenerator:    workIDGenerator,
 		upkeepStateUpdater: upkeepStateUpdater,
+		coordinatorStore:   coordinator.NewMemoryStore(),
+		queueStore:         stores.NewMemoryQueueStore(),
+		snapshotStore:      NewMemorySnapshotStore(),
+		migrateSnapshots:   true,
 		logger:             logger,
 	}
 }
 
+// NewMultiRegistryReportingPluginFactory creates a plugin factory for plugin
+// instances that serve multiple registries on the same chain. The providers of
+// every registry are used for the upkeeps of that registry only, workIDs are
+// namespaced by the registry name and every report contains the upkeeps of a
+// single registry encoded with the encoder of that registry. The block
+// subscriber is shared by all registries. Reports have to be transmitted to
+// the registry named in their info, see RegistryProviders.ContractTransmitter.
+func NewMultiRegistryReportingPluginFactory(
+	registries []RegistryProviders,
+	blocks commontypes.BlockSubscriber,
+	runnerConf runner.RunnerConfig,
+	logger *log.Logger,
+) (ocr3types.ReportingPluginFactory[AutomationReportInfo], error) {
+	return newMultiRegistryReportingPluginFactory(registries, blocks, runnerConf, logger)
+}
+
+func newMultiRegistryReportingPluginFactory(
+	registries []RegistryProviders,
+	blocks commontypes.BlockSubscriber,
+	runnerConf runner.RunnerConfig,
+	logger *log.Logger,
+) (*pluginFactory, error) {
+	mux, err := newRegistryMux(registries, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	return &pluginFactory{
+		logProvider:        mux,
+		events:             mux,
+		blocks:             blocks,
+		rp:                 mux,
+		builder:            mux,
+		getter:             mux,
+		runnable:           mux,
+		runnerConf:         runnerConf,
+		upkeepTypeGetter:   mux.UpkeepTypeGetter,
+		workIDGenerator:    mux.WorkIDGenerator,
+		upkeepStateUpdater: mux,
+		registries:         mux,
+		coordinatorStore:   coordinator.NewMemoryStore(),
+		queueStore:         stores.NewMemoryQueueStore(),
+		snapshotStore:      NewMemorySnapshotStore(),
+		migrateSnapshots:   true,
+		logger:             logger,
+	}, nil
+}
+
 func (factory *pluginFactory) NewReportingPlugin(ctx context.Context, c ocr3types.ReportingPluginConfig) (ocr3types.ReportingPlugin[AutomationReportInfo], ocr3types.ReportingPluginInfo, error) {
 	info := ocr3types.ReportingPluginInfo{
 		Name: fmt.Sprintf("Oracle: %d: Automation Plugin Instance w/ Digest '%s'", c.OracleID, c.ConfigDigest),
-		Limits: ocr3types.ReportingPluginLimits{
-			MaxQueryLength:       0,
-			MaxObservationLength: ocr2keepers.MaxObservationLength,
-			MaxOutcomeLength:     ocr2keepers.MaxOutcomeLength,
-			MaxReportLength:      ocr2keepers.MaxReportLength,
-			MaxReportCount:       ocr2keepers.MaxReportCount,
-		},
 	}
 
 	// decode the off-chain config
@@ -83,6 +172,16 @@ func (factory *pluginFactory) NewReportingPlugin(ctx context.Context, c ocr3type
 		return nil, info, err
 	}
 
+	// the number of reports in a round is limited by the number of agreed
+	// performables allowed in a single outcome
+	info.Limits = ocr3types.ReportingPluginLimits{
+		MaxQueryLength:       ocr2keepers.MaxQueryLength,
+		MaxObservationLength: ocr2keepers.MaxObservationLength,
+		MaxOutcomeLength:     ocr2keepers.MaxOutcomeLength,
+		MaxReportLength:      ocr2keepers.MaxReportLength,
+		MaxReportCount:       conf.ProtocolLimits.OutcomeAgreedPerformablesLimit,
+	}
+
 	parsed, err := strconv.ParseFloat(conf.TargetProbability, 32)
 	if err != nil {
 		return nil, info, fmt.Errorf("%w: failed to parse configured probability", err)
@@ -98,6 +197,9 @@ func (factory *pluginFactory) NewReportingPlugin(ctx context.Context, c ocr3type
 		LogLimit:  conf.LogProviderConfig.LogLimit,
 	})
 
+	// every plugin instance scores the oracles of its own configuration
+	scoreboard := newOracleScoreboard(c.ConfigDigest)
+
 	// create the plugin; all services start automatically
 	p, err := newPlugin(
 		c.ConfigDigest,
@@ -115,6 +217,13 @@ func (factory *pluginFactory) NewReportingPlugin(ctx context.Context, c ocr3type
 		factory.runnable,
 		factory.runnerConf,
 		conf,
+		scoreboard,
+		factory.registries,
+		factory.coordinatorStore,
+		factory.queueStore,
+		factory.snapshotStore,
+		factory.migrateSnapshots,
+		c.OracleID,
 		c.N,
 		c.F,
 		factory.logger,
@@ -123,9 +232,69 @@ func (factory *pluginFactory) NewReportingPlugin(ctx context.Context, c ocr3type
 		return nil, info, err
 	}
 
+	var inspector coordinator.Inspector
+	if plugin, ok := p.(*ocr3Plugin); ok {
+		inspector, _ = plugin.Coordinator.(coordinator.Inspector)
+	}
+
+	factory.mu.Lock()
+	factory.scoreboard = scoreboard
+	factory.inspector = inspector
+	factory.mu.Unlock()
+
 	return p, info, nil
 }
 
+// OracleScores returns the oracle scores of the latest plugin instance created
+// by the factory
+func (factory *pluginFactory) OracleScores() []OracleScore {
+	factory.mu.RLock()
+	defer factory.mu.RUnlock()
+
+	if factory.scoreboard == nil {
+		return nil
+	}
+	return factory.scoreboard.OracleScores()
+}
+
+func (factory *pluginFactory) latestInspector() coordinator.Inspector {
+	factory.mu.RLock()
+	defer factory.mu.RUnlock()
+
+	return factory.inspector
+}
+
+// InspectWorkID returns the coordinator state of a workID in the latest plugin
+// instance created by the factory
+func (factory *pluginFactory) InspectWorkID(workID string) (coordinator.WorkState, bool) {
+	inspector := factory.latestInspector()
+	if inspector == nil {
+		return coordinator.WorkState{}, false
+	}
+	return inspector.InspectWorkID(workID)
+}
+
+// InspectUpkeep returns the coordinator states of the workIDs of an upkeep in
+// the latest plugin instance created by the factory
+func (factory *pluginFactory) InspectUpkeep(upkeepID commontypes.UpkeepIdentifier) []coordinator.WorkState {
+	inspector := factory.latestInspector()
+	if inspector == nil {
+		return nil
+	}
+	return inspector.InspectUpkeep(upkeepID)
+}
+
+// ShouldProcess returns whether the latest plugin instance created by the
+// factory would process the work. Nothing is processed without a plugin
+// instance.
+func (factory *pluginFactory) ShouldProcess(workID string, upkeepID commontypes.UpkeepIdentifier, trigger commontypes.Trigger) bool {
+	inspector := factory.latestInspector()
+	if inspector == nil {
+		return false
+	}
+	return inspector.ShouldProcess(workID, upkeepID, trigger)
+}
+
 func sampleFromProbability(rounds, nodes int, probability float32) (sampleRatio, error) {
 	var ratio sampleRatio
 
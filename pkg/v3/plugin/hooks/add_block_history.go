@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"log"
 
+	"github.com/smartcontractkit/chainlink-common/pkg/types/automation"
+
 	ocr2keepersv3 "github.com/smartcontractkit/chainlink-automation/pkg/v3"
 	"github.com/smartcontractkit/chainlink-automation/pkg/v3/telemetry"
 	"github.com/smartcontractkit/chainlink-automation/pkg/v3/types"
@@ -20,11 +22,31 @@ func NewAddBlockHistoryHook(ms types.MetadataStore, logger *log.Logger) AddBlock
 		logger:   log.New(logger.Writer(), fmt.Sprintf("[%s | build hook:add-block-history]", telemetry.ServiceName), telemetry.LogPkgStdFlags)}
 }
 
-func (h *AddBlockHistoryHook) RunHook(obs *ocr2keepersv3.AutomationObservation, limit int) {
+// RunHook adds the latest blocks to the observation. Blocks more than
+// QueryBlockHistoryWindow blocks older than the latest block of the leader are
+// left out as they are unlikely to become the coordinated block. The hint is
+// ignored when the leader claims a block ahead of the latest block of this
+// node, since the leader can't be trusted to cut the block history of this node.
+// Blocks that don't fit in the observation budget are left out as well.
+func (h *AddBlockHistoryHook) RunHook(obs *ocr2keepersv3.AutomationObservation, query ocr2keepersv3.AutomationQuery, budget *ocr2keepersv3.ObservationBudget, limit int) {
 	blockHistory := h.metadata.GetBlockHistory()
 	if len(blockHistory) > limit {
 		blockHistory = blockHistory[:limit]
 	}
-	obs.BlockHistory = blockHistory
-	h.logger.Printf("adding %d blocks to observation", len(blockHistory))
+
+	if latest, err := blockHistory.Latest(); err == nil && query.LatestBlock.Number > 0 &&
+		query.LatestBlock.Number <= latest.Number {
+		trimmed := make(automation.BlockHistory, 0, len(blockHistory))
+		for _, block := range blockHistory {
+			if block.Number+ocr2keepersv3.QueryBlockHistoryWindow >= query.LatestBlock.Number {
+				trimmed = append(trimmed, block)
+			}
+		}
+		if dropped := len(blockHistory) - len(trimmed); dropped > 0 {
+			h.logger.Printf("leaving out %d blocks older than %d blocks before leader block %d", dropped, ocr2keepersv3.QueryBlockHistoryWindow, query.LatestBlock.Number)
+		}
+		blockHistory = trimmed
+	}
+	obs.BlockHistory = budget.TakeBlockHistory(blockHistory)
+	h.logger.Printf("adding %d blocks to observation", len(obs.BlockHistory))
 }
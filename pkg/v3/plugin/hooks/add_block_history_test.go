@@ -17,6 +17,7 @@ func TestAddBlockHistoryHook_RunHook(t *testing.T) {
 		name           string
 		existingBlocks types.BlockHistory
 		blockHistory   types.BlockHistory
+		query          ocr2keepersv3.AutomationQuery
 		limit          int
 		expectedOutput types.BlockHistory
 	}{
@@ -61,6 +62,34 @@ func TestAddBlockHistoryHook_RunHook(t *testing.T) {
 				{Number: 2},
 			},
 		},
+		{
+			name:           "leaves out blocks outside the window of the leader block",
+			blockHistory:   types.BlockHistory{{Number: 200}, {Number: 150}, {Number: 136}, {Number: 135}, {Number: 100}},
+			query:          ocr2keepersv3.AutomationQuery{LatestBlock: types.BlockKey{Number: 200}},
+			limit:          10,
+			expectedOutput: types.BlockHistory{{Number: 200}, {Number: 150}, {Number: 136}},
+		},
+		{
+			name:           "keeps blocks ahead of the leader block",
+			blockHistory:   types.BlockHistory{{Number: 210}, {Number: 200}, {Number: 100}},
+			query:          ocr2keepersv3.AutomationQuery{LatestBlock: types.BlockKey{Number: 200}},
+			limit:          10,
+			expectedOutput: types.BlockHistory{{Number: 210}, {Number: 200}},
+		},
+		{
+			name:           "ignores a leader block ahead of the latest block",
+			blockHistory:   types.BlockHistory{{Number: 100}, {Number: 99}, {Number: 40}},
+			query:          ocr2keepersv3.AutomationQuery{LatestBlock: types.BlockKey{Number: 110}},
+			limit:          10,
+			expectedOutput: types.BlockHistory{{Number: 100}, {Number: 99}, {Number: 40}},
+		},
+		{
+			name:           "ignores a leader block too far ahead",
+			blockHistory:   types.BlockHistory{{Number: 100}, {Number: 99}},
+			query:          ocr2keepersv3.AutomationQuery{LatestBlock: types.BlockKey{Number: 1000}},
+			limit:          10,
+			expectedOutput: types.BlockHistory{{Number: 100}, {Number: 99}},
+		},
 	}
 
 	for _, tt := range tests {
@@ -82,7 +111,7 @@ func TestAddBlockHistoryHook_RunHook(t *testing.T) {
 			}
 
 			// Run the hook
-			addBlockHistoryHook.RunHook(obs, tt.limit)
+			addBlockHistoryHook.RunHook(obs, tt.query, nil, tt.limit)
 
 			// Assert that the observation's BlockHistory matches the expected output
 			assert.Equal(t, tt.expectedOutput, obs.BlockHistory)
@@ -25,7 +25,7 @@ func NewAddConditionalProposalsHook(ms types.MetadataStore, coord types.Coordina
 	}
 }
 
-func (h *AddConditionalProposalsHook) RunHook(obs *ocr2keepersv3.AutomationObservation, limit int, rSrc [16]byte) error {
+func (h *AddConditionalProposalsHook) RunHook(obs *ocr2keepersv3.AutomationObservation, query ocr2keepersv3.AutomationQuery, budget *ocr2keepersv3.ObservationBudget, limit int, rSrc [16]byte) error {
 	conditionals := h.metadata.ViewProposals(types.ConditionTrigger)
 
 	var err error
@@ -40,10 +40,15 @@ func (h *AddConditionalProposalsHook) RunHook(obs *ocr2keepersv3.AutomationObser
 		conditionals[i], conditionals[j] = conditionals[j], conditionals[i]
 	})
 
+	// proposals the leader hints at are partly left out and otherwise only
+	// added if the limit leaves room
+	conditionals = trimProposalsByQuery(conditionals, query, limit)
+
 	// take first limit
 	if len(conditionals) > limit {
 		conditionals = conditionals[:limit]
 	}
+	conditionals = budget.TakeProposals(ocr2keepersv3.ObservationSectionConditionalProposals, conditionals)
 
 	h.logger.Printf("adding %d conditional proposals to observation", len(conditionals))
 	obs.UpkeepProposals = append(obs.UpkeepProposals, conditionals...)
@@ -19,6 +19,7 @@ func TestAddConditionalSamplesHook_RunHook(t *testing.T) {
 		metadata         types.MetadataStore
 		coordinator      types.Coordinator
 		proposals        []common.CoordinatedBlockProposal
+		query            ocr2keepers.AutomationQuery
 		limit            int
 		src              [16]byte
 		wantNumProposals int
@@ -122,6 +123,30 @@ func TestAddConditionalSamplesHook_RunHook(t *testing.T) {
 			src:              [16]byte{0},
 			wantNumProposals: 2,
 		},
+		{
+			name: "proposals in flight or proposed by the leader are added after other proposals",
+			metadata: &mockMetadataStore{
+				ViewConditionalProposalFn: func() []common.CoordinatedBlockProposal {
+					return []common.CoordinatedBlockProposal{
+						{WorkID: "workID1"},
+						{WorkID: "workID2"},
+						{WorkID: "workID3"},
+					}
+				},
+			},
+			coordinator: &mockCoordinator{
+				FilterProposalsFn: func(proposals []common.CoordinatedBlockProposal) ([]common.CoordinatedBlockProposal, error) {
+					return proposals, nil
+				},
+			},
+			query: ocr2keepers.AutomationQuery{
+				InFlightWorkIDs: []string{"workID1"},
+				UpkeepProposals: []common.CoordinatedBlockProposal{{WorkID: "workID2"}},
+			},
+			limit:            3,
+			src:              [16]byte{1},
+			wantNumProposals: 3,
+		},
 		{
 			name: "if an error is encountered filtering proposals, an error is returned",
 			metadata: &mockMetadataStore{
@@ -161,7 +186,7 @@ func TestAddConditionalSamplesHook_RunHook(t *testing.T) {
 			observation := &ocr2keepers.AutomationObservation{
 				UpkeepProposals: tc.proposals,
 			}
-			err := processor.RunHook(observation, tc.limit, tc.src)
+			err := processor.RunHook(observation, tc.query, nil, tc.limit, tc.src)
 			if tc.expectErr {
 				assert.Error(t, err)
 				assert.Equal(t, tc.wantErr.Error(), err.Error())
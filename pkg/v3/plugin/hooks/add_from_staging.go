@@ -4,13 +4,13 @@ import (
 	"bytes"
 	"fmt"
 	"log"
-	"math"
 	"sort"
 	"sync"
 
 	"github.com/smartcontractkit/chainlink-common/pkg/types/automation"
 
 	ocr2keepersv3 "github.com/smartcontractkit/chainlink-automation/pkg/v3"
+	"github.com/smartcontractkit/chainlink-automation/pkg/v3/prommetrics"
 	"github.com/smartcontractkit/chainlink-automation/pkg/v3/random"
 	"github.com/smartcontractkit/chainlink-automation/pkg/v3/telemetry"
 	"github.com/smartcontractkit/chainlink-automation/pkg/v3/types"
@@ -20,16 +20,21 @@ type AddFromStagingHook struct {
 	store  types.ResultStore
 	logger *log.Logger
 	coord  types.Coordinator
-	sorter stagedResultSorter
+	sorter *stagedResultSorter
 }
 
-func NewAddFromStagingHook(store types.ResultStore, coord types.Coordinator, logger *log.Logger) AddFromStagingHook {
+// NewAddFromStagingHook creates a hook that adds staged results to observations.
+// Results are grouped in windows of starvationThreshold check blocks and
+// results of older windows are added ahead of results of newer windows.
+func NewAddFromStagingHook(store types.ResultStore, coord types.Coordinator, starvationThreshold int, logger *log.Logger) AddFromStagingHook {
 	return AddFromStagingHook{
 		store:  store,
 		coord:  coord,
 		logger: log.New(logger.Writer(), fmt.Sprintf("[%s | build hook:add-from-staging]", telemetry.ServiceName), telemetry.LogPkgStdFlags),
-		sorter: stagedResultSorter{
-			shuffledIDs: make(map[string]string),
+		sorter: &stagedResultSorter{
+			shuffledIDs:         make(map[string]string),
+			skipCounts:          make(map[string]int),
+			starvationThreshold: starvationThreshold,
 		},
 	}
 }
@@ -39,8 +44,17 @@ func NewAddFromStagingHook(store types.ResultStore, coord types.Coordinator, log
 // that is the same across all nodes for a given round. This ensures that all nodes try to
 // send the same subset of workIDs if they are available, while giving different priority
 // to workIDs in different rounds.
-func (hook *AddFromStagingHook) RunHook(obs *ocr2keepersv3.AutomationObservation, limit int, rSrc [16]byte) error {
-	results, err := hook.store.View()
+// Since the random source only changes every few rounds, the same results can be left out
+// of several observations in a row. To keep them from starving, results are first ordered
+// by the window of check blocks they fall in, oldest window first. The window only depends
+// on the result itself, so all nodes holding a result order it the same way.
+// Results the leader reported as in flight in the query are left out, up to
+// the share of the limit that leader hints can suppress.
+// Results are added up to the limit, as far as they fit in the observation budget.
+func (hook *AddFromStagingHook) RunHook(obs *ocr2keepersv3.AutomationObservation, query ocr2keepersv3.AutomationQuery, budget *ocr2keepersv3.ObservationBudget, limit int, rSrc [16]byte) error {
+	// the shared view is not modified, the results are copied before they
+	// are ordered
+	results, err := hook.store.ViewShared()
 	if err != nil {
 		return err
 	}
@@ -49,71 +63,119 @@ func (hook *AddFromStagingHook) RunHook(obs *ocr2keepersv3.AutomationObservation
 		return err
 	}
 
-	b, err := obs.Encode()
-	if err != nil {
-		return err
-	}
-
 	results = hook.sorter.orderResults(results, rSrc)
-	added, _ := hook.addByPercentageExceeded(obs, limit, results, len(b))
+	results = trimResultsByQuery(results, query, limit)
+	added := hook.addWithinBudget(obs, budget, limit, results)
 
-	hook.logger.Printf("skipped %d available results in staging", len(results)-added)
+	starved, maxSkips := hook.sorter.recordSkipped(results, added)
+	prommetrics.AutomationStagedResults.WithLabelValues(prommetrics.StagedResultStateSkipped).Set(float64(len(results) - added))
+	prommetrics.AutomationStagedResults.WithLabelValues(prommetrics.StagedResultStateStarved).Set(float64(starved))
+	prommetrics.AutomationStagedResultMaxSkipCount.Set(float64(maxSkips))
+
+	hook.logger.Printf("skipped %d available results in staging, %d of which are starved", len(results)-added, starved)
 
 	hook.logger.Printf("adding %d results to observation", added)
 
 	return nil
 }
 
-func (hook *AddFromStagingHook) addByPercentageExceeded(obs *ocr2keepersv3.AutomationObservation, limit int, results []automation.CheckResult, baseSize int) (int, int) {
+// addWithinBudget sets the first results as the performables of the
+// observation, up to the limit and as far as they fit in the budget. It
+// returns the number of results added.
+func (hook *AddFromStagingHook) addWithinBudget(obs *ocr2keepersv3.AutomationObservation, budget *ocr2keepersv3.ObservationBudget, limit int, results []automation.CheckResult) int {
 	if limit > len(results) {
 		limit = len(results)
 	}
 
 	if limit <= 0 {
-		return len(obs.Performable), 0
+		return len(obs.Performable)
 	}
 
-	obs.Performable = results[:limit]
-
-	encodingCalls := 1
-	b, _ := obs.Encode()
-
-	if observationSize := len(b); observationSize > ocr2keepersv3.MaxObservationLength {
-		performablesSize := observationSize - baseSize
-		avgPerformableSize := performablesSize / limit
-		exceededBy := observationSize - ocr2keepersv3.MaxObservationLength
-		avgPerformablesExceeded := int(math.Ceil(float64(exceededBy) / float64(avgPerformableSize)))
-		limit -= avgPerformablesExceeded + 1 // ensure we always remove at least one performable on the next call
-		if limit <= 0 {
-			return len(obs.Performable), encodingCalls
-		}
-		added, numEncodings := hook.addByPercentageExceeded(obs, limit, results, baseSize)
-		return added, numEncodings + encodingCalls
+	obs.Performable = budget.TakePerformables(results[:limit])
+	if len(obs.Performable) < limit {
+		hook.logger.Printf("observation budget exceeded, leaving out %d results within the limit", limit-len(obs.Performable))
 	}
-
-	return len(obs.Performable), encodingCalls
+	return len(obs.Performable)
 }
 
 type stagedResultSorter struct {
 	lastRandSrc [16]byte
 	shuffledIDs map[string]string
-	lock        sync.Mutex
+	// skipCounts holds the number of consecutive observations each staged
+	// workID has been left out of. They are local to the node and only used
+	// for metrics, never for ordering.
+	skipCounts          map[string]int
+	starvationThreshold int
+	lock                sync.Mutex
 }
 
-// orderResults orders the results by the shuffled workID
-func (sorter *stagedResultSorter) orderResults(results []automation.CheckResult, rSrc [16]byte) []automation.CheckResult {
+// orderResults returns a copy of the results ordered by check block window,
+// oldest first. Ties are broken by the shuffled workID. The provided results
+// can be shared with the result store and are left as they are.
+func (sorter *stagedResultSorter) orderResults(viewed []automation.CheckResult, rSrc [16]byte) []automation.CheckResult {
 	sorter.lock.Lock()
 	defer sorter.lock.Unlock()
 
+	results := make([]automation.CheckResult, len(viewed))
+	copy(results, viewed)
+
 	shuffledIDs := sorter.updateShuffledIDs(results, rSrc)
-	// sort by the shuffled workID
 	sort.Slice(results, func(i, j int) bool {
+		iWindow, jWindow := sorter.checkWindow(results[i]), sorter.checkWindow(results[j])
+		if iWindow != jWindow {
+			return iWindow < jWindow
+		}
+		// sort by the shuffled workID
 		return shuffledIDs[results[i].WorkID] < shuffledIDs[results[j].WorkID]
 	})
 
 	return results
 }
 
+// checkWindow returns the window of starvationThreshold check blocks the
+// result falls in
+func (sorter *stagedResultSorter) checkWindow(result automation.CheckResult) uint64 {
+	size := uint64(1)
+	if sorter.starvationThreshold > 1 {
+		size = uint64(sorter.starvationThreshold)
+	}
+	return uint64(result.Trigger.BlockNumber) / size
+}
+
+// recordSkipped resets the skip count of the first added results and increments
+// the skip count of the remaining results. Results no longer in staging are
+// forgotten. It returns the number of starved results, left out results of an
+// older check block window than the newest staged result, and the highest skip
+// count.
+func (sorter *stagedResultSorter) recordSkipped(results []automation.CheckResult, added int) (int, int) {
+	sorter.lock.Lock()
+	defer sorter.lock.Unlock()
+
+	var newestWindow uint64
+	for _, result := range results {
+		if window := sorter.checkWindow(result); window > newestWindow {
+			newestWindow = window
+		}
+	}
+
+	skipCounts := make(map[string]int, len(results)-added)
+	starved, maxSkips := 0, 0
+	for _, result := range results[added:] {
+		skips := sorter.skipCounts[result.WorkID] + 1
+		skipCounts[result.WorkID] = skips
+
+		if sorter.checkWindow(result) < newestWindow {
+			starved++
+		}
+		if skips > maxSkips {
+			maxSkips = skips
+		}
+	}
+	sorter.skipCounts = skipCounts
+
+	return starved, maxSkips
+}
+
 // updateShuffledIDs updates the shuffledIDs cache with the new random source or items.
 // NOTE: This function is not thread-safe and should be called with a lock
 func (sorter *stagedResultSorter) updateShuffledIDs(results []automation.CheckResult, rSrc [16]byte) map[string]string {
@@ -3,14 +3,18 @@ package hooks
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"log"
 	"math/big"
 	"testing"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 
 	ocr2keepersv3 "github.com/smartcontractkit/chainlink-automation/pkg/v3"
+	"github.com/smartcontractkit/chainlink-automation/pkg/v3/config"
+	"github.com/smartcontractkit/chainlink-automation/pkg/v3/prommetrics"
 	"github.com/smartcontractkit/chainlink-automation/pkg/v3/random"
 	"github.com/smartcontractkit/chainlink-automation/pkg/v3/types/mocks"
 	types "github.com/smartcontractkit/chainlink-common/pkg/types/automation"
@@ -147,7 +151,7 @@ func TestAddFromStagingHook_RunHook(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			// Prepare mock result store
 			mockResultStore := &mocks.MockResultStore{}
-			mockResultStore.On("View").Return(tt.resultStoreResults, tt.resultStoreErr)
+			mockResultStore.On("ViewShared").Return(tt.resultStoreResults, tt.resultStoreErr)
 
 			// Prepare mock coordinator
 			mockCoordinator := &mocks.MockCoordinator{}
@@ -161,10 +165,10 @@ func TestAddFromStagingHook_RunHook(t *testing.T) {
 			obs := &tt.initialObservation
 
 			// Create the hook with mock result store, coordinator, and logger
-			addFromStagingHook := NewAddFromStagingHook(mockResultStore, mockCoordinator, logger)
+			addFromStagingHook := NewAddFromStagingHook(mockResultStore, mockCoordinator, config.DefaultStagedResultStarvationThreshold, logger)
 
 			// Run the hook
-			err := addFromStagingHook.RunHook(obs, tt.limit, tt.rSrc)
+			err := addFromStagingHook.RunHook(obs, ocr2keepersv3.AutomationQuery{}, nil, tt.limit, tt.rSrc)
 
 			if tt.expectedErr != nil {
 				// Assert that the hook function returns the expected error
@@ -214,22 +218,22 @@ func TestAddFromStagingHook_RunHook_Limits(t *testing.T) {
 			mockResultStore, mockCoordinator := getMocks(tt.n)
 			var logBuf bytes.Buffer
 			logger := log.New(&logBuf, "", 0)
-			addFromStagingHook := NewAddFromStagingHook(mockResultStore, mockCoordinator, logger)
+			addFromStagingHook := NewAddFromStagingHook(mockResultStore, mockCoordinator, config.DefaultStagedResultStarvationThreshold, logger)
 
 			rSrc := [16]byte{1, 1, 2, 2, 3, 3, 4, 4}
 			obs := &ocr2keepersv3.AutomationObservation{}
 
-			err := addFromStagingHook.RunHook(obs, tt.limit, rSrc)
+			err := addFromStagingHook.RunHook(obs, ocr2keepersv3.AutomationQuery{}, nil, tt.limit, rSrc)
 			assert.NoError(t, err)
 			assert.Len(t, obs.Performable, tt.expected)
 
 			// Run the hook again with the same random source
 			// and assert that the results are the same
 			mockResultStore2, mockCoordinator2 := getMocks(tt.n)
-			addFromStagingHook2 := NewAddFromStagingHook(mockResultStore2, mockCoordinator2, logger)
+			addFromStagingHook2 := NewAddFromStagingHook(mockResultStore2, mockCoordinator2, config.DefaultStagedResultStarvationThreshold, logger)
 
 			obs2 := &ocr2keepersv3.AutomationObservation{}
-			err2 := addFromStagingHook2.RunHook(obs2, tt.limit, rSrc)
+			err2 := addFromStagingHook2.RunHook(obs2, ocr2keepersv3.AutomationQuery{}, nil, tt.limit, rSrc)
 			assert.NoError(t, err2)
 			assert.Len(t, obs.Performable, tt.expected)
 			assert.Equal(t, obs.Performable, obs2.Performable)
@@ -237,6 +241,57 @@ func TestAddFromStagingHook_RunHook_Limits(t *testing.T) {
 	}
 }
 
+func TestAddFromStagingHook_RunHook_Starvation(t *testing.T) {
+	results := make([]types.CheckResult, 10)
+	for i := range results {
+		// results 0 to 4 are checked in the window of blocks 10 to 11 and
+		// results 5 to 9 in the window of blocks 12 to 13
+		results[i] = types.CheckResult{UpkeepID: [32]byte{uint8(i)}, WorkID: fmt.Sprintf("10%d", i), Trigger: types.Trigger{BlockNumber: types.BlockNumber(10 + i/5*2 + i%2)}}
+	}
+	newHook := func() AddFromStagingHook {
+		mockResultStore := &mocks.MockResultStore{}
+		mockResultStore.On("ViewShared").Return(results, nil)
+		mockCoordinator := &mocks.MockCoordinator{}
+		mockCoordinator.On("FilterResults", mock.Anything).Return(results, nil)
+		return NewAddFromStagingHook(mockResultStore, mockCoordinator, 2, log.New(io.Discard, "", 0))
+	}
+
+	rSrc := [16]byte{1, 1, 2, 2, 3, 3, 4, 4}
+	runHook := func(hook AddFromStagingHook, limit int) []string {
+		obs := &ocr2keepersv3.AutomationObservation{}
+		assert.NoError(t, hook.RunHook(obs, ocr2keepersv3.AutomationQuery{}, nil, limit, rSrc))
+
+		workIDs := []string{}
+		for _, r := range obs.Performable {
+			workIDs = append(workIDs, r.WorkID)
+		}
+		return workIDs
+	}
+
+	hook := newHook()
+	first := runHook(hook, 3)
+	assert.Len(t, first, 3)
+	for _, id := range first {
+		assert.Contains(t, []string{"100", "101", "102", "103", "104"}, id, "results of the older window are added first")
+	}
+
+	// the same results are added on every node regardless of how often a node
+	// left results out before
+	assert.Equal(t, first, runHook(hook, 3))
+
+	// the remaining results of the older window are starved
+	assert.Equal(t, float64(2), testutil.ToFloat64(prommetrics.AutomationStagedResults.WithLabelValues(prommetrics.StagedResultStateStarved)))
+	assert.Equal(t, float64(7), testutil.ToFloat64(prommetrics.AutomationStagedResults.WithLabelValues(prommetrics.StagedResultStateSkipped)))
+	assert.Equal(t, float64(2), testutil.ToFloat64(prommetrics.AutomationStagedResultMaxSkipCount))
+
+	assert.Equal(t, first, runHook(newHook(), 3))
+
+	// all results of the older window are added ahead of the newer window
+	all := runHook(hook, 10)
+	assert.ElementsMatch(t, []string{"100", "101", "102", "103", "104"}, all[:5])
+	assert.Equal(t, float64(0), testutil.ToFloat64(prommetrics.AutomationStagedResultMaxSkipCount))
+}
+
 func TestAddFromStagingHook_stagedResultSorter(t *testing.T) {
 	tests := []struct {
 		name                string
@@ -337,7 +392,13 @@ func TestAddFromStagingHook_stagedResultSorter(t *testing.T) {
 				sorter.lastRandSrc = tc.lastRandSrc
 			}
 
+			input := make([]types.CheckResult, len(tc.input))
+			copy(input, tc.input)
 			results := sorter.orderResults(tc.input, tc.rSrc)
+			// the input can be shared with the result store and is not reordered
+			for i := range input {
+				assert.Equal(t, input[i].WorkID, tc.input[i].WorkID)
+			}
 			assert.Equal(t, len(tc.expected), len(results))
 			for i := range results {
 				assert.Equal(t, tc.expected[i].WorkID, results[i].WorkID)
@@ -353,8 +414,8 @@ func TestAddFromStagingHook_stagedResultSorter(t *testing.T) {
 	}
 }
 
-func TestAddByEstimate(t *testing.T) {
-	var hook AddFromStagingHook
+func TestAddWithinBudget(t *testing.T) {
+	hook := NewAddFromStagingHook(nil, nil, config.DefaultStagedResultStarvationThreshold, log.New(io.Discard, "", 0))
 
 	var blockHistory types.BlockHistory
 	for i := 0; i < ocr2keepersv3.ObservationBlockHistoryLimit; i++ {
@@ -368,7 +429,7 @@ func TestAddByEstimate(t *testing.T) {
 
 	var proposals []types.CoordinatedBlockProposal
 
-	proposalsToAdd := ocr2keepersv3.ObservationLogRecoveryProposalsLimit + ocr2keepersv3.ObservationConditionalsProposalsLimit
+	proposalsToAdd := config.DefaultProtocolLimitsV0.ObservationLogRecoveryProposalsLimit + config.DefaultProtocolLimitsV0.ObservationConditionalsProposalsLimit
 	for i := 0; i < proposalsToAdd; i++ {
 		proposals = append(proposals, types.CoordinatedBlockProposal{
 			UpkeepID: [32]byte{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255},
@@ -386,59 +447,95 @@ func TestAddByEstimate(t *testing.T) {
 		})
 	}
 
-	t.Run("Add up to 100 lightly populated performables if we have capacity", func(t *testing.T) {
+	newObservation := func() (*ocr2keepersv3.AutomationObservation, *ocr2keepersv3.ObservationBudget) {
+		limits := config.DefaultProtocolLimitsV0
+		budget := ocr2keepersv3.NewObservationBudget(ocr2keepersv3.MaxObservationLength, limits, config.CodecVersionBinary)
 		observation := &ocr2keepersv3.AutomationObservation{
-			UpkeepProposals: proposals,
-			BlockHistory:    blockHistory,
+			BlockHistory: budget.TakeBlockHistory(blockHistory),
 		}
+		observation.UpkeepProposals = append(observation.UpkeepProposals, budget.TakeProposals(ocr2keepersv3.ObservationSectionLogProposals, proposals[:limits.ObservationLogRecoveryProposalsLimit])...)
+		observation.UpkeepProposals = append(observation.UpkeepProposals, budget.TakeProposals(ocr2keepersv3.ObservationSectionConditionalProposals, proposals[limits.ObservationLogRecoveryProposalsLimit:])...)
+		assert.Len(t, observation.BlockHistory, len(blockHistory))
+		assert.Len(t, observation.UpkeepProposals, len(proposals))
+		return observation, budget
+	}
 
-		b, _ := observation.Encode()
+	t.Run("Add up to 100 lightly populated performables if we have capacity", func(t *testing.T) {
+		observation, budget := newObservation()
 
 		results := buildResults(1000, 500)
 
-		added, encodings := hook.addByPercentageExceeded(observation, ocr2keepersv3.ObservationPerformablesLimit, results, len(b))
-		assert.Equal(t, ocr2keepersv3.ObservationPerformablesLimit, added)
-		assert.Equal(t, 1, encodings)
+		added := hook.addWithinBudget(observation, budget, config.DefaultProtocolLimitsV0.ObservationPerformablesLimit, results)
+		assert.Equal(t, config.DefaultProtocolLimitsV0.ObservationPerformablesLimit, added)
 
-		b, err := observation.Encode()
+		b, err := observation.EncodeWithCodec(config.CodecVersionBinary)
 		assert.NoError(t, err)
 		assert.LessOrEqual(t, len(b), ocr2keepersv3.MaxObservationLength)
-		assert.Equal(t, len(b), 210508)
+		assert.Equal(t, len(b), 85570)
 	})
 
 	t.Run("Add up to 100 heavily populated performables if we have capacity", func(t *testing.T) {
-		observation := &ocr2keepersv3.AutomationObservation{
-			UpkeepProposals: proposals,
-			BlockHistory:    blockHistory,
-		}
+		observation, budget := newObservation()
 
-		b, _ := observation.Encode()
+		results := buildResults(1000, 10000)
+
+		added := hook.addWithinBudget(observation, budget, config.DefaultProtocolLimitsV0.ObservationPerformablesLimit, results)
+		assert.Equal(t, 96, added)
+
+		b, err := observation.EncodeWithCodec(config.CodecVersionBinary)
+		assert.NoError(t, err)
+		assert.LessOrEqual(t, len(b), ocr2keepersv3.MaxObservationLength)
+
+		single, err := ocr2keepersv3.AutomationObservation{Performable: results[:1]}.EncodeWithCodec(config.CodecVersionBinary)
+		assert.NoError(t, err)
+		assert.Greater(t, len(b)+len(single), ocr2keepersv3.MaxObservationLength, "no room is left for another performable")
+	})
+
+	t.Run("Unused block history and proposal budget is available to performables", func(t *testing.T) {
+		budget := ocr2keepersv3.NewObservationBudget(ocr2keepersv3.MaxObservationLength, config.DefaultProtocolLimitsV0, config.CodecVersionBinary)
+		observation := &ocr2keepersv3.AutomationObservation{}
+
+		results := buildResults(1000, 10000)
+
+		added := hook.addWithinBudget(observation, budget, 1000, results)
+		assert.Equal(t, 97, added)
+
+		b, err := observation.EncodeWithCodec(config.CodecVersionBinary)
+		assert.NoError(t, err)
+		assert.LessOrEqual(t, len(b), ocr2keepersv3.MaxObservationLength)
+	})
+
+	t.Run("Performables are sized in the json encoding with the json codec", func(t *testing.T) {
+		budget := ocr2keepersv3.NewObservationBudget(ocr2keepersv3.MaxObservationLength, config.DefaultProtocolLimitsV0, config.CodecVersionJSON)
+		observation := &ocr2keepersv3.AutomationObservation{}
 
 		results := buildResults(1000, 10000)
 
-		added, encodings := hook.addByPercentageExceeded(observation, ocr2keepersv3.ObservationPerformablesLimit, results, len(b))
-		assert.Equal(t, 65, added)
-		assert.Equal(t, 2, encodings)
+		added := hook.addWithinBudget(observation, budget, 1000, results)
+		assert.Less(t, added, 97, "json encoded performables are larger")
 
 		b, err := observation.Encode()
 		assert.NoError(t, err)
 		assert.LessOrEqual(t, len(b), ocr2keepersv3.MaxObservationLength)
-		assert.Equal(t, len(b), 976528)
+
+		observation.Performable = append(observation.Performable, results[added])
+		b, err = observation.Encode()
+		assert.NoError(t, err)
+		assert.Greater(t, len(b), ocr2keepersv3.MaxObservationLength, "no room is left for another performable")
 	})
 }
 
-func BenchmarkAddByPercentageExceded(b *testing.B) {
+func BenchmarkAddWithinBudget(b *testing.B) {
 	results := buildResults(1000, 10000)
-	var hook AddFromStagingHook
+	hook := NewAddFromStagingHook(nil, nil, config.DefaultStagedResultStarvationThreshold, log.New(io.Discard, "", 0))
 	observation := &ocr2keepersv3.AutomationObservation{}
 
 	b.ResetTimer()
 
 	for i := 0; i < b.N; i++ {
-		hook.addByPercentageExceeded(observation, 100, results, ocr2keepersv3.ObservationPerformablesLimit)
+		budget := ocr2keepersv3.NewObservationBudget(ocr2keepersv3.MaxObservationLength, config.DefaultProtocolLimitsV0, config.CodecVersionBinary)
+		hook.addWithinBudget(observation, budget, 100, results)
 	}
-
-	// ~5680891 ns/op
 }
 
 func buildResults(num, performDataSize int) []types.CheckResult {
@@ -485,7 +582,7 @@ func getMocks(n int) (*mocks.MockResultStore, *mocks.MockCoordinator) {
 		mockResults[i] = types.CheckResult{UpkeepID: [32]byte{uint8(i)}, WorkID: fmt.Sprintf("10%d", i)}
 	}
 	mockResultStore := &mocks.MockResultStore{}
-	mockResultStore.On("View").Return(mockResults, nil)
+	mockResultStore.On("ViewShared").Return(mockResults, nil)
 	mockCoordinator := &mocks.MockCoordinator{}
 	mockCoordinator.On("FilterResults", mock.Anything).Return(mockResults, nil)
 
@@ -25,7 +25,9 @@ func NewAddLogProposalsHook(metadataStore types.MetadataStore, coordinator types
 	}
 }
 
-func (h *AddLogProposalsHook) RunHook(obs *ocr2keepersv3.AutomationObservation, limit int, rSrc [16]byte) error {
+// RunHook adds a random subset of log recovery proposals to the observation,
+// as far as the limit and the observation budget allow.
+func (h *AddLogProposalsHook) RunHook(obs *ocr2keepersv3.AutomationObservation, query ocr2keepersv3.AutomationQuery, budget *ocr2keepersv3.ObservationBudget, limit int, rSrc [16]byte) error {
 	proposals := h.metadata.ViewProposals(types.LogTrigger)
 
 	var err error
@@ -40,10 +42,15 @@ func (h *AddLogProposalsHook) RunHook(obs *ocr2keepersv3.AutomationObservation,
 		proposals[i], proposals[j] = proposals[j], proposals[i]
 	})
 
+	// proposals the leader hints at are partly left out and otherwise only
+	// added if the limit leaves room
+	proposals = trimProposalsByQuery(proposals, query, limit)
+
 	// take first limit
 	if len(proposals) > limit {
 		proposals = proposals[:limit]
 	}
+	proposals = budget.TakeProposals(ocr2keepersv3.ObservationSectionLogProposals, proposals)
 
 	h.logger.Printf("adding %d log recovery proposals to observation", len(proposals))
 	obs.UpkeepProposals = append(obs.UpkeepProposals, proposals...)
@@ -139,7 +139,7 @@ func TestAddLogRecoveryProposalsHook_RunHook(t *testing.T) {
 			observation := &ocr2keepers.AutomationObservation{
 				UpkeepProposals: tc.proposals,
 			}
-			err := processor.RunHook(observation, tc.limit, tc.src)
+			err := processor.RunHook(observation, ocr2keepers.AutomationQuery{}, nil, tc.limit, tc.src)
 			if tc.expectErr {
 				assert.Error(t, err)
 				assert.Equal(t, tc.wantErr.Error(), err.Error())
@@ -179,6 +179,7 @@ type mockCoordinator struct {
 	FilterResultsFn   func([]commontypes.CheckResult) ([]commontypes.CheckResult, error)
 	ShouldAcceptFn    func(commontypes.ReportedUpkeep) bool
 	ShouldTransmitFn  func(commontypes.ReportedUpkeep) bool
+	InFlightWorkIDsFn func() []string
 }
 
 func (s *mockCoordinator) FilterProposals(p []commontypes.CoordinatedBlockProposal) ([]commontypes.CoordinatedBlockProposal, error) {
@@ -196,3 +197,7 @@ func (s *mockCoordinator) ShouldAccept(upkeep commontypes.ReportedUpkeep) bool {
 func (s *mockCoordinator) ShouldTransmit(upkeep commontypes.ReportedUpkeep) bool {
 	return s.ShouldTransmitFn(upkeep)
 }
+
+func (s *mockCoordinator) InFlightWorkIDs() []string {
+	return s.InFlightWorkIDsFn()
+}
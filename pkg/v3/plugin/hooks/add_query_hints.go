@@ -0,0 +1,40 @@
+package hooks
+
+import (
+	"fmt"
+	"log"
+
+	ocr2keepersv3 "github.com/smartcontractkit/chainlink-automation/pkg/v3"
+	"github.com/smartcontractkit/chainlink-automation/pkg/v3/telemetry"
+	"github.com/smartcontractkit/chainlink-automation/pkg/v3/types"
+)
+
+type AddQueryHintsHook struct {
+	metadata types.MetadataStore
+	coord    types.Coordinator
+	logger   *log.Logger
+}
+
+func NewAddQueryHintsHook(ms types.MetadataStore, coord types.Coordinator, logger *log.Logger) AddQueryHintsHook {
+	return AddQueryHintsHook{
+		metadata: ms,
+		coord:    coord,
+		logger:   log.New(logger.Writer(), fmt.Sprintf("[%s | build hook:add-query-hints]", telemetry.ServiceName), telemetry.LogPkgStdFlags),
+	}
+}
+
+// RunHook adds the latest block and the workIDs pending transmission, as known
+// to this node, to the query.
+func (h *AddQueryHintsHook) RunHook(query *ocr2keepersv3.AutomationQuery, inFlightLimit int) {
+	if latest, err := h.metadata.GetBlockHistory().Latest(); err == nil {
+		query.LatestBlock = latest
+	}
+
+	inFlight := h.coord.InFlightWorkIDs()
+	if len(inFlight) > inFlightLimit {
+		inFlight = inFlight[:inFlightLimit]
+	}
+	query.InFlightWorkIDs = inFlight
+
+	h.logger.Printf("adding latest block %d and %d in flight workIDs to query", query.LatestBlock.Number, len(inFlight))
+}
@@ -0,0 +1,73 @@
+package hooks
+
+import (
+	"github.com/smartcontractkit/chainlink-common/pkg/types/automation"
+
+	ocr2keepersv3 "github.com/smartcontractkit/chainlink-automation/pkg/v3"
+)
+
+// querySuppressionDivisor bounds the trust in leader hints. The hints are not
+// verified, so they can leave out at most a 1/querySuppressionDivisor share of
+// the limit of an observation section. A faulty leader can't keep a node from
+// adding the remaining items.
+const querySuppressionDivisor = 4
+
+// maxSuppressedByQuery returns the number of items leader hints can leave out
+// of an observation section with the provided limit
+func maxSuppressedByQuery(limit int) int {
+	return limit / querySuppressionDivisor
+}
+
+// trimProposalsByQuery leaves out proposals that are in flight according to
+// the leader or that the leader already proposes in its own observation, up to
+// the share of the limit hints can suppress. Any other hinted proposals are
+// moved behind all other proposals, such that they are only added if the limit
+// leaves room. The order within both groups is kept.
+func trimProposalsByQuery(proposals []automation.CoordinatedBlockProposal, query ocr2keepersv3.AutomationQuery, limit int) []automation.CoordinatedBlockProposal {
+	if len(query.InFlightWorkIDs) == 0 && len(query.UpkeepProposals) == 0 {
+		return proposals
+	}
+
+	inFlight := query.InFlight()
+	proposed := query.Proposed()
+	suppress := maxSuppressedByQuery(limit)
+
+	ordered := make([]automation.CoordinatedBlockProposal, 0, len(proposals))
+	hinted := make([]automation.CoordinatedBlockProposal, 0)
+	for _, proposal := range proposals {
+		if inFlight[proposal.WorkID] || proposed[proposal.WorkID] {
+			if suppress > 0 {
+				suppress--
+				continue
+			}
+			hinted = append(hinted, proposal)
+			continue
+		}
+		ordered = append(ordered, proposal)
+	}
+	return append(ordered, hinted...)
+}
+
+// trimResultsByQuery leaves out results that are in flight according to the
+// leader, up to the share of the limit hints can suppress. Results pending
+// transmission on this node are already removed by the coordinator. The
+// results are expected in the order shared by all nodes, such that all nodes
+// leave out the same results.
+func trimResultsByQuery(results []automation.CheckResult, query ocr2keepersv3.AutomationQuery, limit int) []automation.CheckResult {
+	if len(query.InFlightWorkIDs) == 0 {
+		return results
+	}
+
+	inFlight := query.InFlight()
+	suppress := maxSuppressedByQuery(limit)
+
+	trimmed := make([]automation.CheckResult, 0, len(results))
+	for _, result := range results {
+		if suppress > 0 && inFlight[result.WorkID] {
+			suppress--
+			continue
+		}
+		trimmed = append(trimmed, result)
+	}
+	return trimmed
+}
@@ -0,0 +1,48 @@
+package hooks
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	ocr2keepersv3 "github.com/smartcontractkit/chainlink-automation/pkg/v3"
+	"github.com/smartcontractkit/chainlink-common/pkg/types/automation"
+)
+
+func TestTrimProposalsByQuery(t *testing.T) {
+	proposals := []automation.CoordinatedBlockProposal{{WorkID: "workID1"}, {WorkID: "workID2"}, {WorkID: "workID3"}, {WorkID: "workID4"}}
+	query := ocr2keepersv3.AutomationQuery{
+		InFlightWorkIDs: []string{"workID1"},
+		UpkeepProposals: []automation.CoordinatedBlockProposal{{WorkID: "workID3"}},
+	}
+
+	t.Run("an empty query keeps the order of the proposals", func(t *testing.T) {
+		assert.Equal(t, proposals, trimProposalsByQuery(proposals, ocr2keepersv3.AutomationQuery{}, 4))
+	})
+
+	t.Run("hinted workIDs are left out up to a share of the limit", func(t *testing.T) {
+		assert.Equal(t, []automation.CoordinatedBlockProposal{{WorkID: "workID2"}, {WorkID: "workID4"}, {WorkID: "workID3"}}, trimProposalsByQuery(proposals, query, 4))
+		assert.Equal(t, []automation.CoordinatedBlockProposal{{WorkID: "workID2"}, {WorkID: "workID4"}}, trimProposalsByQuery(proposals, query, 8))
+	})
+
+	t.Run("hinted workIDs that can't be left out are ordered last", func(t *testing.T) {
+		assert.Equal(t, []automation.CoordinatedBlockProposal{{WorkID: "workID2"}, {WorkID: "workID4"}, {WorkID: "workID1"}, {WorkID: "workID3"}}, trimProposalsByQuery(proposals, query, 3))
+	})
+}
+
+func TestTrimResultsByQuery(t *testing.T) {
+	results := []automation.CheckResult{{WorkID: "workID1"}, {WorkID: "workID2"}, {WorkID: "workID3"}, {WorkID: "workID4"}}
+
+	t.Run("an empty query keeps all results", func(t *testing.T) {
+		assert.Equal(t, results, trimResultsByQuery(results, ocr2keepersv3.AutomationQuery{}, 8))
+	})
+
+	t.Run("workIDs in flight according to the leader are left out up to a share of the limit", func(t *testing.T) {
+		query := ocr2keepersv3.AutomationQuery{
+			InFlightWorkIDs: []string{"workID2", "workID3", "workID4"},
+			UpkeepProposals: []automation.CoordinatedBlockProposal{{WorkID: "workID1"}},
+		}
+		assert.Equal(t, []automation.CheckResult{{WorkID: "workID1"}, {WorkID: "workID4"}}, trimResultsByQuery(results, query, 8))
+		assert.Equal(t, results, trimResultsByQuery(results, query, 3))
+	})
+}
@@ -88,3 +88,7 @@ func (m *mockResultStore) Add(...types.CheckResult) {
 func (m *mockResultStore) View() ([]types.CheckResult, error) {
 	return nil, nil
 }
+
+func (m *mockResultStore) ViewShared() ([]types.CheckResult, error) {
+	return nil, nil
+}
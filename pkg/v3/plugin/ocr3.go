@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log"
 
+	"github.com/smartcontractkit/libocr/commontypes"
 	"github.com/smartcontractkit/libocr/offchainreporting2plus/ocr3types"
 	ocr2plustypes "github.com/smartcontractkit/libocr/offchainreporting2plus/types"
 	"github.com/smartcontractkit/libocr/quorumhelper"
@@ -21,11 +22,17 @@ import (
 	"github.com/smartcontractkit/chainlink-automation/pkg/v3/types"
 )
 
-type AutomationReportInfo struct{}
+// AutomationReportInfo is attached to every report of the plugin
+type AutomationReportInfo struct {
+	// Registry is the name of the registry the report is encoded for. It is
+	// empty for a plugin instance that serves a single registry.
+	Registry string
+}
 
 type ocr3Plugin struct {
 	ConfigDigest                ocr2plustypes.ConfigDigest
 	ReportEncoder               ocr2keepers.Encoder
+	Registries                  *registryMux
 	Coordinator                 types.Coordinator
 	UpkeepTypeGetter            types.UpkeepTypeGetter
 	WorkIDGenerator             types.WorkIDGenerator
@@ -36,23 +43,60 @@ type ocr3Plugin struct {
 	AddFromStagingHook          hooks.AddFromStagingHook
 	AddConditionalProposalsHook hooks.AddConditionalProposalsHook
 	AddLogProposalsHook         hooks.AddLogProposalsHook
+	AddQueryHintsHook           hooks.AddQueryHintsHook
+	Scoreboard                  *oracleScoreboard
 	Services                    []service.Recoverable
 	Config                      config.OffchainConfig
+	OracleID                    commontypes.OracleID
 	N                           int
 	F                           int
 	Logger                      *log.Logger
+	// SaveSnapshot saves the state of the plugin when it is closed
+	SaveSnapshot func() error
 }
 
 func (plugin *ocr3Plugin) Query(ctx context.Context, outctx ocr3types.OutcomeContext) (ocr2plustypes.Query, error) {
-	return nil, nil
+	plugin.Logger.Printf("inside Query for seqNr %d", outctx.SeqNr)
+	query := ocr2keepersv3.AutomationQuery{Leader: plugin.OracleID}
+
+	plugin.AddQueryHintsHook.RunHook(&query, ocr2keepersv3.QueryInFlightWorkIDsLimit)
+
+	// The proposal hooks use a random source that is fixed for the round, so running them
+	// here produces the proposals this node adds to its own observation in the same round
+	proposals := ocr2keepersv3.AutomationObservation{}
+	budget := ocr2keepersv3.NewObservationBudget(ocr2keepersv3.MaxObservationLength, plugin.Config.ProtocolLimits, plugin.Config.CodecVersion)
+	if err := plugin.AddLogProposalsHook.RunHook(&proposals, ocr2keepersv3.AutomationQuery{}, budget, plugin.Config.ProtocolLimits.ObservationLogRecoveryProposalsLimit, getRandomKeySource(plugin.ConfigDigest, outctx.SeqNr)); err != nil {
+		plugin.Logger.Printf("failed to add log proposals to query in seqNr %d: %v", outctx.SeqNr, err)
+	}
+	if err := plugin.AddConditionalProposalsHook.RunHook(&proposals, ocr2keepersv3.AutomationQuery{}, budget, plugin.Config.ProtocolLimits.ObservationConditionalsProposalsLimit, getRandomKeySource(plugin.ConfigDigest, outctx.SeqNr)); err != nil {
+		plugin.Logger.Printf("failed to add conditional proposals to query in seqNr %d: %v", outctx.SeqNr, err)
+	}
+	query.UpkeepProposals = proposals.UpkeepProposals
+
+	plugin.Logger.Printf("built a query in seqNr %d with latest block %d, %d in flight workIDs and %d upkeep proposals", outctx.SeqNr, query.LatestBlock.Number, len(query.InFlightWorkIDs), len(query.UpkeepProposals))
+
+	return query.Encode()
 }
 
 func (plugin *ocr3Plugin) Observation(ctx context.Context, outctx ocr3types.OutcomeContext, query ocr2plustypes.Query) (ocr2plustypes.Observation, error) {
 	plugin.Logger.Printf("inside Observation for seqNr %d", outctx.SeqNr)
+
+	// Leader hints are only an optimization, an invalid query is ignored
+	automationQuery, err := ocr2keepersv3.DecodeAutomationQuery(query, plugin.UpkeepTypeGetter, plugin.WorkIDGenerator, plugin.Config.ProtocolLimits)
+	if err != nil {
+		plugin.Logger.Printf("ignoring invalid query in seqNr %d err %v", outctx.SeqNr, err)
+		prommetrics.AutomationPluginError.WithLabelValues(prommetrics.PluginStepObservation, prommetrics.PluginErrorTypeDecodeQuery).Inc()
+		automationQuery = ocr2keepersv3.AutomationQuery{}
+	}
+	if automationQuery.Leader == plugin.OracleID {
+		// the proposals in the query are the ones this node is about to add to its observation
+		automationQuery.UpkeepProposals = nil
+	}
+
 	// first round outcome will be nil or empty so no processing should be done
 	if outctx.PreviousOutcome != nil || len(outctx.PreviousOutcome) != 0 {
 		// Decode the outcome to AutomationOutcome
-		automationOutcome, err := ocr2keepersv3.DecodeAutomationOutcome(outctx.PreviousOutcome, plugin.UpkeepTypeGetter, plugin.WorkIDGenerator)
+		automationOutcome, err := ocr2keepersv3.DecodeAutomationOutcome(outctx.PreviousOutcome, plugin.UpkeepTypeGetter, plugin.WorkIDGenerator, plugin.Config.ProtocolLimits)
 		if err != nil {
 			prommetrics.AutomationPluginError.WithLabelValues(prommetrics.PluginStepObservation, prommetrics.PluginErrorTypeDecodeOutcome).Inc()
 			return nil, err
@@ -65,13 +109,16 @@ func (plugin *ocr3Plugin) Observation(ctx context.Context, outctx ocr3types.Outc
 	}
 	// Create new AutomationObservation
 	observation := ocr2keepersv3.AutomationObservation{}
+	// Every hook adds items within its share of the observation budget, any
+	// unused share is available to the hooks that run after it
+	budget := ocr2keepersv3.NewObservationBudget(ocr2keepersv3.MaxObservationLength, plugin.Config.ProtocolLimits, plugin.Config.CodecVersion)
 
-	plugin.AddBlockHistoryHook.RunHook(&observation, ocr2keepersv3.ObservationBlockHistoryLimit)
+	plugin.AddBlockHistoryHook.RunHook(&observation, automationQuery, budget, ocr2keepersv3.ObservationBlockHistoryLimit)
 
-	if err := plugin.AddLogProposalsHook.RunHook(&observation, ocr2keepersv3.ObservationLogRecoveryProposalsLimit, getRandomKeySource(plugin.ConfigDigest, outctx.SeqNr)); err != nil {
+	if err := plugin.AddLogProposalsHook.RunHook(&observation, automationQuery, budget, plugin.Config.ProtocolLimits.ObservationLogRecoveryProposalsLimit, getRandomKeySource(plugin.ConfigDigest, outctx.SeqNr)); err != nil {
 		return nil, err
 	}
-	if err := plugin.AddConditionalProposalsHook.RunHook(&observation, ocr2keepersv3.ObservationConditionalsProposalsLimit, getRandomKeySource(plugin.ConfigDigest, outctx.SeqNr)); err != nil {
+	if err := plugin.AddConditionalProposalsHook.RunHook(&observation, automationQuery, budget, plugin.Config.ProtocolLimits.ObservationConditionalsProposalsLimit, getRandomKeySource(plugin.ConfigDigest, outctx.SeqNr)); err != nil {
 		return nil, err
 	}
 
@@ -80,8 +127,8 @@ func (plugin *ocr3Plugin) Observation(ctx context.Context, outctx ocr3types.Outc
 	// the range of the randomness by dividing the seq number by 10
 	randSrcSeq := outctx.SeqNr / 10
 
-	// The AddFromStagingHook should always be the last hook that is called as it ensures the size constraints of the observation are met
-	if err := plugin.AddFromStagingHook.RunHook(&observation, ocr2keepersv3.ObservationPerformablesLimit, getRandomKeySource(plugin.ConfigDigest, randSrcSeq)); err != nil {
+	// The AddFromStagingHook should always be the last hook that is called as it uses the remainder of the observation budget
+	if err := plugin.AddFromStagingHook.RunHook(&observation, automationQuery, budget, plugin.Config.ProtocolLimits.ObservationPerformablesLimit, getRandomKeySource(plugin.ConfigDigest, randSrcSeq)); err != nil {
 		return nil, err
 	}
 	prommetrics.AutomationPluginPerformables.WithLabelValues(prommetrics.PluginStepResultStore).Set(float64(len(observation.Performable)))
@@ -90,7 +137,7 @@ func (plugin *ocr3Plugin) Observation(ctx context.Context, outctx ocr3types.Outc
 	prommetrics.AutomationPluginPerformables.WithLabelValues(prommetrics.PluginStepObservation).Set(float64(len(observation.Performable)))
 
 	// Encode the observation to bytes
-	return observation.Encode()
+	return observation.EncodeWithCodec(plugin.Config.CodecVersion)
 }
 
 func (plugin *ocr3Plugin) ObservationQuorum(ctx context.Context, outctx ocr3types.OutcomeContext, query ocr2plustypes.Query, aos []ocr2plustypes.AttributedObservation) (bool, error) {
@@ -99,24 +146,36 @@ func (plugin *ocr3Plugin) ObservationQuorum(ctx context.Context, outctx ocr3type
 
 func (plugin *ocr3Plugin) ValidateObservation(ctx context.Context, outctx ocr3types.OutcomeContext, query ocr2plustypes.Query, ao ocr2plustypes.AttributedObservation) error {
 	plugin.Logger.Printf("inside ValidateObservation for seqNr %d", outctx.SeqNr)
-	_, err := ocr2keepersv3.DecodeAutomationObservation(ao.Observation, plugin.UpkeepTypeGetter, plugin.WorkIDGenerator)
+	_, err := ocr2keepersv3.DecodeAutomationObservation(ao.Observation, plugin.UpkeepTypeGetter, plugin.WorkIDGenerator, plugin.Config.ProtocolLimits)
 	return err
 }
 
 func (plugin *ocr3Plugin) Outcome(ctx context.Context, outctx ocr3types.OutcomeContext, query ocr2plustypes.Query, attributedObservations []ocr2plustypes.AttributedObservation) (ocr3types.Outcome, error) {
 	plugin.Logger.Printf("inside Outcome for seqNr %d", outctx.SeqNr)
-	p := newPerformables(plugin.F+1, ocr2keepersv3.OutcomeAgreedPerformablesLimit, getRandomKeySource(plugin.ConfigDigest, outctx.SeqNr), plugin.Logger)
-	c := newCoordinatedBlockProposals(plugin.F+1, ocr2keepersv3.OutcomeSurfacedProposalsRoundHistoryLimit, ocr2keepersv3.OutcomeSurfacedProposalsLimit, getRandomKeySource(plugin.ConfigDigest, outctx.SeqNr), plugin.Logger)
-
+	limits := plugin.Config.ProtocolLimits
+	// with the json codec the reported history is only kept once enabled in the
+	// config, such that nodes agree with nodes of previous releases until then
+	reportedHistory := plugin.Config.CodecVersion == config.CodecVersionBinary || plugin.Config.ReportedHistory
+	p := newPerformables(newPerformablesQuorum(plugin.Config.Quorum, plugin.F), limits.OutcomeAgreedPerformablesLimit, getRandomKeySource(plugin.ConfigDigest, outctx.SeqNr), newPerformablesPriority(plugin.Config.PerformablesPriority), reportedHistory, plugin.Logger)
+	c := newCoordinatedBlockProposals(coordinatedBlockQuorum(plugin.Config.Quorum, plugin.F), plugin.Config.CoordinatedBlockConfirmations, limits.OutcomeSurfacedProposalsRoundHistoryLimit, limits.OutcomeSurfacedProposalsLimit, getRandomKeySource(plugin.ConfigDigest, outctx.SeqNr), plugin.Logger)
+
+	observations := make(map[commontypes.OracleID]ocr2keepersv3.AutomationObservation, len(attributedObservations))
 	for _, attributedObservation := range attributedObservations {
-		observation, err := ocr2keepersv3.DecodeAutomationObservation(attributedObservation.Observation, plugin.UpkeepTypeGetter, plugin.WorkIDGenerator)
+		plugin.Scoreboard.recordObservation(attributedObservation.Observer)
+		observation, err := ocr2keepersv3.DecodeAutomationObservation(attributedObservation.Observation, plugin.UpkeepTypeGetter, plugin.WorkIDGenerator, limits)
 		if err != nil {
 			plugin.Logger.Printf("invalid observation from oracle %d in seqNr %d err %v", attributedObservation.Observer, outctx.SeqNr, err)
 			prommetrics.AutomationPluginError.WithLabelValues(prommetrics.PluginStepOutcome, prommetrics.PluginErrorTypeInvalidOracleObservation).Inc()
+			if errors.Is(err, ocr2keepersv3.ErrInvalidObservation) {
+				plugin.Scoreboard.recordIssue(attributedObservation.Observer, prommetrics.OracleIssueValidation)
+			} else {
+				plugin.Scoreboard.recordIssue(attributedObservation.Observer, prommetrics.OracleIssueDecode)
+			}
 			// Ignore this observation and continue with further observations. It is expected we will get
 			// at least f+1 valid observations
 			continue
 		}
+		observations[attributedObservation.Observer] = observation
 
 		plugin.Logger.Printf("adding observation from oracle %d in sequence %d with %d performables, %d upkeep proposals and %d block history in seqNr %d",
 			attributedObservation.Observer, outctx.SeqNr, len(observation.Performable), len(observation.UpkeepProposals), len(observation.BlockHistory), outctx.SeqNr)
@@ -129,7 +188,7 @@ func (plugin *ocr3Plugin) Outcome(ctx context.Context, outctx ocr3types.OutcomeC
 	prevOutcome := ocr2keepersv3.AutomationOutcome{}
 	if outctx.PreviousOutcome != nil || len(outctx.PreviousOutcome) != 0 {
 		// Decode the outcome to AutomationOutcome
-		ao, err := ocr2keepersv3.DecodeAutomationOutcome(outctx.PreviousOutcome, plugin.UpkeepTypeGetter, plugin.WorkIDGenerator)
+		ao, err := ocr2keepersv3.DecodeAutomationOutcome(outctx.PreviousOutcome, plugin.UpkeepTypeGetter, plugin.WorkIDGenerator, limits)
 		if err != nil {
 			prommetrics.AutomationPluginError.WithLabelValues(prommetrics.PluginStepOutcome, prommetrics.PluginErrorTypeDecodeOutcome).Inc()
 			return nil, err
@@ -137,10 +196,21 @@ func (plugin *ocr3Plugin) Outcome(ctx context.Context, outctx ocr3types.OutcomeC
 		prevOutcome = ao
 	}
 
-	p.set(&outcome)
+	p.set(&outcome, prevOutcome)
 	// Important to maintain the order here. Performables should be set before creating new proposals
 	c.set(&outcome, prevOutcome)
 
+	plugin.Scoreboard.scoreObservations(observations, p, c)
+
+	agreed, rounds := len(outcome.AgreedPerformables), len(outcome.SurfacedProposals)
+	encoded, err := ocr2keepersv3.EncodeOutcomeWithinLength(&outcome, ocr2keepersv3.MaxOutcomeLength, plugin.Config.CodecVersion)
+	if err != nil {
+		return nil, err
+	}
+	if len(outcome.AgreedPerformables) < agreed || len(outcome.SurfacedProposals) < rounds {
+		plugin.Logger.Printf("left out %d performables and %d rounds of proposals to keep the outcome within %d bytes in seqNr %d", agreed-len(outcome.AgreedPerformables), rounds-len(outcome.SurfacedProposals), ocr2keepersv3.MaxOutcomeLength, outctx.SeqNr)
+	}
+
 	newProposals := 0
 	if len(outcome.SurfacedProposals) > 0 {
 		newProposals = len(outcome.SurfacedProposals[0])
@@ -148,7 +218,7 @@ func (plugin *ocr3Plugin) Outcome(ctx context.Context, outctx ocr3types.OutcomeC
 	plugin.Logger.Printf("returning outcome with %d performables and %d new proposals in seqNr %d", len(outcome.AgreedPerformables), newProposals, outctx.SeqNr)
 	prommetrics.AutomationPluginPerformables.WithLabelValues(prommetrics.PluginStepOutcome).Set(float64(len(outcome.AgreedPerformables)))
 
-	return outcome.Encode()
+	return encoded, nil
 }
 
 func (plugin *ocr3Plugin) Reports(ctx context.Context, seqNr uint64, raw ocr3types.Outcome) ([]ocr3types.ReportPlus[AutomationReportInfo], error) {
@@ -159,53 +229,24 @@ func (plugin *ocr3Plugin) Reports(ctx context.Context, seqNr uint64, raw ocr3typ
 		err     error
 	)
 
-	if outcome, err = ocr2keepersv3.DecodeAutomationOutcome(raw, plugin.UpkeepTypeGetter, plugin.WorkIDGenerator); err != nil {
+	if outcome, err = ocr2keepersv3.DecodeAutomationOutcome(raw, plugin.UpkeepTypeGetter, plugin.WorkIDGenerator, plugin.Config.ProtocolLimits); err != nil {
 		prommetrics.AutomationPluginError.WithLabelValues(prommetrics.PluginStepReports, prommetrics.PluginErrorTypeDecodeOutcome).Inc()
 		return nil, err
 	}
-	plugin.Logger.Printf("creating report from outcome with %d agreed performables; max batch size: %d; report gas limit %d", len(outcome.AgreedPerformables), plugin.Config.MaxUpkeepBatchSize, plugin.Config.GasLimitPerReport)
-
-	toPerform := []ocr2keepers.CheckResult{}
-	var gasUsed uint64
-	seenUpkeepIDs := make(map[string]bool)
+	plugin.Logger.Printf("creating report from outcome with %d agreed performables; max batch size: %d; report gas limit %d; packing strategy: %s", len(outcome.AgreedPerformables), plugin.Config.MaxUpkeepBatchSize, plugin.Config.GasLimitPerReport, plugin.Config.ReportPackingStrategy)
 
 	performablesAdded := 0
-	for i, result := range outcome.AgreedPerformables {
-		if len(toPerform) >= plugin.Config.MaxUpkeepBatchSize ||
-			gasUsed+result.GasAllocated+uint64(plugin.Config.GasOverheadPerUpkeep) > uint64(plugin.Config.GasLimitPerReport) ||
-			seenUpkeepIDs[result.UpkeepID.String()] {
-
-			// If report has reached capacity or has existing upkeepID, encode and append this report
-			report, err := plugin.getReportFromPerformables(toPerform)
+	for _, group := range plugin.groupByRegistry(outcome.AgreedPerformables) {
+		for _, toPerform := range packReports(group.performables, plugin.Config.ForRegistry(group.info.Registry)) {
+			report, err := plugin.getReportFromPerformables(group.info, toPerform)
 			if err != nil {
 				prommetrics.AutomationPluginError.WithLabelValues(prommetrics.PluginStepReports, prommetrics.PluginErrorTypeEncodeReport).Inc()
 				prommetrics.AutomationPluginPerformables.WithLabelValues(prommetrics.PluginStepReports).Set(0)
 				return reports, fmt.Errorf("error encountered while encoding: %w", err)
 			}
-			// append to reports and reset collection
 			reports = append(reports, ocr3types.ReportPlus[AutomationReportInfo]{ReportWithInfo: report})
 			performablesAdded += len(toPerform)
-			toPerform = []ocr2keepers.CheckResult{}
-			gasUsed = 0
-			seenUpkeepIDs = make(map[string]bool)
 		}
-
-		// Add the result to current report
-		gasUsed += result.GasAllocated + uint64(plugin.Config.GasOverheadPerUpkeep)
-		toPerform = append(toPerform, outcome.AgreedPerformables[i])
-		seenUpkeepIDs[result.UpkeepID.String()] = true
-	}
-
-	// if there are still values to add
-	if len(toPerform) > 0 {
-		report, err := plugin.getReportFromPerformables(toPerform)
-		if err != nil {
-			prommetrics.AutomationPluginError.WithLabelValues(prommetrics.PluginStepReports, prommetrics.PluginErrorTypeEncodeReport).Inc()
-			prommetrics.AutomationPluginPerformables.WithLabelValues(prommetrics.PluginStepReports).Set(0)
-			return reports, fmt.Errorf("error encountered while encoding: %w", err)
-		}
-		reports = append(reports, ocr3types.ReportPlus[AutomationReportInfo]{ReportWithInfo: report})
-		performablesAdded += len(toPerform)
 	}
 
 	plugin.Logger.Printf("%d reports created for sequence number %d", len(reports), seqNr)
@@ -215,7 +256,12 @@ func (plugin *ocr3Plugin) Reports(ctx context.Context, seqNr uint64, raw ocr3typ
 
 func (plugin *ocr3Plugin) ShouldAcceptAttestedReport(_ context.Context, seqNr uint64, report ocr3types.ReportWithInfo[AutomationReportInfo]) (bool, error) {
 	plugin.Logger.Printf("inside ShouldAcceptAttestedReport for seqNr %d", seqNr)
-	upkeeps, err := plugin.ReportEncoder.Extract(report.Report)
+	encoder, err := plugin.reportEncoder(report.Info)
+	if err != nil {
+		return false, err
+	}
+
+	upkeeps, err := encoder.Extract(report.Report)
 	if err != nil {
 		return false, err
 	}
@@ -238,7 +284,12 @@ func (plugin *ocr3Plugin) ShouldAcceptAttestedReport(_ context.Context, seqNr ui
 
 func (plugin *ocr3Plugin) ShouldTransmitAcceptedReport(_ context.Context, seqNr uint64, report ocr3types.ReportWithInfo[AutomationReportInfo]) (bool, error) {
 	plugin.Logger.Printf("inside ShouldTransmitAcceptedReport for seqNr %d", seqNr)
-	upkeeps, err := plugin.ReportEncoder.Extract(report.Report)
+	encoder, err := plugin.reportEncoder(report.Info)
+	if err != nil {
+		return false, err
+	}
+
+	upkeeps, err := encoder.Extract(report.Report)
 	if err != nil {
 		return false, err
 	}
@@ -266,6 +317,11 @@ func (plugin *ocr3Plugin) Close() error {
 		err = errors.Join(err, plugin.Services[i].Close())
 	}
 
+	// the snapshot is taken once the services stopped changing the state
+	if plugin.SaveSnapshot != nil {
+		err = errors.Join(err, plugin.SaveSnapshot())
+	}
+
 	return err
 }
 
@@ -280,13 +336,63 @@ func (plugin *ocr3Plugin) startServices() {
 	}
 }
 
-func (plugin *ocr3Plugin) getReportFromPerformables(toPerform []ocr2keepers.CheckResult) (ocr3types.ReportWithInfo[AutomationReportInfo], error) {
-	encoded, err := plugin.ReportEncoder.Encode(toPerform...)
+func (plugin *ocr3Plugin) getReportFromPerformables(info AutomationReportInfo, toPerform []ocr2keepers.CheckResult) (ocr3types.ReportWithInfo[AutomationReportInfo], error) {
+	encoder, err := plugin.reportEncoder(info)
+	if err != nil {
+		return ocr3types.ReportWithInfo[AutomationReportInfo]{}, err
+	}
+
+	encoded, err := encoder.Encode(toPerform...)
 	return ocr3types.ReportWithInfo[AutomationReportInfo]{
 		Report: ocr2plustypes.Report(encoded),
+		Info:   info,
 	}, err
 }
 
+// reportEncoder returns the encoder of the registry a report is for
+func (plugin *ocr3Plugin) reportEncoder(info AutomationReportInfo) (ocr2keepers.Encoder, error) {
+	if plugin.Registries == nil {
+		return plugin.ReportEncoder, nil
+	}
+
+	encoder, ok := plugin.Registries.encoder(info.Registry)
+	if !ok {
+		return nil, fmt.Errorf("no encoder for registry '%s'", info.Registry)
+	}
+
+	return encoder, nil
+}
+
+type registryPerformables struct {
+	info         AutomationReportInfo
+	performables []ocr2keepers.CheckResult
+}
+
+// groupByRegistry splits the performables by the registry they belong to, in
+// the order the registries are configured, such that every report only
+// contains upkeeps of a single registry
+func (plugin *ocr3Plugin) groupByRegistry(performables []ocr2keepers.CheckResult) []registryPerformables {
+	if plugin.Registries == nil {
+		return []registryPerformables{{performables: performables}}
+	}
+
+	groups := make([]registryPerformables, len(plugin.Registries.registries))
+	for i, registry := range plugin.Registries.registries {
+		groups[i].info.Registry = registry.Name
+	}
+
+	for _, performable := range performables {
+		i, _, ok := plugin.Registries.splitWorkID(performable.WorkID)
+		if !ok {
+			plugin.Logger.Printf("skipping performable with workID '%s' of an unknown registry", performable.WorkID)
+			continue
+		}
+		groups[i].performables = append(groups[i].performables, performable)
+	}
+
+	return groups
+}
+
 // Generates a randomness source derived from the config and seq # so
 // that it's the same across the network for the same round.
 // similar key building as libocr transmit selector.
@@ -5,19 +5,23 @@ import (
 	"context"
 	"encoding/hex"
 	"errors"
+	"fmt"
 	"io"
 	"log"
 	"math/big"
+	"os"
 	"strings"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/goccy/go-json"
+	"github.com/smartcontractkit/libocr/commontypes"
 	"github.com/smartcontractkit/libocr/offchainreporting2plus/ocr3types"
 	ocr2plustypes "github.com/smartcontractkit/libocr/offchainreporting2plus/types"
 	"github.com/stretchr/testify/assert"
 
 	ocr2keepers2 "github.com/smartcontractkit/chainlink-automation/pkg/v3"
+	"github.com/smartcontractkit/chainlink-automation/pkg/v3/config"
 	"github.com/smartcontractkit/chainlink-automation/pkg/v3/plugin/hooks"
 	"github.com/smartcontractkit/chainlink-automation/pkg/v3/service"
 	"github.com/smartcontractkit/chainlink-automation/pkg/v3/types"
@@ -25,10 +29,107 @@ import (
 )
 
 func TestOcr3Plugin_Query(t *testing.T) {
-	plugin := &ocr3Plugin{}
-	query, err := plugin.Query(context.Background(), ocr3types.OutcomeContext{})
-	assert.Nil(t, query)
-	assert.Nil(t, err)
+	conditionalID := ocr2keepers.UpkeepIdentifier{}
+	conditionalID.FromBigInt(big.NewInt(1))
+	proposal := ocr2keepers.CoordinatedBlockProposal{
+		UpkeepID: conditionalID,
+		WorkID:   mockWorkIDGenerator(conditionalID, ocr2keepers.Trigger{}),
+	}
+
+	metadataStore := &mockMetadataStore{
+		GetBlockHistoryFn: func() ocr2keepers.BlockHistory {
+			return ocr2keepers.BlockHistory{{Number: 5, Hash: [32]byte{5}}, {Number: 4, Hash: [32]byte{4}}}
+		},
+		ViewProposalsFn: func(upkeepType types.UpkeepType) []ocr2keepers.CoordinatedBlockProposal {
+			if upkeepType == types.ConditionTrigger {
+				return []ocr2keepers.CoordinatedBlockProposal{proposal}
+			}
+			return nil
+		},
+	}
+
+	coordinator := &mockCoordinator{
+		FilterProposalsFn: func(proposals []ocr2keepers.CoordinatedBlockProposal) ([]ocr2keepers.CoordinatedBlockProposal, error) {
+			return proposals, nil
+		},
+		FilterResultsFn: func(results []ocr2keepers.CheckResult) ([]ocr2keepers.CheckResult, error) {
+			return results, nil
+		},
+		InFlightWorkIDsFn: func() []string {
+			return []string{"workID2", "workID3"}
+		},
+	}
+
+	newPlugin := func(oracleID commontypes.OracleID, logger *log.Logger) *ocr3Plugin {
+		return &ocr3Plugin{
+			Config:                      config.OffchainConfig{ProtocolLimits: config.DefaultProtocolLimitsV0},
+			OracleID:                    oracleID,
+			UpkeepTypeGetter:            mockUpkeepTypeGetter,
+			WorkIDGenerator:             mockWorkIDGenerator,
+			AddBlockHistoryHook:         hooks.NewAddBlockHistoryHook(metadataStore, logger),
+			AddConditionalProposalsHook: hooks.NewAddConditionalProposalsHook(metadataStore, coordinator, logger),
+			AddLogProposalsHook:         hooks.NewAddLogProposalsHook(metadataStore, coordinator, logger),
+			AddQueryHintsHook:           hooks.NewAddQueryHintsHook(metadataStore, coordinator, logger),
+			AddFromStagingHook: hooks.NewAddFromStagingHook(&mockResultStore{
+				ViewFn: func() ([]ocr2keepers.CheckResult, error) {
+					return nil, nil
+				},
+			}, coordinator, config.DefaultStagedResultStarvationThreshold, logger),
+			Logger: logger,
+		}
+	}
+
+	t.Run("the leader adds its latest block, in flight workIDs and proposals to the query", func(t *testing.T) {
+		plugin := newPlugin(2, log.New(io.Discard, "", 0))
+
+		query, err := plugin.Query(context.Background(), ocr3types.OutcomeContext{SeqNr: 1})
+		assert.NoError(t, err)
+
+		automationQuery, err := ocr2keepers2.DecodeAutomationQuery(query, mockUpkeepTypeGetter, mockWorkIDGenerator, config.DefaultProtocolLimitsV0)
+		assert.NoError(t, err)
+		assert.Equal(t, commontypes.OracleID(2), automationQuery.Leader)
+		assert.Equal(t, ocr2keepers.BlockKey{Number: 5, Hash: [32]byte{5}}, automationQuery.LatestBlock)
+		assert.Equal(t, []string{"workID2", "workID3"}, automationQuery.InFlightWorkIDs)
+		assert.Equal(t, []ocr2keepers.CoordinatedBlockProposal{proposal}, automationQuery.UpkeepProposals)
+	})
+
+	t.Run("followers leave out proposals the leader already adds to its own observation", func(t *testing.T) {
+		leader := newPlugin(2, log.New(io.Discard, "", 0))
+		query, err := leader.Query(context.Background(), ocr3types.OutcomeContext{SeqNr: 1})
+		assert.NoError(t, err)
+
+		for _, tc := range []struct {
+			oracleID          commontypes.OracleID
+			expectedProposals int
+		}{
+			{oracleID: 2, expectedProposals: 1},
+			{oracleID: 3, expectedProposals: 0},
+		} {
+			plugin := newPlugin(tc.oracleID, log.New(io.Discard, "", 0))
+
+			observation, err := plugin.Observation(context.Background(), ocr3types.OutcomeContext{SeqNr: 1}, query)
+			assert.NoError(t, err)
+
+			decoded, err := ocr2keepers2.DecodeAutomationObservation(observation, mockUpkeepTypeGetter, mockWorkIDGenerator, config.DefaultProtocolLimitsV0)
+			assert.NoError(t, err)
+			assert.Len(t, decoded.UpkeepProposals, tc.expectedProposals)
+			assert.Len(t, decoded.BlockHistory, 2)
+		}
+	})
+
+	t.Run("an invalid query is ignored", func(t *testing.T) {
+		var logBuf bytes.Buffer
+		plugin := newPlugin(3, log.New(&logBuf, "", 0))
+
+		observation, err := plugin.Observation(context.Background(), ocr3types.OutcomeContext{SeqNr: 1}, ocr2plustypes.Query{0xff})
+		assert.NoError(t, err)
+		assert.True(t, strings.Contains(logBuf.String(), "ignoring invalid query in seqNr 1"))
+
+		decoded, err := ocr2keepers2.DecodeAutomationObservation(observation, mockUpkeepTypeGetter, mockWorkIDGenerator, config.DefaultProtocolLimitsV0)
+		assert.NoError(t, err)
+		assert.Len(t, decoded.UpkeepProposals, 1)
+		assert.Len(t, decoded.BlockHistory, 2)
+	})
 }
 
 func TestOcr3Plugin_Observation(t *testing.T) {
@@ -81,10 +182,11 @@ func TestOcr3Plugin_Observation(t *testing.T) {
 		}
 
 		plugin := &ocr3Plugin{
+			Config:                      config.OffchainConfig{ProtocolLimits: config.DefaultProtocolLimitsV0},
 			UpkeepTypeGetter:            mockUpkeepTypeGetter,
 			WorkIDGenerator:             mockWorkIDGenerator,
 			AddBlockHistoryHook:         hooks.NewAddBlockHistoryHook(metadataStore, logger),
-			AddFromStagingHook:          hooks.NewAddFromStagingHook(resultStore, coordinator, logger),
+			AddFromStagingHook:          hooks.NewAddFromStagingHook(resultStore, coordinator, config.DefaultStagedResultStarvationThreshold, logger),
 			AddConditionalProposalsHook: hooks.NewAddConditionalProposalsHook(metadataStore, coordinator, logger),
 			AddLogProposalsHook:         hooks.NewAddLogProposalsHook(metadataStore, coordinator, logger),
 			Logger:                      logger,
@@ -156,10 +258,11 @@ func TestOcr3Plugin_Observation(t *testing.T) {
 		}
 
 		plugin := &ocr3Plugin{
+			Config:                      config.OffchainConfig{ProtocolLimits: config.DefaultProtocolLimitsV0},
 			UpkeepTypeGetter:            mockUpkeepTypeGetter,
 			WorkIDGenerator:             mockWorkIDGenerator,
 			AddBlockHistoryHook:         hooks.NewAddBlockHistoryHook(metadataStore, logger),
-			AddFromStagingHook:          hooks.NewAddFromStagingHook(resultStore, coordinator, logger),
+			AddFromStagingHook:          hooks.NewAddFromStagingHook(resultStore, coordinator, config.DefaultStagedResultStarvationThreshold, logger),
 			AddConditionalProposalsHook: hooks.NewAddConditionalProposalsHook(metadataStore, coordinator, logger),
 			AddLogProposalsHook:         hooks.NewAddLogProposalsHook(metadataStore, coordinator, logger),
 			Logger:                      logger,
@@ -228,10 +331,11 @@ func TestOcr3Plugin_Observation(t *testing.T) {
 		}
 
 		plugin := &ocr3Plugin{
+			Config:                      config.OffchainConfig{ProtocolLimits: config.DefaultProtocolLimitsV0},
 			UpkeepTypeGetter:            mockUpkeepTypeGetter,
 			WorkIDGenerator:             mockWorkIDGenerator,
 			AddBlockHistoryHook:         hooks.NewAddBlockHistoryHook(metadataStore, logger),
-			AddFromStagingHook:          hooks.NewAddFromStagingHook(resultStore, coordinator, logger),
+			AddFromStagingHook:          hooks.NewAddFromStagingHook(resultStore, coordinator, config.DefaultStagedResultStarvationThreshold, logger),
 			AddConditionalProposalsHook: hooks.NewAddConditionalProposalsHook(metadataStore, coordinator, logger),
 			AddLogProposalsHook:         hooks.NewAddLogProposalsHook(metadataStore, coordinator, logger),
 			Logger:                      logger,
@@ -308,13 +412,14 @@ func TestOcr3Plugin_Observation(t *testing.T) {
 		}
 
 		plugin := &ocr3Plugin{
+			Config:                      config.OffchainConfig{ProtocolLimits: config.DefaultProtocolLimitsV0},
 			UpkeepTypeGetter:            mockUpkeepTypeGetter,
 			WorkIDGenerator:             mockWorkIDGenerator,
 			RemoveFromStagingHook:       hooks.NewRemoveFromStagingHook(resultStore, logger),
 			RemoveFromMetadataHook:      hooks.NewRemoveFromMetadataHook(metadataStore, logger),
 			AddToProposalQHook:          hooks.NewAddToProposalQHook(proposalQueue, logger),
 			AddBlockHistoryHook:         hooks.NewAddBlockHistoryHook(metadataStore, logger),
-			AddFromStagingHook:          hooks.NewAddFromStagingHook(resultStore, coordinator, logger),
+			AddFromStagingHook:          hooks.NewAddFromStagingHook(resultStore, coordinator, config.DefaultStagedResultStarvationThreshold, logger),
 			AddConditionalProposalsHook: hooks.NewAddConditionalProposalsHook(metadataStore, coordinator, logger),
 			AddLogProposalsHook:         hooks.NewAddLogProposalsHook(metadataStore, coordinator, logger),
 			Logger:                      logger,
@@ -408,6 +513,7 @@ func TestOcr3Plugin_Observation(t *testing.T) {
 		}
 
 		plugin := &ocr3Plugin{
+			Config:           config.OffchainConfig{ProtocolLimits: config.DefaultProtocolLimitsV0},
 			UpkeepTypeGetter: mockUpkeepTypeGetter,
 			WorkIDGenerator: func(identifier ocr2keepers.UpkeepIdentifier, trigger ocr2keepers.Trigger) string {
 				var triggerExtBytes []byte
@@ -422,7 +528,7 @@ func TestOcr3Plugin_Observation(t *testing.T) {
 			RemoveFromMetadataHook:      hooks.NewRemoveFromMetadataHook(metadataStore, logger),
 			AddToProposalQHook:          hooks.NewAddToProposalQHook(proposalQueue, logger),
 			AddBlockHistoryHook:         hooks.NewAddBlockHistoryHook(metadataStore, logger),
-			AddFromStagingHook:          hooks.NewAddFromStagingHook(resultStore, coordinator, logger),
+			AddFromStagingHook:          hooks.NewAddFromStagingHook(resultStore, coordinator, config.DefaultStagedResultStarvationThreshold, logger),
 			AddConditionalProposalsHook: hooks.NewAddConditionalProposalsHook(metadataStore, coordinator, logger),
 			AddLogProposalsHook:         hooks.NewAddLogProposalsHook(metadataStore, coordinator, logger),
 			Logger:                      logger,
@@ -522,6 +628,7 @@ func TestOcr3Plugin_Observation(t *testing.T) {
 		}
 
 		plugin := &ocr3Plugin{
+			Config:           config.OffchainConfig{ProtocolLimits: config.DefaultProtocolLimitsV0},
 			UpkeepTypeGetter: mockUpkeepTypeGetter,
 			WorkIDGenerator: func(identifier ocr2keepers.UpkeepIdentifier, trigger ocr2keepers.Trigger) string {
 				var triggerExtBytes []byte
@@ -536,7 +643,7 @@ func TestOcr3Plugin_Observation(t *testing.T) {
 			RemoveFromMetadataHook:      hooks.NewRemoveFromMetadataHook(metadataStore, logger),
 			AddToProposalQHook:          hooks.NewAddToProposalQHook(proposalQueue, logger),
 			AddBlockHistoryHook:         hooks.NewAddBlockHistoryHook(metadataStore, logger),
-			AddFromStagingHook:          hooks.NewAddFromStagingHook(resultStore, coordinator, logger),
+			AddFromStagingHook:          hooks.NewAddFromStagingHook(resultStore, coordinator, config.DefaultStagedResultStarvationThreshold, logger),
 			AddConditionalProposalsHook: hooks.NewAddConditionalProposalsHook(metadataStore, coordinator, logger),
 			AddLogProposalsHook:         hooks.NewAddLogProposalsHook(metadataStore, coordinator, logger),
 			Logger:                      logger,
@@ -626,6 +733,7 @@ func TestOcr3Plugin_Observation(t *testing.T) {
 		}
 
 		plugin := &ocr3Plugin{
+			Config:           config.OffchainConfig{ProtocolLimits: config.DefaultProtocolLimitsV0},
 			UpkeepTypeGetter: mockUpkeepTypeGetter,
 			WorkIDGenerator: func(identifier ocr2keepers.UpkeepIdentifier, trigger ocr2keepers.Trigger) string {
 				var triggerExtBytes []byte
@@ -640,7 +748,7 @@ func TestOcr3Plugin_Observation(t *testing.T) {
 			RemoveFromMetadataHook:      hooks.NewRemoveFromMetadataHook(metadataStore, logger),
 			AddToProposalQHook:          hooks.NewAddToProposalQHook(proposalQueue, logger),
 			AddBlockHistoryHook:         hooks.NewAddBlockHistoryHook(metadataStore, logger),
-			AddFromStagingHook:          hooks.NewAddFromStagingHook(resultStore, coordinator, logger),
+			AddFromStagingHook:          hooks.NewAddFromStagingHook(resultStore, coordinator, config.DefaultStagedResultStarvationThreshold, logger),
 			AddLogProposalsHook:         hooks.NewAddLogProposalsHook(metadataStore, coordinator, logger),
 			AddConditionalProposalsHook: hooks.NewAddConditionalProposalsHook(metadataStore, coordinator, logger),
 			Logger:                      logger,
@@ -739,6 +847,7 @@ func TestOcr3Plugin_Observation(t *testing.T) {
 		}
 
 		plugin := &ocr3Plugin{
+			Config:           config.OffchainConfig{ProtocolLimits: config.DefaultProtocolLimitsV0},
 			UpkeepTypeGetter: mockUpkeepTypeGetter,
 			WorkIDGenerator: func(identifier ocr2keepers.UpkeepIdentifier, trigger ocr2keepers.Trigger) string {
 				var triggerExtBytes []byte
@@ -753,7 +862,7 @@ func TestOcr3Plugin_Observation(t *testing.T) {
 			RemoveFromMetadataHook:      hooks.NewRemoveFromMetadataHook(metadataStore, logger),
 			AddToProposalQHook:          hooks.NewAddToProposalQHook(proposalQueue, logger),
 			AddBlockHistoryHook:         hooks.NewAddBlockHistoryHook(metadataStore, logger),
-			AddFromStagingHook:          hooks.NewAddFromStagingHook(resultStore, coordinator, logger),
+			AddFromStagingHook:          hooks.NewAddFromStagingHook(resultStore, coordinator, config.DefaultStagedResultStarvationThreshold, logger),
 			AddLogProposalsHook:         hooks.NewAddLogProposalsHook(metadataStore, coordinator, logger),
 			AddConditionalProposalsHook: hooks.NewAddConditionalProposalsHook(metadataStore, coordinator, logger),
 			Logger:                      logger,
@@ -868,6 +977,7 @@ func TestOcr3Plugin_Observation(t *testing.T) {
 		}
 
 		plugin := &ocr3Plugin{
+			Config:           config.OffchainConfig{ProtocolLimits: config.DefaultProtocolLimitsV0},
 			UpkeepTypeGetter: mockUpkeepTypeGetter,
 			WorkIDGenerator: func(identifier ocr2keepers.UpkeepIdentifier, trigger ocr2keepers.Trigger) string {
 				var triggerExtBytes []byte
@@ -882,7 +992,7 @@ func TestOcr3Plugin_Observation(t *testing.T) {
 			RemoveFromMetadataHook:      hooks.NewRemoveFromMetadataHook(metadataStore, logger),
 			AddToProposalQHook:          hooks.NewAddToProposalQHook(proposalQueue, logger),
 			AddBlockHistoryHook:         hooks.NewAddBlockHistoryHook(metadataStore, logger),
-			AddFromStagingHook:          hooks.NewAddFromStagingHook(resultStore, coordinator, logger),
+			AddFromStagingHook:          hooks.NewAddFromStagingHook(resultStore, coordinator, config.DefaultStagedResultStarvationThreshold, logger),
 			AddLogProposalsHook:         hooks.NewAddLogProposalsHook(metadataStore, coordinator, logger),
 			AddConditionalProposalsHook: hooks.NewAddConditionalProposalsHook(metadataStore, coordinator, logger),
 			Logger:                      logger,
@@ -919,6 +1029,7 @@ func TestOcr3Plugin_Observation(t *testing.T) {
 
 	t.Run("subsequent round processing, decoding an invalid previous outcome returns an error", func(t *testing.T) {
 		plugin := &ocr3Plugin{
+			Config: config.OffchainConfig{ProtocolLimits: config.DefaultProtocolLimitsV0},
 			Logger: log.New(io.Discard, "", 1),
 		}
 
@@ -944,7 +1055,7 @@ func TestOcr3Plugin_ValidateObservation(t *testing.T) {
 			name:        "validating an empty observation returns an error",
 			observation: ocr2plustypes.AttributedObservation{},
 			expectsErr:  true,
-			wantErr:     errors.New("invalid character '\x00' looking for beginning of value"),
+			wantErr:     errors.New("empty encoding"),
 		},
 		{
 			name: "successfully validates a well formed observation",
@@ -988,6 +1099,7 @@ func TestOcr3Plugin_ValidateObservation(t *testing.T) {
 	} {
 		t.Run(tc.name, func(t *testing.T) {
 			plugin := &ocr3Plugin{
+				Config:           config.OffchainConfig{ProtocolLimits: config.DefaultProtocolLimitsV0},
 				Logger:           log.New(io.Discard, "ocr3-validate-observation-test", log.Ldate),
 				UpkeepTypeGetter: mockUpkeepTypeGetter,
 				WorkIDGenerator:  tc.wg,
@@ -1069,7 +1181,7 @@ func TestOcr3Plugin_Outcome(t *testing.T) {
 			},
 			prevOutcome: ocr3types.Outcome([]byte(`invalid`)),
 			expectsErr:  true,
-			wantErr:     errors.New("invalid character 'i' looking for beginning of value"),
+			wantErr:     errors.New("unsupported outcome encoding version 105"),
 		},
 		{
 			name: "processing an valid observation with an invalid previous outcome returns an error",
@@ -1091,6 +1203,7 @@ func TestOcr3Plugin_Outcome(t *testing.T) {
 			logger := log.New(&logBuf, "ocr3-test-outcome", 0)
 
 			plugin := &ocr3Plugin{
+				Config:           config.OffchainConfig{ProtocolLimits: config.DefaultProtocolLimitsV0},
 				UpkeepTypeGetter: mockUpkeepTypeGetter,
 				WorkIDGenerator:  tc.wg,
 				Logger:           logger,
@@ -1128,7 +1241,7 @@ func TestOcr3Plugin_Reports(t *testing.T) {
 			sequenceNumber: 5,
 			outcome:        ocr3types.Outcome([]byte{}),
 			expectsErr:     true,
-			wantErr:        errors.New("invalid character '\x00' looking for beginning of value"),
+			wantErr:        errors.New("empty encoding"),
 		},
 		{
 			name:                "an empty json object generates a nil report",
@@ -1271,6 +1384,7 @@ func TestOcr3Plugin_Reports(t *testing.T) {
 			logger := log.New(&logBuf, "ocr3-test-reports", 0)
 
 			plugin := &ocr3Plugin{
+				Config:           config.OffchainConfig{ProtocolLimits: config.DefaultProtocolLimitsV0},
 				Logger:           logger,
 				ReportEncoder:    tc.encoder,
 				UpkeepTypeGetter: tc.utg,
@@ -1373,6 +1487,7 @@ func TestOcr3Plugin_ShouldAcceptAttestedReport(t *testing.T) {
 			logger := log.New(&logBuf, "ocr3-test-shouldAcceptAttestedReport", 0)
 
 			plugin := &ocr3Plugin{
+				Config:        config.OffchainConfig{ProtocolLimits: config.DefaultProtocolLimitsV0},
 				Logger:        logger,
 				ReportEncoder: tc.encoder,
 				Coordinator:   tc.coordinator,
@@ -1468,6 +1583,7 @@ func TestOcr3Plugin_ShouldTransmitAcceptedReport(t *testing.T) {
 			logger := log.New(&logBuf, "ocr3-test-shouldAcceptAttestedReport", 0)
 
 			plugin := &ocr3Plugin{
+				Config:        config.OffchainConfig{ProtocolLimits: config.DefaultProtocolLimitsV0},
 				Logger:        logger,
 				ReportEncoder: tc.encoder,
 				Coordinator:   tc.coordinator,
@@ -1490,6 +1606,7 @@ func TestOcr3Plugin_startServices(t *testing.T) {
 
 	startedCh := make(chan struct{}, 1)
 	plugin := &ocr3Plugin{
+		Config: config.OffchainConfig{ProtocolLimits: config.DefaultProtocolLimitsV0},
 		Logger: logger,
 		Services: []service.Recoverable{
 			&mockRecoverable{
@@ -1531,6 +1648,10 @@ func (s *mockResultStore) View() ([]ocr2keepers.CheckResult, error) {
 	return s.ViewFn()
 }
 
+func (s *mockResultStore) ViewShared() ([]ocr2keepers.CheckResult, error) {
+	return s.ViewFn()
+}
+
 func (s *mockResultStore) Remove(r ...string) {
 	s.RemoveFn(r...)
 }
@@ -1538,6 +1659,7 @@ func (s *mockResultStore) Remove(r ...string) {
 type mockProposalQueue struct {
 	EnqueueFn func(items ...ocr2keepers.CoordinatedBlockProposal) error
 	DequeueFn func(t types.UpkeepType, n int) ([]ocr2keepers.CoordinatedBlockProposal, error)
+	RequeueFn func(items ...ocr2keepers.CoordinatedBlockProposal) error
 }
 
 func (s *mockProposalQueue) Enqueue(items ...ocr2keepers.CoordinatedBlockProposal) error {
@@ -1548,6 +1670,10 @@ func (s *mockProposalQueue) Dequeue(t types.UpkeepType, n int) ([]ocr2keepers.Co
 	return s.DequeueFn(t, n)
 }
 
+func (s *mockProposalQueue) Requeue(items ...ocr2keepers.CoordinatedBlockProposal) error {
+	return s.RequeueFn(items...)
+}
+
 type mockEncoder struct {
 	EncodeFn  func(...ocr2keepers.CheckResult) ([]byte, error)
 	ExtractFn func([]byte) ([]ocr2keepers.ReportedUpkeep, error)
@@ -1601,6 +1727,7 @@ type mockCoordinator struct {
 	ShouldAcceptFn    func(ocr2keepers.ReportedUpkeep) bool
 	ShouldTransmitFn  func(ocr2keepers.ReportedUpkeep) bool
 	AcceptFn          func(ocr2keepers.ReportedUpkeep) bool
+	InFlightWorkIDsFn func() []string
 }
 
 func (s *mockCoordinator) FilterProposals(p []ocr2keepers.CoordinatedBlockProposal) ([]ocr2keepers.CoordinatedBlockProposal, error) {
@@ -1623,6 +1750,10 @@ func (s *mockCoordinator) Accept(r ocr2keepers.ReportedUpkeep) bool {
 	return s.AcceptFn(r)
 }
 
+func (s *mockCoordinator) InFlightWorkIDs() []string {
+	return s.InFlightWorkIDsFn()
+}
+
 func mockWorkIDGenerator(id ocr2keepers.UpkeepIdentifier, trigger ocr2keepers.Trigger) string {
 	wid := string(id[:])
 	if trigger.LogTriggerExtension != nil {
@@ -1637,3 +1768,130 @@ func mockUpkeepTypeGetter(id ocr2keepers.UpkeepIdentifier) types.UpkeepType {
 	}
 	return types.LogTrigger
 }
+
+func TestOcr3Plugin_Outcome_MatchesLegacyNodes(t *testing.T) {
+	// the fixture holds the observations and outcomes of nodes running a
+	// release that only encodes json
+	b, err := os.ReadFile("fixtures/legacy_node_outcomes.json")
+	assert.NoError(t, err)
+
+	var rounds []struct {
+		Observations []string `json:"observations"`
+		Outcome      string   `json:"outcome"`
+	}
+	assert.NoError(t, json.Unmarshal(b, &rounds))
+	assert.NotEmpty(t, rounds)
+
+	conf, err := config.DecodeOffchainConfig([]byte("{}"))
+	assert.NoError(t, err)
+	assert.Equal(t, config.CodecVersionJSON, conf.CodecVersion)
+
+	plugin := &ocr3Plugin{
+		ConfigDigest:     ocr2plustypes.ConfigDigest{1, 2, 3},
+		UpkeepTypeGetter: legacyTypeGetter,
+		WorkIDGenerator:  legacyWorkID,
+		Scoreboard:       newOracleScoreboard(ocr2plustypes.ConfigDigest{}),
+		Config:           conf,
+		N:                4,
+		F:                1,
+		Logger:           log.New(io.Discard, "", 0),
+	}
+
+	var previous ocr3types.Outcome
+	for i, round := range rounds {
+		var aos []ocr2plustypes.AttributedObservation
+		for j, encoded := range round.Observations {
+			raw, err := hex.DecodeString(encoded)
+			assert.NoError(t, err)
+
+			// an observation of this release is indistinguishable from one of a legacy node
+			observation, err := ocr2keepers2.DecodeAutomationObservation(raw, legacyTypeGetter, legacyWorkID, conf.ProtocolLimits)
+			assert.NoError(t, err)
+			reencoded, err := observation.EncodeWithCodec(conf.CodecVersion)
+			assert.NoError(t, err)
+			assert.Equal(t, raw, reencoded)
+
+			aos = append(aos, ocr2plustypes.AttributedObservation{Observation: raw, Observer: commontypes.OracleID(j)})
+		}
+
+		outcome, err := plugin.Outcome(context.Background(), ocr3types.OutcomeContext{SeqNr: uint64(10 + i), PreviousOutcome: previous}, nil, aos)
+		assert.NoError(t, err)
+
+		expected, err := hex.DecodeString(round.Outcome)
+		assert.NoError(t, err)
+		assert.Equal(t, string(expected), string(outcome), "outcome of round %d", i)
+
+		previous = outcome
+	}
+}
+
+func legacyTypeGetter(uid ocr2keepers.UpkeepIdentifier) types.UpkeepType {
+	if uid[15] == 1 {
+		return types.LogTrigger
+	}
+	return types.ConditionTrigger
+}
+
+func legacyWorkID(uid ocr2keepers.UpkeepIdentifier, trigger ocr2keepers.Trigger) string {
+	if trigger.LogTriggerExtension != nil {
+		return fmt.Sprintf("%x-%x-%d", uid[:], trigger.LogTriggerExtension.TxHash, trigger.LogTriggerExtension.Index)
+	}
+	return fmt.Sprintf("%x", uid[:])
+}
+
+func TestOcr3Plugin_Outcome_ReportedHistoryFollowsConfig(t *testing.T) {
+	var uid ocr2keepers.UpkeepIdentifier
+	uid[31] = 1
+	result := ocr2keepers.CheckResult{
+		Eligible:     true,
+		UpkeepID:     uid,
+		Trigger:      ocr2keepers.Trigger{BlockNumber: 100, BlockHash: [32]byte{100}},
+		GasAllocated: 100_000,
+		PerformData:  []byte{1},
+		FastGasWei:   big.NewInt(10),
+		LinkNative:   big.NewInt(20),
+	}
+	result.WorkID = legacyWorkID(result.UpkeepID, result.Trigger)
+
+	for _, tc := range []struct {
+		name            string
+		codecVersion    uint8
+		reportedHistory bool
+		agreedInRound   []int
+	}{
+		{name: "json codec agrees a result again by default", codecVersion: config.CodecVersionJSON, agreedInRound: []int{1, 1}},
+		{name: "json codec skips a reported result once enabled", codecVersion: config.CodecVersionJSON, reportedHistory: true, agreedInRound: []int{1, 0}},
+		{name: "binary codec skips a reported result", codecVersion: config.CodecVersionBinary, agreedInRound: []int{1, 0}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			conf, err := config.DecodeOffchainConfig([]byte(fmt.Sprintf(`{"codecVersion": %d, "reportedHistory": %t}`, tc.codecVersion, tc.reportedHistory)))
+			assert.NoError(t, err)
+
+			plugin := &ocr3Plugin{
+				UpkeepTypeGetter: legacyTypeGetter,
+				WorkIDGenerator:  legacyWorkID,
+				Scoreboard:       newOracleScoreboard(ocr2plustypes.ConfigDigest{}),
+				Config:           conf,
+				N:                4,
+				F:                1,
+				Logger:           log.New(io.Discard, "", 0),
+			}
+
+			observation, err := ocr2keepers2.AutomationObservation{Performable: []ocr2keepers.CheckResult{result}}.EncodeWithCodec(tc.codecVersion)
+			assert.NoError(t, err)
+			aos := []ocr2plustypes.AttributedObservation{{Observation: observation, Observer: 0}, {Observation: observation, Observer: 1}}
+
+			var previous ocr3types.Outcome
+			for round, agreed := range tc.agreedInRound {
+				encoded, err := plugin.Outcome(context.Background(), ocr3types.OutcomeContext{SeqNr: uint64(round + 1), PreviousOutcome: previous}, nil, aos)
+				assert.NoError(t, err)
+
+				outcome, err := ocr2keepers2.DecodeAutomationOutcome(encoded, legacyTypeGetter, legacyWorkID, conf.ProtocolLimits)
+				assert.NoError(t, err)
+				assert.Len(t, outcome.AgreedPerformables, agreed, "round %d", round)
+
+				previous = encoded
+			}
+		})
+	}
+}
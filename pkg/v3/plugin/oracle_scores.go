@@ -0,0 +1,158 @@
+package plugin
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/smartcontractkit/libocr/commontypes"
+	ocr2plustypes "github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+
+	ocr2keepers "github.com/smartcontractkit/chainlink-common/pkg/types/automation"
+
+	ocr2keepersv3 "github.com/smartcontractkit/chainlink-automation/pkg/v3"
+	"github.com/smartcontractkit/chainlink-automation/pkg/v3/prommetrics"
+)
+
+// StaleBlockHistoryThreshold is the number of blocks the latest block in an
+// observed block history can be behind the coordinated quorum block before
+// the block history is considered stale
+const StaleBlockHistoryThreshold = 10
+
+// OracleScore holds the observation quality counters of a single oracle, as
+// seen by this node in the outcome step of the plugin instance
+type OracleScore struct {
+	OracleID commontypes.OracleID
+	// Observations is the number of observations received from the oracle
+	Observations uint64
+	// DecodeFailures is the number of observations that could not be decoded
+	DecodeFailures uint64
+	// ValidationFailures is the number of observations that were decoded but
+	// contained invalid values or exceeded the protocol limits
+	ValidationFailures uint64
+	// NoQuorumOverlap is the number of observations with performables where
+	// none of the performables reached quorum while other results did
+	NoQuorumOverlap uint64
+	// StaleBlockHistory is the number of observations with a block history
+	// that is empty or lags the coordinated quorum block by more than
+	// StaleBlockHistoryThreshold blocks
+	StaleBlockHistory uint64
+}
+
+// OracleScoreInspector provides the observation quality scores of the oracles
+// in the DON to operators
+type OracleScoreInspector interface {
+	// OracleScores returns the scores of all oracles ordered by oracle ID
+	OracleScores() []OracleScore
+}
+
+// oracleScoreboard keeps an OracleScore for every oracle that sent an
+// observation. Scores are updated in the outcome step and can be read
+// concurrently through the inspection API. A nil scoreboard records nothing.
+type oracleScoreboard struct {
+	mu     sync.RWMutex
+	scores map[commontypes.OracleID]*OracleScore
+	// digest labels the metrics of the scoreboard, such that the metrics of
+	// plugin instances with different configs are kept apart
+	digest string
+}
+
+func newOracleScoreboard(digest ocr2plustypes.ConfigDigest) *oracleScoreboard {
+	return &oracleScoreboard{
+		scores: make(map[commontypes.OracleID]*OracleScore),
+		digest: digest.Hex(),
+	}
+}
+
+// recordObservation counts a received observation, regardless of whether it
+// can be decoded
+func (s *oracleScoreboard) recordObservation(oracle commontypes.OracleID) {
+	if s == nil {
+		return
+	}
+	s.update(oracle, func(score *OracleScore) { score.Observations++ })
+	prommetrics.AutomationOracleObservations.WithLabelValues(s.digest, oracleLabel(oracle)).Inc()
+}
+
+// recordIssue counts an issue of the provided type, one of the prommetrics
+// OracleIssue values, for an observation of the oracle
+func (s *oracleScoreboard) recordIssue(oracle commontypes.OracleID, issue string) {
+	if s == nil {
+		return
+	}
+	s.update(oracle, func(score *OracleScore) {
+		switch issue {
+		case prommetrics.OracleIssueDecode:
+			score.DecodeFailures++
+		case prommetrics.OracleIssueValidation:
+			score.ValidationFailures++
+		case prommetrics.OracleIssueNoQuorumOverlap:
+			score.NoQuorumOverlap++
+		case prommetrics.OracleIssueStaleBlockHistory:
+			score.StaleBlockHistory++
+		}
+	})
+	prommetrics.AutomationOracleObservationIssues.WithLabelValues(s.digest, oracleLabel(oracle), issue).Inc()
+}
+
+func (s *oracleScoreboard) update(oracle commontypes.OracleID, fn func(*OracleScore)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	score, ok := s.scores[oracle]
+	if !ok {
+		score = &OracleScore{OracleID: oracle}
+		s.scores[oracle] = score
+	}
+	fn(score)
+}
+
+// OracleScores returns a copy of the scores of all oracles ordered by oracle ID
+func (s *oracleScoreboard) OracleScores() []OracleScore {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	scores := make([]OracleScore, 0, len(s.scores))
+	for _, score := range s.scores {
+		scores = append(scores, *score)
+	}
+	sort.Slice(scores, func(i, j int) bool {
+		return scores[i].OracleID < scores[j].OracleID
+	})
+	return scores
+}
+
+// scoreObservations records the issues of valid observations that can only
+// be determined once the outcome performables and coordinated block are known
+func (s *oracleScoreboard) scoreObservations(observations map[commontypes.OracleID]ocr2keepersv3.AutomationObservation, p *performables, c *coordinatedBlockProposals) {
+	if s == nil {
+		return
+	}
+	quorumBlock, hasQuorumBlock := c.getLatestQuorumBlock()
+	hasQuorumResults := p.hasQuorumResults()
+
+	for oracle, observation := range observations {
+		if hasQuorumResults && len(observation.Performable) > 0 && !p.anyReachedQuorum(observation.Performable) {
+			s.recordIssue(oracle, prommetrics.OracleIssueNoQuorumOverlap)
+		}
+		if hasQuorumBlock && isStaleBlockHistory(observation.BlockHistory, quorumBlock) {
+			s.recordIssue(oracle, prommetrics.OracleIssueStaleBlockHistory)
+		}
+	}
+}
+
+// isStaleBlockHistory returns true if the latest block in the history is more
+// than StaleBlockHistoryThreshold blocks behind the quorum block
+func isStaleBlockHistory(history ocr2keepers.BlockHistory, quorumBlock ocr2keepers.BlockKey) bool {
+	var latest ocr2keepers.BlockNumber
+	for _, block := range history {
+		if block.Number > latest {
+			latest = block.Number
+		}
+	}
+	return latest+StaleBlockHistoryThreshold < quorumBlock.Number
+}
+
+func oracleLabel(oracle commontypes.OracleID) string {
+	return strconv.Itoa(int(oracle))
+}
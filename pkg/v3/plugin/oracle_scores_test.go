@@ -0,0 +1,128 @@
+package plugin
+
+import (
+	"context"
+	"io"
+	"log"
+	"math/big"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/smartcontractkit/libocr/commontypes"
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/ocr3types"
+	ocr2plustypes "github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+	"github.com/stretchr/testify/assert"
+
+	ocr2keepers "github.com/smartcontractkit/chainlink-common/pkg/types/automation"
+
+	ocr2keepersv3 "github.com/smartcontractkit/chainlink-automation/pkg/v3"
+	"github.com/smartcontractkit/chainlink-automation/pkg/v3/config"
+	"github.com/smartcontractkit/chainlink-automation/pkg/v3/prommetrics"
+)
+
+func TestOracleScoreboard_Outcome(t *testing.T) {
+	result := func(id int64) ocr2keepers.CheckResult {
+		uid := ocr2keepers.UpkeepIdentifier{}
+		uid.FromBigInt(big.NewInt(id))
+		return ocr2keepers.CheckResult{
+			Eligible:     true,
+			UpkeepID:     uid,
+			WorkID:       uid.String(),
+			GasAllocated: 1,
+			FastGasWei:   big.NewInt(1),
+			LinkNative:   big.NewInt(1),
+		}
+	}
+	history := func(from, to ocr2keepers.BlockNumber) ocr2keepers.BlockHistory {
+		var h ocr2keepers.BlockHistory
+		for n := to; n >= from; n-- {
+			h = append(h, ocr2keepers.BlockKey{Number: n, Hash: [32]byte{byte(n)}})
+		}
+		return h
+	}
+	encode := func(observation ocr2keepersv3.AutomationObservation) []byte {
+		b, err := observation.Encode()
+		assert.NoError(t, err)
+		return b
+	}
+
+	invalidResult := result(1)
+	invalidResult.GasAllocated = 0
+
+	observations := []ocr2plustypes.AttributedObservation{
+		// agrees with the other valid observations
+		{Observer: 0, Observation: encode(ocr2keepersv3.AutomationObservation{Performable: []ocr2keepers.CheckResult{result(1)}, BlockHistory: history(95, 100)})},
+		{Observer: 1, Observation: encode(ocr2keepersv3.AutomationObservation{Performable: []ocr2keepers.CheckResult{result(1), result(2)}, BlockHistory: history(95, 100)})},
+		// only has a result nobody else has and an old block history
+		{Observer: 2, Observation: encode(ocr2keepersv3.AutomationObservation{Performable: []ocr2keepers.CheckResult{result(3)}, BlockHistory: history(80, 85)})},
+		// cannot be decoded
+		{Observer: 3, Observation: []byte("invalid")},
+		// decoded but invalid
+		{Observer: 4, Observation: encode(ocr2keepersv3.AutomationObservation{Performable: []ocr2keepers.CheckResult{invalidResult}})},
+	}
+
+	digest := ocr2plustypes.ConfigDigest{1, 2, 3}
+	scoreboard := newOracleScoreboard(digest)
+	plugin := &ocr3Plugin{
+		Config:           config.OffchainConfig{ProtocolLimits: config.DefaultProtocolLimitsV0, Quorum: config.QuorumConfig{Performables: config.QuorumFPlusOne}},
+		UpkeepTypeGetter: mockUpkeepTypeGetter,
+		WorkIDGenerator: func(id ocr2keepers.UpkeepIdentifier, _ ocr2keepers.Trigger) string {
+			return id.String()
+		},
+		Scoreboard: scoreboard,
+		N:          5,
+		F:          1,
+		Logger:     log.New(io.Discard, "", 0),
+	}
+
+	for i := 0; i < 2; i++ {
+		_, err := plugin.Outcome(context.Background(), ocr3types.OutcomeContext{}, nil, observations)
+		assert.NoError(t, err)
+	}
+
+	assert.Equal(t, []OracleScore{
+		{OracleID: 0, Observations: 2},
+		{OracleID: 1, Observations: 2},
+		{OracleID: 2, Observations: 2, NoQuorumOverlap: 2, StaleBlockHistory: 2},
+		{OracleID: 3, Observations: 2, DecodeFailures: 2},
+		{OracleID: 4, Observations: 2, ValidationFailures: 2},
+	}, scoreboard.OracleScores())
+
+	// the metrics are kept apart per config digest like the scoreboard
+	assert.Equal(t, float64(2), testutil.ToFloat64(prommetrics.AutomationOracleObservations.WithLabelValues(digest.Hex(), "3")))
+	assert.Equal(t, float64(2), testutil.ToFloat64(prommetrics.AutomationOracleObservationIssues.WithLabelValues(digest.Hex(), "3", prommetrics.OracleIssueDecode)))
+}
+
+func TestOracleScoreboard_NoQuorum(t *testing.T) {
+	scoreboard := newOracleScoreboard(ocr2plustypes.ConfigDigest{})
+	p := newPerformables(newFixedQuorum(2), 10, [16]byte{}, nil, true, log.New(io.Discard, "", 0))
+	c := newCoordinatedBlockProposals(2, 0, 10, 10, [16]byte{}, log.New(io.Discard, "", 0))
+
+	uid := ocr2keepers.UpkeepIdentifier{}
+	uid.FromBigInt(big.NewInt(1))
+	observations := map[commontypes.OracleID]ocr2keepersv3.AutomationObservation{
+		1: {Performable: []ocr2keepers.CheckResult{{UpkeepID: uid, WorkID: "1"}}},
+	}
+	for _, observation := range observations {
+		p.add(observation)
+		c.add(observation)
+	}
+
+	// without any quorum result or block there is nothing to compare against
+	scoreboard.scoreObservations(observations, p, c)
+	assert.Empty(t, scoreboard.OracleScores())
+
+	var nilScoreboard *oracleScoreboard
+	nilScoreboard.recordObservation(1)
+	nilScoreboard.recordIssue(1, "decode")
+	nilScoreboard.scoreObservations(observations, p, c)
+}
+
+func TestIsStaleBlockHistory(t *testing.T) {
+	quorumBlock := ocr2keepers.BlockKey{Number: 100}
+
+	assert.True(t, isStaleBlockHistory(nil, quorumBlock))
+	assert.True(t, isStaleBlockHistory(ocr2keepers.BlockHistory{{Number: 89}}, quorumBlock))
+	assert.False(t, isStaleBlockHistory(ocr2keepers.BlockHistory{{Number: 90}}, quorumBlock))
+	assert.False(t, isStaleBlockHistory(ocr2keepers.BlockHistory{{Number: 85}, {Number: 101}}, quorumBlock))
+}
@@ -16,23 +16,32 @@ type resultAndCount struct {
 }
 
 type performables struct {
-	limit           int
-	keyRandSource   [16]byte
-	quorumThreshold int
+	limit         int
+	keyRandSource [16]byte
+	quorum        performablesQuorum
+	priority      performablesPriority
+	// reportedHistory enables the reported history, which is only part of
+	// outcomes of codec versions that encode it
+	reportedHistory bool
 	logger          *log.Logger
 	resultCount     map[string]resultAndCount
 }
 
 // Performables gets quorum on agreed check results which should ultimately be
 // performed within a report. It assumes only valid observations are added to it
-// and simply adds all results which achieve the quorumThreshold.
+// and simply adds all results which achieve the quorum for the result.
 // Results are agreed upon by their UniqueID() which contains all the data
-// within the result.
-func newPerformables(quorumThreshold int, limit int, rSrc [16]byte, logger *log.Logger) *performables {
+// within the result. Agreed results are ordered by the provided priority, if
+// any, before the limit is applied. If the reported history is enabled,
+// results that were agreed in recent rounds, according to the reported history
+// of the previous outcome, are not agreed again.
+func newPerformables(quorum performablesQuorum, limit int, rSrc [16]byte, priority performablesPriority, reportedHistory bool, logger *log.Logger) *performables {
 	return &performables{
-		quorumThreshold: quorumThreshold,
+		quorum:          quorum,
 		limit:           limit,
 		keyRandSource:   rSrc,
+		priority:        priority,
+		reportedHistory: reportedHistory,
 		logger:          logger,
 		resultCount:     make(map[string]resultAndCount),
 	}
@@ -57,8 +66,13 @@ func (p *performables) add(observation ocr2keepersv3.AutomationObservation) {
 	p.logger.Printf("Added %d new results from %d performables", len(p.resultCount)-initialCount, len(observation.Performable))
 }
 
-func (p *performables) set(outcome *ocr2keepersv3.AutomationOutcome) {
+func (p *performables) set(outcome *ocr2keepersv3.AutomationOutcome, prevOutcome ocr2keepersv3.AutomationOutcome) {
 	performable := make([]ocr2keepers.CheckResult, 0)
+	reported := map[string]ocr2keepers.BlockNumber{}
+	if p.reportedHistory {
+		reported = reportedCheckBlocks(prevOutcome.ReportedHistory)
+	}
+	alreadyReported := 0
 
 	// Added workIDs
 	addedWid := make(map[string]bool)
@@ -71,15 +85,27 @@ func (p *performables) set(outcome *ocr2keepersv3.AutomationOutcome) {
 		// Traverse in sorted order of UID
 		payload := p.resultCount[uid]
 		// For every payload that reaches threshold and workID has not been added before, add it to performables
-		if payload.count >= p.quorumThreshold && !addedWid[payload.result.WorkID] {
+		if payload.count >= p.quorum(payload.result) && !addedWid[payload.result.WorkID] {
+			if wasReported(reported, payload.result) {
+				alreadyReported++
+				continue
+			}
 			addedWid[payload.result.WorkID] = true
 			performable = append(performable, payload.result)
 		}
 	}
-	p.logger.Printf("Adding %d agreed performables reaching quorumThreshold %d", len(performable), p.quorumThreshold)
+	if alreadyReported > 0 {
+		p.logger.Printf("Skipping %d results reaching quorum that were reported in recent rounds", alreadyReported)
+	}
+	p.logger.Printf("Adding %d agreed performables reaching quorum", len(performable))
 
-	// Sort by a shuffled workID.
+	// Sort by priority, using a shuffled workID as the tie breaker.
 	sort.Slice(performable, func(i, j int) bool {
+		if p.priority != nil {
+			if c := p.priority(performable[i], performable[j]); c != 0 {
+				return c < 0
+			}
+		}
 		return random.ShuffleString(performable[i].WorkID, p.keyRandSource) < random.ShuffleString(performable[j].WorkID, p.keyRandSource)
 	})
 
@@ -89,4 +115,72 @@ func (p *performables) set(outcome *ocr2keepersv3.AutomationOutcome) {
 	}
 	p.logger.Printf("Setting outcome.AgreedPerformables with %d performables", len(performable))
 	outcome.AgreedPerformables = performable
+	if p.reportedHistory {
+		outcome.ReportedHistory = appendReportedHistory(performable, prevOutcome.ReportedHistory)
+	}
+}
+
+// hasQuorumResults returns true if any of the added results reached quorum
+func (p *performables) hasQuorumResults() bool {
+	for _, payload := range p.resultCount {
+		if payload.count >= p.quorum(payload.result) {
+			return true
+		}
+	}
+	return false
+}
+
+// anyReachedQuorum returns true if any of the provided results reached quorum
+func (p *performables) anyReachedQuorum(results []ocr2keepers.CheckResult) bool {
+	for _, result := range results {
+		if payload, ok := p.resultCount[result.UniqueID()]; ok && payload.count >= p.quorum(payload.result) {
+			return true
+		}
+	}
+	return false
+}
+
+// reportedCheckBlocks returns the latest reported check block for every workID
+// in the reported history
+func reportedCheckBlocks(history [][]ocr2keepersv3.ReportedWork) map[string]ocr2keepers.BlockNumber {
+	reported := make(map[string]ocr2keepers.BlockNumber)
+	for _, round := range history {
+		for _, r := range round {
+			if block, ok := reported[r.WorkID]; !ok || r.CheckBlock > block {
+				reported[r.WorkID] = r.CheckBlock
+			}
+		}
+	}
+	return reported
+}
+
+// wasReported returns true if the result was agreed in a recent round. A log
+// trigger is performed once per log, while conditional upkeeps share a workID
+// across check blocks and may be performed again for a newer check block.
+func wasReported(reported map[string]ocr2keepers.BlockNumber, result ocr2keepers.CheckResult) bool {
+	block, ok := reported[result.WorkID]
+	if !ok {
+		return false
+	}
+	if result.Trigger.LogTriggerExtension != nil {
+		return true
+	}
+	return result.Trigger.BlockNumber <= block
+}
+
+// appendReportedHistory adds the performables agreed in this round as the
+// latest round of the history, dropping the oldest rounds beyond the limit
+func appendReportedHistory(performable []ocr2keepers.CheckResult, history [][]ocr2keepersv3.ReportedWork) [][]ocr2keepersv3.ReportedWork {
+	round := make([]ocr2keepersv3.ReportedWork, 0, len(performable))
+	for _, result := range performable {
+		round = append(round, ocr2keepersv3.ReportedWork{
+			WorkID:     result.WorkID,
+			CheckBlock: result.Trigger.BlockNumber,
+		})
+	}
+
+	if len(history) >= ocr2keepersv3.OutcomeReportedHistoryRoundLimit {
+		history = history[:ocr2keepersv3.OutcomeReportedHistoryRoundLimit-1]
+	}
+	return append([][]ocr2keepersv3.ReportedWork{round}, history...)
 }
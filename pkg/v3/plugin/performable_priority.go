@@ -0,0 +1,71 @@
+package plugin
+
+import (
+	"cmp"
+
+	ocr2keepers "github.com/smartcontractkit/chainlink-common/pkg/types/automation"
+
+	"github.com/smartcontractkit/chainlink-automation/pkg/v3/config"
+)
+
+// performablesPriority compares two agreed performables. It returns a negative
+// number when a should be preferred over b, a positive number when b should be
+// preferred over a and zero when neither is preferred. Priorities must only
+// depend on agreed fields so that every node orders performables the same way.
+type performablesPriority func(a, b ocr2keepers.CheckResult) int
+
+// newPerformablesPriority builds the priority function from the offchain
+// config. Upkeep tiers are applied first, followed by the configured strategy.
+func newPerformablesPriority(conf config.PerformablesPriorityConfig) performablesPriority {
+	var strategy performablesPriority
+
+	switch conf.Strategy {
+	case config.PerformablesPriorityOldestTriggerFirst:
+		strategy = oldestTriggerFirst
+	case config.PerformablesPriorityLogTriggerFirst:
+		strategy = func(a, b ocr2keepers.CheckResult) int {
+			return cmp.Compare(triggerTypeRank(b), triggerTypeRank(a))
+		}
+	case config.PerformablesPriorityConditionalFirst:
+		strategy = func(a, b ocr2keepers.CheckResult) int {
+			return cmp.Compare(triggerTypeRank(a), triggerTypeRank(b))
+		}
+	}
+
+	tiers := conf.UpkeepTiers
+
+	return func(a, b ocr2keepers.CheckResult) int {
+		if len(tiers) > 0 {
+			// higher tiers come first
+			if c := cmp.Compare(tiers[b.UpkeepID.String()], tiers[a.UpkeepID.String()]); c != 0 {
+				return c
+			}
+		}
+		if strategy != nil {
+			return strategy(a, b)
+		}
+		return 0
+	}
+}
+
+// oldestTriggerFirst prefers the performable that has waited longest. For log
+// triggers the block of the log is used as that is when the upkeep became
+// eligible; the check block is used otherwise.
+func oldestTriggerFirst(a, b ocr2keepers.CheckResult) int {
+	return cmp.Compare(triggerBlock(a.Trigger), triggerBlock(b.Trigger))
+}
+
+func triggerBlock(t ocr2keepers.Trigger) ocr2keepers.BlockNumber {
+	if t.LogTriggerExtension != nil && t.LogTriggerExtension.BlockNumber != 0 {
+		return t.LogTriggerExtension.BlockNumber
+	}
+	return t.BlockNumber
+}
+
+// triggerTypeRank is 0 for conditional upkeeps and 1 for log trigger upkeeps
+func triggerTypeRank(r ocr2keepers.CheckResult) int {
+	if r.Trigger.LogTriggerExtension != nil {
+		return 1
+	}
+	return 0
+}
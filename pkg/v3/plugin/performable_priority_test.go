@@ -0,0 +1,130 @@
+package plugin
+
+import (
+	"bytes"
+	"log"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	ocr2keepers "github.com/smartcontractkit/chainlink-common/pkg/types/automation"
+
+	ocr2keepersv3 "github.com/smartcontractkit/chainlink-automation/pkg/v3"
+	"github.com/smartcontractkit/chainlink-automation/pkg/v3/config"
+)
+
+func TestPerformablesPriority(t *testing.T) {
+	upkeepID := func(i int64) ocr2keepers.UpkeepIdentifier {
+		uid := ocr2keepers.UpkeepIdentifier{}
+		uid.FromBigInt(big.NewInt(i))
+		return uid
+	}
+
+	conditional := func(id int64, block ocr2keepers.BlockNumber) ocr2keepers.CheckResult {
+		return ocr2keepers.CheckResult{
+			UpkeepID: upkeepID(id),
+			Trigger:  ocr2keepers.Trigger{BlockNumber: block},
+		}
+	}
+
+	logTrigger := func(id int64, block, logBlock ocr2keepers.BlockNumber) ocr2keepers.CheckResult {
+		return ocr2keepers.CheckResult{
+			UpkeepID: upkeepID(id),
+			Trigger: ocr2keepers.Trigger{
+				BlockNumber:         block,
+				LogTriggerExtension: &ocr2keepers.LogTriggerExtension{BlockNumber: logBlock},
+			},
+		}
+	}
+
+	for _, tc := range []struct {
+		name     string
+		conf     config.PerformablesPriorityConfig
+		a        ocr2keepers.CheckResult
+		b        ocr2keepers.CheckResult
+		expected int
+	}{
+		{
+			name:     "no strategy has no preference",
+			conf:     config.PerformablesPriorityConfig{Strategy: config.PerformablesPriorityNone},
+			a:        conditional(1, 10),
+			b:        logTrigger(2, 5, 1),
+			expected: 0,
+		},
+		{
+			name:     "oldest trigger first prefers the older check block",
+			conf:     config.PerformablesPriorityConfig{Strategy: config.PerformablesPriorityOldestTriggerFirst},
+			a:        conditional(1, 10),
+			b:        conditional(2, 20),
+			expected: -1,
+		},
+		{
+			name:     "oldest trigger first uses the log block for log triggers",
+			conf:     config.PerformablesPriorityConfig{Strategy: config.PerformablesPriorityOldestTriggerFirst},
+			a:        conditional(1, 10),
+			b:        logTrigger(2, 20, 5),
+			expected: 1,
+		},
+		{
+			name:     "log trigger first prefers log triggers",
+			conf:     config.PerformablesPriorityConfig{Strategy: config.PerformablesPriorityLogTriggerFirst},
+			a:        conditional(1, 10),
+			b:        logTrigger(2, 20, 20),
+			expected: 1,
+		},
+		{
+			name:     "conditional first prefers conditionals",
+			conf:     config.PerformablesPriorityConfig{Strategy: config.PerformablesPriorityConditionalFirst},
+			a:        conditional(1, 10),
+			b:        logTrigger(2, 20, 20),
+			expected: -1,
+		},
+		{
+			name: "higher tiers are preferred over the strategy",
+			conf: config.PerformablesPriorityConfig{
+				Strategy:    config.PerformablesPriorityOldestTriggerFirst,
+				UpkeepTiers: map[string]int{"2": 1},
+			},
+			a:        conditional(1, 10),
+			b:        conditional(2, 20),
+			expected: 1,
+		},
+		{
+			name: "equal tiers fall back to the strategy",
+			conf: config.PerformablesPriorityConfig{
+				Strategy:    config.PerformablesPriorityOldestTriggerFirst,
+				UpkeepTiers: map[string]int{"1": 3, "2": 3},
+			},
+			a:        conditional(1, 10),
+			b:        conditional(2, 20),
+			expected: -1,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			priority := newPerformablesPriority(tc.conf)
+
+			assert.Equal(t, tc.expected, priority(tc.a, tc.b))
+			assert.Equal(t, -tc.expected, priority(tc.b, tc.a))
+		})
+	}
+
+	t.Run("priority is applied before the limit", func(t *testing.T) {
+		results := []ocr2keepers.CheckResult{}
+		for i := int64(1); i <= 10; i++ {
+			result := conditional(i, ocr2keepers.BlockNumber(100-i))
+			result.WorkID = upkeepID(i).String()
+			results = append(results, result)
+		}
+
+		p := newPerformables(newFixedQuorum(1), 3, [16]byte{}, newPerformablesPriority(config.PerformablesPriorityConfig{
+			Strategy: config.PerformablesPriorityOldestTriggerFirst,
+		}), false, log.New(&bytes.Buffer{}, "", 0))
+		p.add(ocr2keepersv3.AutomationObservation{Performable: results})
+
+		outcome := ocr2keepersv3.AutomationOutcome{}
+		p.set(&outcome, ocr2keepersv3.AutomationOutcome{})
+
+		assert.Equal(t, []ocr2keepers.CheckResult{results[9], results[8], results[7]}, outcome.AgreedPerformables)
+	})
+}
@@ -371,15 +371,79 @@ func TestPerformables(t *testing.T) {
 			// Prepare logger
 			var logBuf bytes.Buffer
 			logger := log.New(&logBuf, "", 0)
-			performables := newPerformables(tt.threshold, tt.limit, [16]byte{}, logger)
+			performables := newPerformables(newFixedQuorum(tt.threshold), tt.limit, [16]byte{}, nil, false, logger)
 			for _, observation := range tt.observations {
 				performables.add(observation)
 			}
 			outcome := ocr2keepers.AutomationOutcome{}
-			performables.set(&outcome)
+			performables.set(&outcome, ocr2keepers.AutomationOutcome{})
 
 			assert.Equal(t, tt.expectedOutcomeWorkIDs, outcome.AgreedPerformables)
 			assert.Equal(t, tt.wantResultCount, performables.resultCount)
 		})
 	}
 }
+
+func TestPerformables_ReportedHistory(t *testing.T) {
+	conditional := func(workID string, block types.BlockNumber) types.CheckResult {
+		return types.CheckResult{WorkID: workID, Trigger: types.Trigger{BlockNumber: block}}
+	}
+	logTrigger := func(workID string, block types.BlockNumber) types.CheckResult {
+		return types.CheckResult{WorkID: workID, Trigger: types.Trigger{BlockNumber: block, LogTriggerExtension: &types.LogTriggerExtension{}}}
+	}
+
+	prevOutcome := ocr2keepers.AutomationOutcome{
+		ReportedHistory: [][]ocr2keepers.ReportedWork{
+			{{WorkID: "conditional1", CheckBlock: 10}},
+			{{WorkID: "log1", CheckBlock: 5}, {WorkID: "conditional2", CheckBlock: 10}},
+		},
+	}
+
+	performables := newPerformables(newFixedQuorum(1), 10, [16]byte{}, nil, true, log.New(&bytes.Buffer{}, "", 0))
+	performables.add(ocr2keepers.AutomationObservation{
+		Performable: []types.CheckResult{
+			conditional("conditional1", 10),
+			conditional("conditional2", 11),
+			logTrigger("log1", 12),
+			logTrigger("log2", 12),
+		},
+	})
+
+	outcome := ocr2keepers.AutomationOutcome{}
+	performables.set(&outcome, prevOutcome)
+
+	assert.ElementsMatch(t, []types.CheckResult{conditional("conditional2", 11), logTrigger("log2", 12)}, outcome.AgreedPerformables)
+	assert.Len(t, outcome.ReportedHistory, 3)
+	assert.ElementsMatch(t, []ocr2keepers.ReportedWork{{WorkID: "conditional2", CheckBlock: 11}, {WorkID: "log2", CheckBlock: 12}}, outcome.ReportedHistory[0])
+	assert.Equal(t, prevOutcome.ReportedHistory, outcome.ReportedHistory[1:])
+}
+
+func TestPerformables_ReportedHistoryDisabled(t *testing.T) {
+	prevOutcome := ocr2keepers.AutomationOutcome{
+		ReportedHistory: [][]ocr2keepers.ReportedWork{
+			{{WorkID: "conditional1", CheckBlock: 10}},
+		},
+	}
+
+	performables := newPerformables(newFixedQuorum(1), 10, [16]byte{}, nil, false, log.New(&bytes.Buffer{}, "", 0))
+	performables.add(ocr2keepers.AutomationObservation{
+		Performable: []types.CheckResult{{WorkID: "conditional1", Trigger: types.Trigger{BlockNumber: 10}}},
+	})
+
+	outcome := ocr2keepers.AutomationOutcome{}
+	performables.set(&outcome, prevOutcome)
+
+	assert.Equal(t, []types.CheckResult{{WorkID: "conditional1", Trigger: types.Trigger{BlockNumber: 10}}}, outcome.AgreedPerformables)
+	assert.Nil(t, outcome.ReportedHistory)
+}
+
+func TestAppendReportedHistory(t *testing.T) {
+	history := [][]ocr2keepers.ReportedWork{}
+	for i := 0; i < ocr2keepers.OutcomeReportedHistoryRoundLimit+5; i++ {
+		history = appendReportedHistory([]types.CheckResult{{WorkID: "workID", Trigger: types.Trigger{BlockNumber: types.BlockNumber(i)}}}, history)
+	}
+
+	assert.Len(t, history, ocr2keepers.OutcomeReportedHistoryRoundLimit)
+	assert.Equal(t, types.BlockNumber(ocr2keepers.OutcomeReportedHistoryRoundLimit+4), history[0][0].CheckBlock)
+	assert.Equal(t, types.BlockNumber(5), history[len(history)-1][0].CheckBlock)
+}
@@ -3,7 +3,9 @@ package plugin
 import (
 	"fmt"
 	"log"
+	"time"
 
+	"github.com/smartcontractkit/libocr/commontypes"
 	"github.com/smartcontractkit/libocr/offchainreporting2plus/ocr3types"
 	ocr2plustypes "github.com/smartcontractkit/libocr/offchainreporting2plus/types"
 
@@ -35,17 +37,32 @@ func newPlugin(
 	runnable types.Runnable,
 	rConf runner.RunnerConfig,
 	conf config.OffchainConfig,
+	scoreboard *oracleScoreboard,
+	registries *registryMux,
+	coordinatorStore coordinator.Store,
+	queueStore stores.QueueStore,
+	snapshots SnapshotStore,
+	migrateSnapshot bool,
+	oracleID commontypes.OracleID,
 	n int,
 	f int,
 	logger *log.Logger,
 ) (ocr3types.ReportingPlugin[AutomationReportInfo], error) {
 	// create the value stores
-	resultStore := stores.New(logger)
-	metadataStore, err := stores.NewMetadataStore(blockSource, upkeepTypeGetter)
+	resultStore := stores.NewWithCapacity(logger, conf.ResultStoreCapacity)
+	metadataStore, err := stores.NewMetadataStoreWithLimits(logger, blockSource, upkeepTypeGetter, conf.MetadataStore.MaxConditionalProposals, conf.MetadataStore.MaxLogRecoveryProposals)
 	if err != nil {
 		return nil, err
 	}
 
+	// restore the state of the previous plugin instance before anything reads
+	// from the value stores
+	if snapshot, ok := loadSnapshot(snapshots, digest, migrateSnapshot, logger); ok {
+		resultStore.Restore(snapshot.Results...)
+		metadataStore.RestoreProposals(snapshot.MetadataProposals...)
+		logger.Printf("restored plugin snapshot of config digest %s taken at %s", snapshot.ConfigDigest, snapshot.CreatedAt)
+	}
+
 	// create a new runner instance
 	runner, err := runner.NewRunner(
 		logger,
@@ -57,16 +74,19 @@ func newPlugin(
 	}
 
 	// create the event coordinator
-	coord := coordinator.NewCoordinator(events, upkeepTypeGetter, conf, logger)
+	coord := coordinator.NewCoordinatorWithStore(events, upkeepTypeGetter, metadataStore, conf, coordinatorStore, logger)
 
-	retryQ := stores.NewRetryQueue(logger)
+	retryQ := stores.NewRetryQueueWithStore(logger, conf.MaxRetryAttempts, time.Duration(conf.MaxRetryInterval)*time.Millisecond, stores.UpkeepStateDeadLetter(upkeepStateUpdater, logger), queueStore)
 
 	retrySvc := flows.NewRetryFlow(coord, resultStore, runner, retryQ, flows.RetryCheckInterval, upkeepStateUpdater, logger)
 
-	proposalQ := stores.NewProposalQueue(upkeepTypeGetter)
+	proposalQ := stores.NewProposalQueueWithStore(upkeepTypeGetter, queueStore, logger)
+	// the final flows dequeue as many proposals per tick as an outcome can
+	// surface, such that the proposal queue keeps up with the agreed proposals
+	finalBatchSize := conf.ProtocolLimits.OutcomeSurfacedProposalsLimit
 
 	// initialize the log trigger eligibility flow
-	logTriggerFlows := flows.LogTriggerFlows(
+	logTriggerFlows := flows.LogTriggerFlowsWithBatchSize(
 		coord,
 		resultStore,
 		metadataStore,
@@ -79,6 +99,7 @@ func newPlugin(
 		flows.RecoveryFinalInterval,
 		retryQ,
 		proposalQ,
+		finalBatchSize,
 		upkeepStateUpdater,
 		logger,
 	)
@@ -86,7 +107,7 @@ func newPlugin(
 	// create service recoverers to provide panic recovery on dependent services
 	allSvcs := append(logTriggerFlows, []service.Recoverable{retrySvc, resultStore, metadataStore, coord, runner}...)
 
-	contionalFlows := flows.ConditionalTriggerFlows(
+	contionalFlows := flows.ConditionalTriggerFlowsWithBatchSize(
 		coord,
 		ratio,
 		getter,
@@ -96,6 +117,7 @@ func newPlugin(
 		metadataStore,
 		runner,
 		proposalQ,
+		finalBatchSize,
 		retryQ,
 		upkeepStateUpdater,
 		logger,
@@ -117,6 +139,7 @@ func newPlugin(
 	plugin := &ocr3Plugin{
 		ConfigDigest:                digest,
 		ReportEncoder:               encoder,
+		Registries:                  registries,
 		Coordinator:                 coord,
 		UpkeepTypeGetter:            upkeepTypeGetter,
 		WorkIDGenerator:             workIDGenerator,
@@ -124,16 +147,25 @@ func newPlugin(
 		RemoveFromMetadataHook:      hooks.NewRemoveFromMetadataHook(metadataStore, logger),
 		AddToProposalQHook:          hooks.NewAddToProposalQHook(proposalQ, logger),
 		AddBlockHistoryHook:         hooks.NewAddBlockHistoryHook(metadataStore, logger),
-		AddFromStagingHook:          hooks.NewAddFromStagingHook(resultStore, coord, logger),
+		AddFromStagingHook:          hooks.NewAddFromStagingHook(resultStore, coord, conf.StagedResultStarvationThreshold, logger),
 		AddConditionalProposalsHook: hooks.NewAddConditionalProposalsHook(metadataStore, coord, logger),
 		AddLogProposalsHook:         hooks.NewAddLogProposalsHook(metadataStore, coord, logger),
+		AddQueryHintsHook:           hooks.NewAddQueryHintsHook(metadataStore, coord, logger),
+		Scoreboard:                  scoreboard,
 		Services:                    recoverSvcs,
 		Config:                      conf,
+		OracleID:                    oracleID,
 		N:                           n,
 		F:                           f,
 		Logger:                      log.New(logger.Writer(), fmt.Sprintf("[%s | plugin]", telemetry.ServiceName), telemetry.LogPkgStdFlags),
 	}
 
+	if snapshots != nil {
+		plugin.SaveSnapshot = func() error {
+			return saveSnapshot(snapshots, digest, resultStore.Entries(), metadataStore.ProposalEntries())
+		}
+	}
+
 	plugin.startServices()
 
 	return plugin, nil
@@ -0,0 +1,63 @@
+package plugin
+
+import (
+	ocr2keepers "github.com/smartcontractkit/chainlink-common/pkg/types/automation"
+
+	"github.com/smartcontractkit/chainlink-automation/pkg/v3/config"
+)
+
+// performablesQuorum returns the number of observations a check result needs
+// to be included in the agreed performables. The quorum must only depend on
+// agreed fields so that every node applies the same quorum to a result.
+type performablesQuorum func(result ocr2keepers.CheckResult) int
+
+// newFixedQuorum applies the same quorum to all check results
+func newFixedQuorum(threshold int) performablesQuorum {
+	return func(ocr2keepers.CheckResult) int {
+		return threshold
+	}
+}
+
+// newPerformablesQuorum builds the performables quorum from the offchain config
+// for a DON that tolerates f faulty nodes. Upkeep overrides take precedence
+// over the default quorum.
+func newPerformablesQuorum(conf config.QuorumConfig, f int) performablesQuorum {
+	threshold := quorumThreshold(conf.Performables, f)
+	if len(conf.UpkeepPerformables) == 0 {
+		return newFixedQuorum(threshold)
+	}
+
+	upkeepThresholds := make(map[string]int, len(conf.UpkeepPerformables))
+	for upkeepID, quorum := range conf.UpkeepPerformables {
+		upkeepThresholds[upkeepID] = quorumThreshold(quorum, f)
+	}
+
+	return func(result ocr2keepers.CheckResult) int {
+		if t, ok := upkeepThresholds[result.UpkeepID.String()]; ok {
+			return t
+		}
+		return threshold
+	}
+}
+
+// coordinatedBlockQuorum returns the number of observations a block needs to be
+// used as the coordinated block. It is never lower than f+1 so that at least
+// one honest node observed the block, and never higher than 2f+1, the number
+// of observations an outcome is guaranteed to be built from.
+func coordinatedBlockQuorum(conf config.QuorumConfig, f int) int {
+	threshold := f + 1
+	if conf.CoordinatedBlockMinimum > threshold {
+		threshold = conf.CoordinatedBlockMinimum
+	}
+	if threshold > 2*f+1 {
+		threshold = 2*f + 1
+	}
+	return threshold
+}
+
+func quorumThreshold(quorum string, f int) int {
+	if quorum == config.QuorumTwoFPlusOne {
+		return 2*f + 1
+	}
+	return f + 1
+}
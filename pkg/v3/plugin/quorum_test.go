@@ -0,0 +1,80 @@
+package plugin
+
+import (
+	"bytes"
+	"log"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	ocr2keepers "github.com/smartcontractkit/chainlink-common/pkg/types/automation"
+
+	ocr2keepersv3 "github.com/smartcontractkit/chainlink-automation/pkg/v3"
+	"github.com/smartcontractkit/chainlink-automation/pkg/v3/config"
+)
+
+func TestPerformablesQuorum(t *testing.T) {
+	result := func(id int64) ocr2keepers.CheckResult {
+		uid := ocr2keepers.UpkeepIdentifier{}
+		uid.FromBigInt(big.NewInt(id))
+		return ocr2keepers.CheckResult{UpkeepID: uid, WorkID: uid.String()}
+	}
+
+	t.Run("defaults to f+1", func(t *testing.T) {
+		quorum := newPerformablesQuorum(config.QuorumConfig{Performables: config.QuorumFPlusOne}, 2)
+		assert.Equal(t, 3, quorum(result(1)))
+	})
+
+	t.Run("2f+1 for all upkeeps", func(t *testing.T) {
+		quorum := newPerformablesQuorum(config.QuorumConfig{Performables: config.QuorumTwoFPlusOne}, 2)
+		assert.Equal(t, 5, quorum(result(1)))
+	})
+
+	t.Run("upkeep overrides take precedence", func(t *testing.T) {
+		quorum := newPerformablesQuorum(config.QuorumConfig{
+			Performables:       config.QuorumFPlusOne,
+			UpkeepPerformables: map[string]string{"7": config.QuorumTwoFPlusOne},
+		}, 2)
+		assert.Equal(t, 5, quorum(result(7)))
+		assert.Equal(t, 3, quorum(result(8)))
+	})
+
+	t.Run("results of high value upkeeps need more observations", func(t *testing.T) {
+		quorum := newPerformablesQuorum(config.QuorumConfig{
+			Performables:       config.QuorumFPlusOne,
+			UpkeepPerformables: map[string]string{"7": config.QuorumTwoFPlusOne},
+		}, 1)
+
+		p := newPerformables(quorum, 10, [16]byte{}, nil, true, log.New(&bytes.Buffer{}, "", 0))
+		for i := 0; i < 2; i++ {
+			p.add(ocr2keepersv3.AutomationObservation{Performable: []ocr2keepers.CheckResult{result(7), result(8)}})
+		}
+
+		outcome := ocr2keepersv3.AutomationOutcome{}
+		p.set(&outcome, ocr2keepersv3.AutomationOutcome{})
+		assert.Equal(t, []ocr2keepers.CheckResult{result(8)}, outcome.AgreedPerformables)
+
+		p.add(ocr2keepersv3.AutomationObservation{Performable: []ocr2keepers.CheckResult{result(7)}})
+		p.set(&outcome, ocr2keepersv3.AutomationOutcome{})
+		assert.ElementsMatch(t, []ocr2keepers.CheckResult{result(7), result(8)}, outcome.AgreedPerformables)
+	})
+}
+
+func TestCoordinatedBlockQuorum(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		minimum  int
+		f        int
+		expected int
+	}{
+		{name: "defaults to f+1", minimum: 0, f: 1, expected: 2},
+		{name: "minimum below f+1 is raised", minimum: 1, f: 2, expected: 3},
+		{name: "configured minimum", minimum: 4, f: 2, expected: 4},
+		{name: "minimum above 2f+1 is reduced", minimum: 7, f: 2, expected: 5},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, coordinatedBlockQuorum(config.QuorumConfig{CoordinatedBlockMinimum: tc.minimum}, tc.f))
+		})
+	}
+}
@@ -0,0 +1,377 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/ocr3types"
+	ocr2plustypes "github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+
+	ocr2keepers "github.com/smartcontractkit/chainlink-common/pkg/types/automation"
+
+	"github.com/smartcontractkit/chainlink-automation/pkg/v3/types"
+)
+
+// registryWorkIDSeparator separates the registry name from the workID of the
+// registry in namespaced workIDs
+const registryWorkIDSeparator = ":"
+
+// RegistryProviders binds the providers of a single registry for a plugin
+// instance that serves multiple registries on the same chain.
+type RegistryProviders struct {
+	// Name identifies the registry in namespaced workIDs, in the registries
+	// section of the offchain config and in the info of reports. Names must
+	// be unique and cannot contain ':'.
+	Name string
+	// Owns reports whether an upkeep belongs to the registry. Every node of
+	// the DON must resolve an upkeep to the same registry.
+	Owns func(ocr2keepers.UpkeepIdentifier) bool
+
+	LogProvider         ocr2keepers.LogEventProvider
+	EventProvider       types.TransmitEventProvider
+	Runnable            types.Runnable
+	RecoverableProvider ocr2keepers.RecoverableProvider
+	PayloadBuilder      ocr2keepers.PayloadBuilder
+	UpkeepProvider      ocr2keepers.ConditionalUpkeepProvider
+	Encoder             ocr2keepers.Encoder
+	UpkeepTypeGetter    types.UpkeepTypeGetter
+	WorkIDGenerator     types.WorkIDGenerator
+	UpkeepStateUpdater  ocr2keepers.UpkeepStateUpdater
+
+	// ContractTransmitter transmits the reports of the registry and
+	// ContractConfigTracker tracks the OCR config of the registry. Reports of
+	// all registries are signed under the config digest of the DON, so every
+	// registry must be configured with the same OCR config as the DON.
+	ContractTransmitter   ocr3types.ContractTransmitter[AutomationReportInfo]
+	ContractConfigTracker ocr2plustypes.ContractConfigTracker
+}
+
+// registryMux multiplexes the providers of multiple registries such that the
+// plugin can use them as the providers of a single registry. WorkIDs of all
+// registries are namespaced by the registry name, such that the workIDs of
+// different registries never collide. WorkIDs are namespaced as they enter the
+// plugin and restored before they are passed back to the providers of the
+// registry.
+type registryMux struct {
+	registries []RegistryProviders
+	byName     map[string]int
+	logger     *log.Logger
+}
+
+func newRegistryMux(registries []RegistryProviders, logger *log.Logger) (*registryMux, error) {
+	if len(registries) == 0 {
+		return nil, fmt.Errorf("at least one registry is required")
+	}
+
+	m := &registryMux{
+		registries: registries,
+		byName:     make(map[string]int, len(registries)),
+		logger:     logger,
+	}
+	for i, r := range registries {
+		if r.Name == "" || strings.Contains(r.Name, registryWorkIDSeparator) {
+			return nil, fmt.Errorf("invalid registry name '%s'", r.Name)
+		}
+		if _, ok := m.byName[r.Name]; ok {
+			return nil, fmt.Errorf("duplicate registry name '%s'", r.Name)
+		}
+		if r.Owns == nil {
+			return nil, fmt.Errorf("registry '%s' cannot resolve its upkeeps", r.Name)
+		}
+		m.byName[r.Name] = i
+	}
+	return m, nil
+}
+
+// namespace prefixes a workID of the registry with the registry name
+func namespace(registry, workID string) string {
+	return registry + registryWorkIDSeparator + workID
+}
+
+// splitWorkID returns the registry and the registry workID of a namespaced
+// workID
+func (m *registryMux) splitWorkID(workID string) (int, string, bool) {
+	name, registryWorkID, ok := strings.Cut(workID, registryWorkIDSeparator)
+	if !ok {
+		return 0, "", false
+	}
+	i, ok := m.byName[name]
+	return i, registryWorkID, ok
+}
+
+// registryOf returns the registry that owns the upkeep
+func (m *registryMux) registryOf(id ocr2keepers.UpkeepIdentifier) (int, bool) {
+	for i, r := range m.registries {
+		if r.Owns(id) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// UpkeepTypeGetter resolves the upkeep type with the registry of the upkeep
+func (m *registryMux) UpkeepTypeGetter(id ocr2keepers.UpkeepIdentifier) types.UpkeepType {
+	i, ok := m.registryOf(id)
+	if !ok {
+		// the upkeep type is encoded in the upkeep ID, any registry can tell
+		i = 0
+	}
+	return m.registries[i].UpkeepTypeGetter(id)
+}
+
+// WorkIDGenerator generates a namespaced workID with the registry of the
+// upkeep. Upkeeps that do not belong to any registry get an empty workID,
+// which fails validation of observations and outcomes.
+func (m *registryMux) WorkIDGenerator(id ocr2keepers.UpkeepIdentifier, trigger ocr2keepers.Trigger) string {
+	i, ok := m.registryOf(id)
+	if !ok {
+		return ""
+	}
+	r := m.registries[i]
+	return namespace(r.Name, r.WorkIDGenerator(id, trigger))
+}
+
+// collectPayloads gets payloads from every registry and namespaces their
+// workIDs. An error is only returned if all registries failed, the payloads of
+// the other registries are returned otherwise.
+func (m *registryMux) collectPayloads(ctx context.Context, get func(context.Context, RegistryProviders) ([]ocr2keepers.UpkeepPayload, error)) ([]ocr2keepers.UpkeepPayload, error) {
+	var (
+		payloads []ocr2keepers.UpkeepPayload
+		errs     error
+		failed   int
+	)
+	for _, r := range m.registries {
+		registryPayloads, err := get(ctx, r)
+		if err != nil {
+			m.logger.Printf("failed to get payloads of registry '%s': %s", r.Name, err)
+			errs = errors.Join(errs, fmt.Errorf("registry '%s': %w", r.Name, err))
+			failed++
+			continue
+		}
+		for _, payload := range registryPayloads {
+			payload.WorkID = namespace(r.Name, payload.WorkID)
+			payloads = append(payloads, payload)
+		}
+	}
+	if failed == len(m.registries) {
+		return nil, errs
+	}
+	return payloads, nil
+}
+
+// GetLatestPayloads implements ocr2keepers.LogEventProvider
+func (m *registryMux) GetLatestPayloads(ctx context.Context) ([]ocr2keepers.UpkeepPayload, error) {
+	return m.collectPayloads(ctx, func(ctx context.Context, r RegistryProviders) ([]ocr2keepers.UpkeepPayload, error) {
+		return r.LogProvider.GetLatestPayloads(ctx)
+	})
+}
+
+// SetConfig implements ocr2keepers.LogEventProvider
+func (m *registryMux) SetConfig(conf ocr2keepers.LogEventProviderConfig) {
+	for _, r := range m.registries {
+		r.LogProvider.SetConfig(conf)
+	}
+}
+
+// Start implements ocr2keepers.LogEventProvider
+func (m *registryMux) Start(ctx context.Context) error {
+	var err error
+	for _, r := range m.registries {
+		err = errors.Join(err, r.LogProvider.Start(ctx))
+	}
+	return err
+}
+
+// Close implements ocr2keepers.LogEventProvider
+func (m *registryMux) Close() error {
+	var err error
+	for _, r := range m.registries {
+		err = errors.Join(err, r.LogProvider.Close())
+	}
+	return err
+}
+
+// GetRecoveryProposals implements ocr2keepers.RecoverableProvider
+func (m *registryMux) GetRecoveryProposals(ctx context.Context) ([]ocr2keepers.UpkeepPayload, error) {
+	return m.collectPayloads(ctx, func(ctx context.Context, r RegistryProviders) ([]ocr2keepers.UpkeepPayload, error) {
+		return r.RecoverableProvider.GetRecoveryProposals(ctx)
+	})
+}
+
+// GetActiveUpkeeps implements ocr2keepers.ConditionalUpkeepProvider
+func (m *registryMux) GetActiveUpkeeps(ctx context.Context) ([]ocr2keepers.UpkeepPayload, error) {
+	return m.collectPayloads(ctx, func(ctx context.Context, r RegistryProviders) ([]ocr2keepers.UpkeepPayload, error) {
+		return r.UpkeepProvider.GetActiveUpkeeps(ctx)
+	})
+}
+
+// BuildPayloads implements ocr2keepers.PayloadBuilder. An error is only
+// returned if all registries failed, the payloads of the other registries are
+// returned otherwise.
+func (m *registryMux) BuildPayloads(ctx context.Context, proposals ...ocr2keepers.CoordinatedBlockProposal) ([]ocr2keepers.UpkeepPayload, error) {
+	grouped := make([][]ocr2keepers.CoordinatedBlockProposal, len(m.registries))
+	for _, proposal := range proposals {
+		i, workID, ok := m.splitWorkID(proposal.WorkID)
+		if !ok {
+			m.logger.Printf("skipping proposal with workID '%s' of an unknown registry", proposal.WorkID)
+			continue
+		}
+		proposal.WorkID = workID
+		grouped[i] = append(grouped[i], proposal)
+	}
+
+	var (
+		payloads        []ocr2keepers.UpkeepPayload
+		errs            error
+		queried, failed int
+	)
+	for i, group := range grouped {
+		if len(group) == 0 {
+			continue
+		}
+		queried++
+		r := m.registries[i]
+		registryPayloads, err := r.PayloadBuilder.BuildPayloads(ctx, group...)
+		if err != nil {
+			m.logger.Printf("failed to build payloads of registry '%s': %s", r.Name, err)
+			errs = errors.Join(errs, fmt.Errorf("registry '%s': %w", r.Name, err))
+			failed++
+			continue
+		}
+		for _, payload := range registryPayloads {
+			if payload.WorkID != "" {
+				payload.WorkID = namespace(r.Name, payload.WorkID)
+			}
+			payloads = append(payloads, payload)
+		}
+	}
+	if queried > 0 && failed == queried {
+		return nil, errs
+	}
+	return payloads, nil
+}
+
+// CheckUpkeeps implements types.Runnable. An error is only
+// returned if all registries failed, the results of the other registries are
+// returned otherwise.
+func (m *registryMux) CheckUpkeeps(ctx context.Context, payloads ...ocr2keepers.UpkeepPayload) ([]ocr2keepers.CheckResult, error) {
+	grouped := make([][]ocr2keepers.UpkeepPayload, len(m.registries))
+	for _, payload := range payloads {
+		i, workID, ok := m.splitWorkID(payload.WorkID)
+		if !ok {
+			m.logger.Printf("skipping payload with workID '%s' of an unknown registry", payload.WorkID)
+			continue
+		}
+		payload.WorkID = workID
+		grouped[i] = append(grouped[i], payload)
+	}
+
+	var (
+		results         []ocr2keepers.CheckResult
+		errs            error
+		queried, failed int
+	)
+	for i, group := range grouped {
+		if len(group) == 0 {
+			continue
+		}
+		queried++
+		r := m.registries[i]
+		registryResults, err := r.Runnable.CheckUpkeeps(ctx, group...)
+		if err != nil {
+			m.logger.Printf("failed to check upkeeps of registry '%s': %s", r.Name, err)
+			errs = errors.Join(errs, fmt.Errorf("registry '%s': %w", r.Name, err))
+			failed++
+			continue
+		}
+		for _, result := range registryResults {
+			result.WorkID = namespace(r.Name, result.WorkID)
+			results = append(results, result)
+		}
+	}
+	if queried > 0 && failed == queried {
+		return nil, errs
+	}
+	return results, nil
+}
+
+// GetLatestEvents implements types.TransmitEventProvider. An error is only
+// returned if all registries failed, the events of the other registries are
+// returned otherwise.
+func (m *registryMux) GetLatestEvents(ctx context.Context) ([]ocr2keepers.TransmitEvent, error) {
+	var (
+		events []ocr2keepers.TransmitEvent
+		errs   error
+		failed int
+	)
+	for _, r := range m.registries {
+		registryEvents, err := r.EventProvider.GetLatestEvents(ctx)
+		if err != nil {
+			m.logger.Printf("failed to get latest events of registry '%s': %s", r.Name, err)
+			errs = errors.Join(errs, fmt.Errorf("registry '%s': %w", r.Name, err))
+			failed++
+			continue
+		}
+		for _, event := range registryEvents {
+			event.WorkID = namespace(r.Name, event.WorkID)
+			events = append(events, event)
+		}
+	}
+	if failed == len(m.registries) {
+		return nil, errs
+	}
+	return events, nil
+}
+
+// SetUpkeepState implements ocr2keepers.UpkeepStateUpdater
+func (m *registryMux) SetUpkeepState(ctx context.Context, result ocr2keepers.CheckResult, state ocr2keepers.UpkeepState) error {
+	i, workID, ok := m.splitWorkID(result.WorkID)
+	if !ok {
+		return fmt.Errorf("workID '%s' of an unknown registry", result.WorkID)
+	}
+	result.WorkID = workID
+	return m.registries[i].UpkeepStateUpdater.SetUpkeepState(ctx, result, state)
+}
+
+// encoder returns the report encoder of the registry
+func (m *registryMux) encoder(name string) (ocr2keepers.Encoder, bool) {
+	i, ok := m.byName[name]
+	if !ok {
+		return nil, false
+	}
+	return registryEncoder{name: name, encoder: m.registries[i].Encoder}, true
+}
+
+// registryEncoder encodes reports for a single registry, restoring the
+// workIDs of the registry on encoding and namespacing them on extraction
+type registryEncoder struct {
+	name    string
+	encoder ocr2keepers.Encoder
+}
+
+func (e registryEncoder) Encode(results ...ocr2keepers.CheckResult) ([]byte, error) {
+	restored := make([]ocr2keepers.CheckResult, len(results))
+	for i, result := range results {
+		name, workID, ok := strings.Cut(result.WorkID, registryWorkIDSeparator)
+		if !ok || name != e.name {
+			return nil, fmt.Errorf("result with workID '%s' does not belong to registry '%s'", result.WorkID, e.name)
+		}
+		result.WorkID = workID
+		restored[i] = result
+	}
+	return e.encoder.Encode(restored...)
+}
+
+func (e registryEncoder) Extract(report []byte) ([]ocr2keepers.ReportedUpkeep, error) {
+	upkeeps, err := e.encoder.Extract(report)
+	if err != nil {
+		return nil, err
+	}
+	for i := range upkeeps {
+		upkeeps[i].WorkID = namespace(e.name, upkeeps[i].WorkID)
+	}
+	return upkeeps, nil
+}
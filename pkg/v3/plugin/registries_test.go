@@ -0,0 +1,314 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"math/big"
+	"testing"
+
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/ocr3types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ocr2keepers "github.com/smartcontractkit/chainlink-common/pkg/types/automation"
+
+	ocr2keepersv3 "github.com/smartcontractkit/chainlink-automation/pkg/v3"
+	"github.com/smartcontractkit/chainlink-automation/pkg/v3/config"
+)
+
+type mockPayloadProvider struct {
+	ocr2keepers.LogEventProvider
+	ocr2keepers.ConditionalUpkeepProvider
+	payloads []ocr2keepers.UpkeepPayload
+	err      error
+}
+
+func (p *mockPayloadProvider) GetActiveUpkeeps(_ context.Context) ([]ocr2keepers.UpkeepPayload, error) {
+	return p.payloads, p.err
+}
+
+type mockRunnable struct {
+	checked []ocr2keepers.UpkeepPayload
+	err     error
+}
+
+func (r *mockRunnable) CheckUpkeeps(_ context.Context, payloads ...ocr2keepers.UpkeepPayload) ([]ocr2keepers.CheckResult, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	r.checked = append(r.checked, payloads...)
+	results := make([]ocr2keepers.CheckResult, len(payloads))
+	for i, payload := range payloads {
+		results[i] = ocr2keepers.CheckResult{UpkeepID: payload.UpkeepID, WorkID: payload.WorkID}
+	}
+	return results, nil
+}
+
+type mockPayloadBuilder struct {
+	err error
+}
+
+func (b *mockPayloadBuilder) BuildPayloads(_ context.Context, proposals ...ocr2keepers.CoordinatedBlockProposal) ([]ocr2keepers.UpkeepPayload, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	payloads := make([]ocr2keepers.UpkeepPayload, len(proposals))
+	for i, proposal := range proposals {
+		payloads[i] = ocr2keepers.UpkeepPayload{UpkeepID: proposal.UpkeepID, WorkID: proposal.WorkID}
+	}
+	return payloads, nil
+}
+
+type mockEventProvider struct {
+	events []ocr2keepers.TransmitEvent
+	err    error
+}
+
+func (p *mockEventProvider) GetLatestEvents(_ context.Context) ([]ocr2keepers.TransmitEvent, error) {
+	return p.events, p.err
+}
+
+func testUpkeepID(id int64) ocr2keepers.UpkeepIdentifier {
+	uid := ocr2keepers.UpkeepIdentifier{}
+	uid.FromBigInt(big.NewInt(id))
+	return uid
+}
+
+// ownsRange returns an Owns function for the upkeep IDs in [from, to)
+func ownsRange(from, to int64) func(ocr2keepers.UpkeepIdentifier) bool {
+	return func(id ocr2keepers.UpkeepIdentifier) bool {
+		n := id.BigInt().Int64()
+		return n >= from && n < to
+	}
+}
+
+func testWorkIDGenerator(id ocr2keepers.UpkeepIdentifier, _ ocr2keepers.Trigger) string {
+	return id.String()
+}
+
+func TestNewRegistryMux_Validation(t *testing.T) {
+	logger := log.New(io.Discard, "", 0)
+	owns := ownsRange(0, 1)
+
+	_, err := newRegistryMux(nil, logger)
+	assert.ErrorContains(t, err, "at least one registry is required")
+
+	_, err = newRegistryMux([]RegistryProviders{{Name: "a:b", Owns: owns}}, logger)
+	assert.ErrorContains(t, err, "invalid registry name 'a:b'")
+
+	_, err = newRegistryMux([]RegistryProviders{{Name: "a", Owns: owns}, {Name: "a", Owns: owns}}, logger)
+	assert.ErrorContains(t, err, "duplicate registry name 'a'")
+
+	_, err = newRegistryMux([]RegistryProviders{{Name: "a"}}, logger)
+	assert.ErrorContains(t, err, "registry 'a' cannot resolve its upkeeps")
+}
+
+func TestRegistryMux_NamespacesWorkIDs(t *testing.T) {
+	runnableA, runnableB := &mockRunnable{}, &mockRunnable{}
+
+	mux, err := newRegistryMux([]RegistryProviders{
+		{
+			Name:             "a",
+			Owns:             ownsRange(0, 100),
+			UpkeepProvider:   &mockPayloadProvider{payloads: []ocr2keepers.UpkeepPayload{{UpkeepID: testUpkeepID(1), WorkID: "1"}}},
+			Runnable:         runnableA,
+			UpkeepTypeGetter: mockUpkeepTypeGetter,
+			WorkIDGenerator:  testWorkIDGenerator,
+		},
+		{
+			Name:             "b",
+			Owns:             ownsRange(100, 200),
+			UpkeepProvider:   &mockPayloadProvider{payloads: []ocr2keepers.UpkeepPayload{{UpkeepID: testUpkeepID(101), WorkID: "1"}}},
+			Runnable:         runnableB,
+			UpkeepTypeGetter: mockUpkeepTypeGetter,
+			WorkIDGenerator:  testWorkIDGenerator,
+		},
+	}, log.New(io.Discard, "", 0))
+	require.NoError(t, err)
+
+	// the same registry workID does not collide across registries
+	payloads, err := mux.GetActiveUpkeeps(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []ocr2keepers.UpkeepPayload{
+		{UpkeepID: testUpkeepID(1), WorkID: "a:1"},
+		{UpkeepID: testUpkeepID(101), WorkID: "b:1"},
+	}, payloads)
+
+	// every registry only checks its own payloads with its own workIDs
+	results, err := mux.CheckUpkeeps(context.Background(), payloads...)
+	require.NoError(t, err)
+	assert.Equal(t, []ocr2keepers.UpkeepPayload{{UpkeepID: testUpkeepID(1), WorkID: "1"}}, runnableA.checked)
+	assert.Equal(t, []ocr2keepers.UpkeepPayload{{UpkeepID: testUpkeepID(101), WorkID: "1"}}, runnableB.checked)
+	assert.Equal(t, "a:1", results[0].WorkID)
+	assert.Equal(t, "b:1", results[1].WorkID)
+
+	assert.Equal(t, "a:"+testUpkeepID(1).String(), mux.WorkIDGenerator(testUpkeepID(1), ocr2keepers.Trigger{}))
+	assert.Equal(t, "b:"+testUpkeepID(101).String(), mux.WorkIDGenerator(testUpkeepID(101), ocr2keepers.Trigger{}))
+	assert.Equal(t, "", mux.WorkIDGenerator(testUpkeepID(201), ocr2keepers.Trigger{}))
+}
+
+func TestRegistryMux_PartialFailure(t *testing.T) {
+	mux, err := newRegistryMux([]RegistryProviders{
+		{Name: "a", Owns: ownsRange(0, 100), UpkeepProvider: &mockPayloadProvider{err: errors.New("rpc down")}},
+		{Name: "b", Owns: ownsRange(100, 200), UpkeepProvider: &mockPayloadProvider{payloads: []ocr2keepers.UpkeepPayload{{WorkID: "1"}}}},
+	}, log.New(io.Discard, "", 0))
+	require.NoError(t, err)
+
+	// a failing registry does not hold up the other registries
+	payloads, err := mux.GetActiveUpkeeps(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []ocr2keepers.UpkeepPayload{{WorkID: "b:1"}}, payloads)
+
+	mux.registries[1].UpkeepProvider = &mockPayloadProvider{err: errors.New("rpc down")}
+	_, err = mux.GetActiveUpkeeps(context.Background())
+	assert.ErrorContains(t, err, "registry 'a': rpc down")
+	assert.ErrorContains(t, err, "registry 'b': rpc down")
+}
+
+func TestRegistryMux_PartialFailureOfBatches(t *testing.T) {
+	runnableB := &mockRunnable{}
+	mux, err := newRegistryMux([]RegistryProviders{
+		{
+			Name:           "a",
+			Owns:           ownsRange(0, 100),
+			Runnable:       &mockRunnable{err: errors.New("rpc down")},
+			PayloadBuilder: &mockPayloadBuilder{err: errors.New("rpc down")},
+			EventProvider:  &mockEventProvider{err: errors.New("rpc down")},
+		},
+		{
+			Name:           "b",
+			Owns:           ownsRange(100, 200),
+			Runnable:       runnableB,
+			PayloadBuilder: &mockPayloadBuilder{},
+			EventProvider:  &mockEventProvider{events: []ocr2keepers.TransmitEvent{{WorkID: "1"}}},
+		},
+	}, log.New(io.Discard, "", 0))
+	require.NoError(t, err)
+
+	// the events of the other registries are returned without an error
+	events, err := mux.GetLatestEvents(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []ocr2keepers.TransmitEvent{{WorkID: "b:1"}}, events)
+
+	payloads, err := mux.BuildPayloads(context.Background(),
+		ocr2keepers.CoordinatedBlockProposal{UpkeepID: testUpkeepID(1), WorkID: "a:1"},
+		ocr2keepers.CoordinatedBlockProposal{UpkeepID: testUpkeepID(101), WorkID: "b:1"},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, []ocr2keepers.UpkeepPayload{{UpkeepID: testUpkeepID(101), WorkID: "b:1"}}, payloads)
+
+	results, err := mux.CheckUpkeeps(context.Background(),
+		ocr2keepers.UpkeepPayload{UpkeepID: testUpkeepID(1), WorkID: "a:1"},
+		ocr2keepers.UpkeepPayload{UpkeepID: testUpkeepID(101), WorkID: "b:1"},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, []ocr2keepers.CheckResult{{UpkeepID: testUpkeepID(101), WorkID: "b:1"}}, results)
+	assert.Equal(t, []ocr2keepers.UpkeepPayload{{UpkeepID: testUpkeepID(101), WorkID: "1"}}, runnableB.checked)
+
+	// an error is returned once all registries of the batch failed
+	_, err = mux.CheckUpkeeps(context.Background(), ocr2keepers.UpkeepPayload{UpkeepID: testUpkeepID(1), WorkID: "a:1"})
+	assert.ErrorContains(t, err, "registry 'a': rpc down")
+
+	_, err = mux.BuildPayloads(context.Background(), ocr2keepers.CoordinatedBlockProposal{UpkeepID: testUpkeepID(1), WorkID: "a:1"})
+	assert.ErrorContains(t, err, "registry 'a': rpc down")
+
+	mux.registries[1].EventProvider = &mockEventProvider{err: errors.New("rpc down")}
+	_, err = mux.GetLatestEvents(context.Background())
+	assert.ErrorContains(t, err, "registry 'a': rpc down")
+	assert.ErrorContains(t, err, "registry 'b': rpc down")
+}
+
+func TestOcr3Plugin_Reports_MultipleRegistries(t *testing.T) {
+	// the encoder of every registry prefixes the report with the registry name
+	encoder := func(name string) ocr2keepers.Encoder {
+		return &mockEncoder{
+			EncodeFn: func(results ...ocr2keepers.CheckResult) ([]byte, error) {
+				b, err := json.Marshal(results)
+				return append([]byte(name), b...), err
+			},
+			ExtractFn: func(b []byte) ([]ocr2keepers.ReportedUpkeep, error) {
+				var results []ocr2keepers.CheckResult
+				if err := json.Unmarshal(b[len(name):], &results); err != nil {
+					return nil, err
+				}
+				upkeeps := make([]ocr2keepers.ReportedUpkeep, len(results))
+				for i, result := range results {
+					upkeeps[i] = ocr2keepers.ReportedUpkeep{UpkeepID: result.UpkeepID, WorkID: result.WorkID}
+				}
+				return upkeeps, nil
+			},
+		}
+	}
+
+	mux, err := newRegistryMux([]RegistryProviders{
+		{Name: "a", Owns: ownsRange(0, 3), Encoder: encoder("a"), UpkeepTypeGetter: mockUpkeepTypeGetter, WorkIDGenerator: testWorkIDGenerator},
+		{Name: "b", Owns: ownsRange(3, 10), Encoder: encoder("b"), UpkeepTypeGetter: mockUpkeepTypeGetter, WorkIDGenerator: testWorkIDGenerator},
+	}, log.New(io.Discard, "", 0))
+	require.NoError(t, err)
+
+	result := func(id int64) ocr2keepers.CheckResult {
+		uid := testUpkeepID(id)
+		return ocr2keepers.CheckResult{
+			Eligible:     true,
+			UpkeepID:     uid,
+			WorkID:       mux.WorkIDGenerator(uid, ocr2keepers.Trigger{}),
+			GasAllocated: 100,
+			FastGasWei:   big.NewInt(1),
+			LinkNative:   big.NewInt(1),
+		}
+	}
+
+	outcome, err := ocr2keepersv3.AutomationOutcome{
+		AgreedPerformables: []ocr2keepers.CheckResult{result(3), result(1), result(4), result(2)},
+	}.Encode()
+	require.NoError(t, err)
+
+	plugin := &ocr3Plugin{
+		Config: config.OffchainConfig{
+			ProtocolLimits:       config.DefaultProtocolLimitsV0,
+			GasLimitPerReport:    1000,
+			GasOverheadPerUpkeep: 0,
+			MaxUpkeepBatchSize:   10,
+			// registry b only fits a single upkeep in a report
+			Registries: map[string]config.RegistryReportConfig{
+				"b": {GasLimitPerReport: 150},
+			},
+		},
+		Registries:       mux,
+		UpkeepTypeGetter: mux.UpkeepTypeGetter,
+		WorkIDGenerator:  mux.WorkIDGenerator,
+		Logger:           log.New(io.Discard, "", 0),
+	}
+
+	reports, err := plugin.Reports(context.Background(), 1, outcome)
+	require.NoError(t, err)
+	require.Len(t, reports, 3)
+
+	assert.Equal(t, AutomationReportInfo{Registry: "a"}, reports[0].ReportWithInfo.Info)
+	assert.Equal(t, AutomationReportInfo{Registry: "b"}, reports[1].ReportWithInfo.Info)
+	assert.Equal(t, AutomationReportInfo{Registry: "b"}, reports[2].ReportWithInfo.Info)
+
+	// reports are encoded with the workIDs of the registry and extracted with
+	// the namespaced workIDs of the plugin
+	var encoded []ocr2keepers.CheckResult
+	require.NoError(t, json.Unmarshal(reports[0].ReportWithInfo.Report[1:], &encoded))
+	assert.Equal(t, testUpkeepID(1).String(), encoded[0].WorkID)
+
+	var extracted []ocr2keepers.ReportedUpkeep
+	plugin.Coordinator = &mockCoordinator{
+		ShouldTransmitFn: func(upkeep ocr2keepers.ReportedUpkeep) bool {
+			extracted = append(extracted, upkeep)
+			return true
+		},
+	}
+	transmit, err := plugin.ShouldTransmitAcceptedReport(context.Background(), 1, reports[0].ReportWithInfo)
+	require.NoError(t, err)
+	assert.True(t, transmit)
+	assert.Equal(t, []string{"a:" + testUpkeepID(1).String(), "a:" + testUpkeepID(2).String()}, []string{extracted[0].WorkID, extracted[1].WorkID})
+
+	_, err = plugin.ShouldTransmitAcceptedReport(context.Background(), 1, ocr3types.ReportWithInfo[AutomationReportInfo]{Info: AutomationReportInfo{Registry: "c"}})
+	assert.ErrorContains(t, err, "no encoder for registry 'c'")
+}
@@ -0,0 +1,77 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/ocr3types"
+	ocr2plustypes "github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+)
+
+// registryTransmitter routes every report to the transmitter of the registry
+// named in the report info. Reports are signed once for the DON under its
+// config digest, so a registry can only verify a report if it is configured
+// with the same OCR config, signers and transmitters as the DON. The
+// transmitter checks the config digest of the registry before transmitting,
+// such that a registry with a different config fails here instead of on chain.
+type registryTransmitter struct {
+	registries []RegistryProviders
+	byName     map[string]int
+}
+
+var _ ocr3types.ContractTransmitter[AutomationReportInfo] = &registryTransmitter{}
+
+func newRegistryTransmitter(registries []RegistryProviders) (*registryTransmitter, error) {
+	t := &registryTransmitter{
+		registries: registries,
+		byName:     make(map[string]int, len(registries)),
+	}
+	for i, r := range registries {
+		if r.ContractTransmitter == nil {
+			return nil, fmt.Errorf("registry '%s' has no contract transmitter", r.Name)
+		}
+		if r.ContractConfigTracker == nil {
+			return nil, fmt.Errorf("registry '%s' has no contract config tracker", r.Name)
+		}
+		t.byName[r.Name] = i
+	}
+	return t, nil
+}
+
+// Transmit sends the report to the registry named in the report info after
+// checking that the registry verifies reports of the config digest
+func (t *registryTransmitter) Transmit(ctx context.Context, digest ocr2plustypes.ConfigDigest, seqNr uint64, report ocr3types.ReportWithInfo[AutomationReportInfo], sigs []ocr2plustypes.AttributedOnchainSignature) error {
+	i, ok := t.byName[report.Info.Registry]
+	if !ok {
+		return fmt.Errorf("no transmitter for registry '%s'", report.Info.Registry)
+	}
+	r := t.registries[i]
+
+	_, registryDigest, err := r.ContractConfigTracker.LatestConfigDetails(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get the config digest of registry '%s': %w", r.Name, err)
+	}
+	if registryDigest != digest {
+		return fmt.Errorf("registry '%s' is configured with config digest %s, the report is signed under %s", r.Name, registryDigest, digest)
+	}
+
+	return r.ContractTransmitter.Transmit(ctx, digest, seqNr, report, sigs)
+}
+
+// FromAccount returns the account all registries transmit from. The DON has a
+// single transmitter account per oracle, so the transmitters of all registries
+// must use the same account.
+func (t *registryTransmitter) FromAccount(ctx context.Context) (ocr2plustypes.Account, error) {
+	var account ocr2plustypes.Account
+	for i, r := range t.registries {
+		registryAccount, err := r.ContractTransmitter.FromAccount(ctx)
+		if err != nil {
+			return "", fmt.Errorf("failed to get the transmitter account of registry '%s': %w", r.Name, err)
+		}
+		if i > 0 && registryAccount != account {
+			return "", fmt.Errorf("registry '%s' transmits from account %s instead of %s", r.Name, registryAccount, account)
+		}
+		account = registryAccount
+	}
+	return account, nil
+}
@@ -0,0 +1,79 @@
+package plugin
+
+import (
+	"context"
+	"testing"
+
+	"github.com/smartcontractkit/libocr/offchainreporting2plus/ocr3types"
+	ocr2plustypes "github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockContractTransmitter struct {
+	account     ocr2plustypes.Account
+	transmitted []ocr3types.ReportWithInfo[AutomationReportInfo]
+}
+
+func (t *mockContractTransmitter) Transmit(_ context.Context, _ ocr2plustypes.ConfigDigest, _ uint64, report ocr3types.ReportWithInfo[AutomationReportInfo], _ []ocr2plustypes.AttributedOnchainSignature) error {
+	t.transmitted = append(t.transmitted, report)
+	return nil
+}
+
+func (t *mockContractTransmitter) FromAccount(_ context.Context) (ocr2plustypes.Account, error) {
+	return t.account, nil
+}
+
+type mockContractConfigTracker struct {
+	ocr2plustypes.ContractConfigTracker
+	digest ocr2plustypes.ConfigDigest
+}
+
+func (t *mockContractConfigTracker) LatestConfigDetails(_ context.Context) (uint64, ocr2plustypes.ConfigDigest, error) {
+	return 1, t.digest, nil
+}
+
+func TestRegistryTransmitter(t *testing.T) {
+	digest := ocr2plustypes.ConfigDigest{1}
+	a := &mockContractTransmitter{account: "0xabc"}
+	b := &mockContractTransmitter{account: "0xabc"}
+	bTracker := &mockContractConfigTracker{digest: digest}
+
+	_, err := newRegistryTransmitter([]RegistryProviders{{Name: "a"}})
+	assert.ErrorContains(t, err, "registry 'a' has no contract transmitter")
+	_, err = newRegistryTransmitter([]RegistryProviders{{Name: "a", ContractTransmitter: a}})
+	assert.ErrorContains(t, err, "registry 'a' has no contract config tracker")
+
+	transmitter, err := newRegistryTransmitter([]RegistryProviders{
+		{Name: "a", ContractTransmitter: a, ContractConfigTracker: &mockContractConfigTracker{digest: digest}},
+		{Name: "b", ContractTransmitter: b, ContractConfigTracker: bTracker},
+	})
+	require.NoError(t, err)
+
+	account, err := transmitter.FromAccount(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, ocr2plustypes.Account("0xabc"), account)
+
+	t.Run("reports are routed by the registry in the report info", func(t *testing.T) {
+		report := ocr3types.ReportWithInfo[AutomationReportInfo]{Report: []byte("b"), Info: AutomationReportInfo{Registry: "b"}}
+		require.NoError(t, transmitter.Transmit(context.Background(), digest, 1, report, nil))
+		assert.Empty(t, a.transmitted)
+		assert.Equal(t, []ocr3types.ReportWithInfo[AutomationReportInfo]{report}, b.transmitted)
+
+		err := transmitter.Transmit(context.Background(), digest, 1, ocr3types.ReportWithInfo[AutomationReportInfo]{Info: AutomationReportInfo{Registry: "c"}}, nil)
+		assert.ErrorContains(t, err, "no transmitter for registry 'c'")
+	})
+
+	t.Run("reports are not sent to registries with a different config digest", func(t *testing.T) {
+		bTracker.digest = ocr2plustypes.ConfigDigest{2}
+		err := transmitter.Transmit(context.Background(), digest, 2, ocr3types.ReportWithInfo[AutomationReportInfo]{Info: AutomationReportInfo{Registry: "b"}}, nil)
+		assert.ErrorContains(t, err, "registry 'b' is configured with config digest")
+		assert.Len(t, b.transmitted, 1)
+	})
+
+	t.Run("all registries transmit from the same account", func(t *testing.T) {
+		b.account = "0xdef"
+		_, err := transmitter.FromAccount(context.Background())
+		assert.ErrorContains(t, err, "registry 'b' transmits from account 0xdef instead of 0xabc")
+	})
+}
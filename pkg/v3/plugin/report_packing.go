@@ -0,0 +1,116 @@
+package plugin
+
+import (
+	"sort"
+
+	ocr2keepers "github.com/smartcontractkit/chainlink-common/pkg/types/automation"
+
+	"github.com/smartcontractkit/chainlink-automation/pkg/v3/config"
+)
+
+// reportBatch is a group of agreed performables that is encoded into a
+// single report
+type reportBatch struct {
+	results   []ocr2keepers.CheckResult
+	gasUsed   uint64
+	upkeepIDs map[ocr2keepers.UpkeepIdentifier]bool
+}
+
+func newReportBatch() *reportBatch {
+	return &reportBatch{
+		results:   []ocr2keepers.CheckResult{},
+		upkeepIDs: make(map[ocr2keepers.UpkeepIdentifier]bool),
+	}
+}
+
+func (b *reportBatch) add(result ocr2keepers.CheckResult, gas uint64) {
+	b.results = append(b.results, result)
+	b.gasUsed += gas
+	b.upkeepIDs[result.UpkeepID] = true
+}
+
+// fits returns true if the result can be added to the batch without exceeding
+// the configured batch size or report gas limit, and without including the
+// same upkeep twice in a report
+func (b *reportBatch) fits(result ocr2keepers.CheckResult, gas uint64, conf config.OffchainConfig) bool {
+	return len(b.results) < conf.MaxUpkeepBatchSize &&
+		b.gasUsed+gas <= uint64(conf.GasLimitPerReport) &&
+		!b.upkeepIDs[result.UpkeepID]
+}
+
+// packReports groups agreed performables into batches according to the
+// configured report packing strategy. Packing only depends on the agreed
+// outcome and config, so every node produces the same reports.
+func packReports(results []ocr2keepers.CheckResult, conf config.OffchainConfig) [][]ocr2keepers.CheckResult {
+	switch conf.ReportPackingStrategy {
+	case config.ReportPackingStrategyFirstFitDecreasing:
+		return packFirstFitDecreasing(results, conf)
+	default:
+		return packSequential(results, conf)
+	}
+}
+
+// packSequential adds results to the current batch in the provided order and
+// starts a new batch as soon as a result does not fit in the current one
+func packSequential(results []ocr2keepers.CheckResult, conf config.OffchainConfig) [][]ocr2keepers.CheckResult {
+	var batches [][]ocr2keepers.CheckResult
+
+	current := newReportBatch()
+	for _, result := range results {
+		gas := result.GasAllocated + uint64(conf.GasOverheadPerUpkeep)
+		if !current.fits(result, gas, conf) {
+			batches = append(batches, current.results)
+			current = newReportBatch()
+		}
+
+		current.add(result, gas)
+	}
+
+	if len(current.results) > 0 {
+		batches = append(batches, current.results)
+	}
+
+	return batches
+}
+
+// packFirstFitDecreasing sorts results by gas in decreasing order, using the
+// workID as a tie breaker, and adds each result to the first batch it fits in.
+// A result that does not fit in any batch starts a new one.
+func packFirstFitDecreasing(results []ocr2keepers.CheckResult, conf config.OffchainConfig) [][]ocr2keepers.CheckResult {
+	sorted := make([]ocr2keepers.CheckResult, len(results))
+	copy(sorted, results)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].GasAllocated != sorted[j].GasAllocated {
+			return sorted[i].GasAllocated > sorted[j].GasAllocated
+		}
+		return sorted[i].WorkID < sorted[j].WorkID
+	})
+
+	var open []*reportBatch
+	for _, result := range sorted {
+		gas := result.GasAllocated + uint64(conf.GasOverheadPerUpkeep)
+
+		var target *reportBatch
+		for _, batch := range open {
+			if batch.fits(result, gas, conf) {
+				target = batch
+				break
+			}
+		}
+
+		if target == nil {
+			target = newReportBatch()
+			open = append(open, target)
+		}
+
+		target.add(result, gas)
+	}
+
+	batches := make([][]ocr2keepers.CheckResult, 0, len(open))
+	for _, batch := range open {
+		batches = append(batches, batch.results)
+	}
+
+	return batches
+}
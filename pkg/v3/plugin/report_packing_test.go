@@ -0,0 +1,107 @@
+package plugin
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	ocr2keepers "github.com/smartcontractkit/chainlink-common/pkg/types/automation"
+
+	"github.com/smartcontractkit/chainlink-automation/pkg/v3/config"
+)
+
+func TestPackReports(t *testing.T) {
+	result := func(upkeepID int64, gas uint64) ocr2keepers.CheckResult {
+		uid := ocr2keepers.UpkeepIdentifier{}
+		uid.FromBigInt(big.NewInt(upkeepID))
+		return ocr2keepers.CheckResult{
+			UpkeepID:     uid,
+			GasAllocated: gas,
+			WorkID:       fmt.Sprintf("workID%d-%d", upkeepID, gas),
+		}
+	}
+
+	gasOf := func(batches [][]ocr2keepers.CheckResult) [][]uint64 {
+		var out [][]uint64
+		for _, batch := range batches {
+			gas := []uint64{}
+			for _, r := range batch {
+				gas = append(gas, r.GasAllocated)
+			}
+			out = append(out, gas)
+		}
+		return out
+	}
+
+	conf := config.OffchainConfig{
+		GasLimitPerReport:    1000,
+		GasOverheadPerUpkeep: 0,
+		MaxUpkeepBatchSize:   3,
+	}
+
+	for _, tc := range []struct {
+		name     string
+		strategy string
+		results  []ocr2keepers.CheckResult
+		expected [][]uint64
+	}{
+		{
+			name:     "no results produce no reports",
+			strategy: config.ReportPackingStrategyFirstFitDecreasing,
+			results:  nil,
+			expected: nil,
+		},
+		{
+			name:     "sequential starts a new report on the first overflow",
+			strategy: config.ReportPackingStrategySequential,
+			results:  []ocr2keepers.CheckResult{result(1, 600), result(2, 500), result(3, 400), result(4, 500)},
+			expected: [][]uint64{{600}, {500, 400}, {500}},
+		},
+		{
+			name:     "first fit decreasing fills earlier reports",
+			strategy: config.ReportPackingStrategyFirstFitDecreasing,
+			results:  []ocr2keepers.CheckResult{result(1, 600), result(2, 500), result(3, 400), result(4, 500)},
+			expected: [][]uint64{{600, 400}, {500, 500}},
+		},
+		{
+			name:     "first fit decreasing respects the batch size",
+			strategy: config.ReportPackingStrategyFirstFitDecreasing,
+			results:  []ocr2keepers.CheckResult{result(1, 10), result(2, 10), result(3, 10), result(4, 10), result(5, 10)},
+			expected: [][]uint64{{10, 10, 10}, {10, 10}},
+		},
+		{
+			name:     "first fit decreasing keeps one upkeep per report",
+			strategy: config.ReportPackingStrategyFirstFitDecreasing,
+			results:  []ocr2keepers.CheckResult{result(1, 100), result(1, 200), result(2, 300)},
+			expected: [][]uint64{{300, 200}, {100}},
+		},
+		{
+			name:     "first fit decreasing places oversized results alone",
+			strategy: config.ReportPackingStrategyFirstFitDecreasing,
+			results:  []ocr2keepers.CheckResult{result(1, 100), result(2, 2000)},
+			expected: [][]uint64{{2000}, {100}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := conf
+			c.ReportPackingStrategy = tc.strategy
+
+			assert.Equal(t, tc.expected, gasOf(packReports(tc.results, c)))
+		})
+	}
+
+	t.Run("first fit decreasing is independent of the input order", func(t *testing.T) {
+		c := conf
+		c.ReportPackingStrategy = config.ReportPackingStrategyFirstFitDecreasing
+
+		results := []ocr2keepers.CheckResult{result(1, 300), result(2, 300), result(3, 700), result(4, 100), result(5, 300)}
+		reversed := make([]ocr2keepers.CheckResult, len(results))
+		for i := range results {
+			reversed[len(results)-1-i] = results[i]
+		}
+
+		assert.Equal(t, packReports(results, c), packReports(reversed, c))
+	})
+}
@@ -0,0 +1,218 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	ocr2plustypes "github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+
+	"github.com/smartcontractkit/chainlink-automation/pkg/v3/stores"
+)
+
+// SnapshotVersion is the version of the snapshots written by this release.
+// Snapshots of older versions remain readable; a change to the snapshot
+// format requires a new version and a case in DecodeSnapshot.
+const SnapshotVersion = 1
+
+// Snapshot is the state of a plugin instance when it was closed. It is
+// restored into the next plugin instance such that in-flight work survives a
+// config change or a restart of the node. The coordinator and queue state is
+// not part of the snapshot, it is persisted by the coordinator and queue
+// stores themselves, which version their file logs like the snapshot.
+type Snapshot struct {
+	Version int `json:"version"`
+	// ConfigDigest is the hex encoded config digest of the plugin instance
+	// that took the snapshot
+	ConfigDigest string    `json:"configDigest"`
+	CreatedAt    time.Time `json:"createdAt"`
+
+	Results           []stores.ResultEntry   `json:"results"`
+	MetadataProposals []stores.MetadataEntry `json:"metadataProposals"`
+}
+
+// EncodeSnapshot encodes the snapshot with the current snapshot version
+func EncodeSnapshot(snapshot Snapshot) ([]byte, error) {
+	snapshot.Version = SnapshotVersion
+	return json.Marshal(snapshot)
+}
+
+// DecodeSnapshot decodes a snapshot of any version written by this or an
+// earlier release
+func DecodeSnapshot(b []byte) (Snapshot, error) {
+	var header struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(b, &header); err != nil {
+		return Snapshot{}, fmt.Errorf("failed to decode snapshot: %w", err)
+	}
+
+	switch header.Version {
+	case 1:
+		var snapshot Snapshot
+		if err := json.Unmarshal(b, &snapshot); err != nil {
+			return Snapshot{}, fmt.Errorf("failed to decode snapshot: %w", err)
+		}
+		return snapshot, nil
+	default:
+		return Snapshot{}, fmt.Errorf("unsupported snapshot version %d", header.Version)
+	}
+}
+
+// SnapshotStore keeps the encoded snapshot of the last closed plugin instance
+type SnapshotStore interface {
+	// SaveSnapshot replaces the stored snapshot
+	SaveSnapshot([]byte) error
+	// LoadSnapshot returns the stored snapshot or nil if there is none
+	LoadSnapshot() ([]byte, error)
+}
+
+type memorySnapshotStore struct {
+	mu       sync.RWMutex
+	snapshot []byte
+}
+
+var _ SnapshotStore = (*memorySnapshotStore)(nil)
+
+// NewMemorySnapshotStore returns a SnapshotStore that keeps the snapshot in
+// memory. The snapshot survives a re-instantiation of the plugin, but not a
+// restart of the process. As a plugin is only re-instantiated in the same
+// process on a config change, its snapshots need to be migrated across config
+// digests to be restored.
+func NewMemorySnapshotStore() *memorySnapshotStore {
+	return &memorySnapshotStore{}
+}
+
+func (s *memorySnapshotStore) SaveSnapshot(b []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.snapshot = b
+	return nil
+}
+
+func (s *memorySnapshotStore) LoadSnapshot() ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.snapshot, nil
+}
+
+// FileSnapshotStore is a SnapshotStore that keeps the snapshot in a file. The
+// file is replaced atomically such that a crash while saving leaves the
+// previous snapshot in place.
+type FileSnapshotStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+var _ SnapshotStore = (*FileSnapshotStore)(nil)
+
+func NewFileSnapshotStore(path string) *FileSnapshotStore {
+	return &FileSnapshotStore{path: path}
+}
+
+func (s *FileSnapshotStore) SaveSnapshot(b []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to save snapshot: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save snapshot: %w", err)
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to save snapshot: %w", err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to save snapshot: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to save snapshot: %w", err)
+	}
+
+	return nil
+}
+
+func (s *FileSnapshotStore) LoadSnapshot() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to load snapshot: %w", err)
+	}
+
+	return b, nil
+}
+
+// loadSnapshot returns the stored snapshot if it was taken for the provided
+// config digest or if snapshots of other config digests are migrated
+func loadSnapshot(store SnapshotStore, digest ocr2plustypes.ConfigDigest, migrate bool, logger *log.Logger) (Snapshot, bool) {
+	if store == nil {
+		return Snapshot{}, false
+	}
+
+	b, err := store.LoadSnapshot()
+	if err != nil {
+		logger.Printf("failed to load plugin snapshot: %s", err)
+		return Snapshot{}, false
+	}
+	if len(b) == 0 {
+		return Snapshot{}, false
+	}
+
+	snapshot, err := DecodeSnapshot(b)
+	if err != nil {
+		logger.Printf("ignoring plugin snapshot: %s", err)
+		return Snapshot{}, false
+	}
+
+	if snapshot.ConfigDigest != digest.Hex() {
+		if !migrate {
+			logger.Printf("ignoring plugin snapshot of config digest %s", snapshot.ConfigDigest)
+			return Snapshot{}, false
+		}
+		logger.Printf("migrating plugin snapshot of config digest %s", snapshot.ConfigDigest)
+	}
+
+	return snapshot, true
+}
+
+// saveSnapshot takes a snapshot of the plugin state and saves it in the store
+func saveSnapshot(
+	store SnapshotStore,
+	digest ocr2plustypes.ConfigDigest,
+	results []stores.ResultEntry,
+	proposals []stores.MetadataEntry,
+) error {
+	snapshot := Snapshot{
+		ConfigDigest:      digest.Hex(),
+		CreatedAt:         time.Now(),
+		Results:           results,
+		MetadataProposals: proposals,
+	}
+
+	b, err := EncodeSnapshot(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+
+	return store.SaveSnapshot(b)
+}
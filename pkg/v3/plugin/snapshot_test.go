@@ -0,0 +1,92 @@
+package plugin
+
+import (
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ocr2plustypes "github.com/smartcontractkit/libocr/offchainreporting2plus/types"
+
+	"github.com/smartcontractkit/chainlink-automation/pkg/v3/runner"
+	"github.com/smartcontractkit/chainlink-automation/pkg/v3/stores"
+	ocr2keepers "github.com/smartcontractkit/chainlink-common/pkg/types/automation"
+)
+
+func TestDecodeSnapshot(t *testing.T) {
+	snapshot := Snapshot{
+		ConfigDigest: ocr2plustypes.ConfigDigest{1}.Hex(),
+		CreatedAt:    time.Now().UTC(),
+		Results: []stores.ResultEntry{
+			{Result: ocr2keepers.CheckResult{WorkID: "workID1", Eligible: true}, AddedAt: time.Now().UTC()},
+		},
+		MetadataProposals: []stores.MetadataEntry{
+			{Proposal: ocr2keepers.CoordinatedBlockProposal{WorkID: "workID2"}, CreatedAt: time.Now().UTC()},
+		},
+	}
+
+	b, err := EncodeSnapshot(snapshot)
+	require.NoError(t, err)
+
+	decoded, err := DecodeSnapshot(b)
+	require.NoError(t, err)
+
+	snapshot.Version = SnapshotVersion
+	assert.Equal(t, snapshot, decoded)
+
+	_, err = DecodeSnapshot([]byte(`{"version":99}`))
+	assert.ErrorContains(t, err, "unsupported snapshot version 99")
+
+	_, err = DecodeSnapshot([]byte(`{"version":`))
+	assert.Error(t, err)
+}
+
+func TestFileSnapshotStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	store := NewFileSnapshotStore(path)
+
+	b, err := store.LoadSnapshot()
+	require.NoError(t, err)
+	assert.Nil(t, b)
+
+	require.NoError(t, store.SaveSnapshot([]byte(`{"version":1}`)))
+	require.NoError(t, store.SaveSnapshot([]byte(`{"version":1,"configDigest":"01"}`)))
+
+	b, err = NewFileSnapshotStore(path).LoadSnapshot()
+	require.NoError(t, err)
+	assert.Equal(t, []byte(`{"version":1,"configDigest":"01"}`), b)
+
+	// no temporary files are left behind
+	files, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, files, 1)
+}
+
+func TestSnapshot_RestoresIntoNextInstance(t *testing.T) {
+	logger := log.New(io.Discard, "", 0)
+	digest := ocr2plustypes.ConfigDigest{1}
+	store := NewMemorySnapshotStore()
+
+	results := []stores.ResultEntry{{Result: ocr2keepers.CheckResult{WorkID: "workID3"}, AddedAt: time.Now()}}
+	require.NoError(t, saveSnapshot(store, digest, results, nil))
+
+	// a snapshot of another config digest is only restored when migrating
+	_, ok := loadSnapshot(store, ocr2plustypes.ConfigDigest{2}, false, logger)
+	assert.False(t, ok)
+	_, ok = loadSnapshot(store, ocr2plustypes.ConfigDigest{2}, true, logger)
+	assert.True(t, ok)
+
+	snapshot, ok := loadSnapshot(store, digest, false, logger)
+	require.True(t, ok)
+	assert.Equal(t, results[0].Result, snapshot.Results[0].Result)
+
+	// the default memory store is only restored into the next plugin instance
+	// of a config change, so its snapshots are migrated
+	factory := newReportingPluginFactory(nil, nil, nil, nil, nil, nil, nil, runner.RunnerConfig{}, nil, nil, nil, nil, logger)
+	assert.True(t, factory.migrateSnapshots)
+}
@@ -12,6 +12,7 @@ const NamespaceAutomation = "automation"
 const (
 	PluginErrorTypeInvalidOracleObservation = "invalid_oracle_observation"
 	PluginErrorTypeDecodeOutcome            = "decode_outcome"
+	PluginErrorTypeDecodeQuery              = "decode_query"
 	PluginErrorTypeEncodeReport             = "encode_report"
 )
 
@@ -23,6 +24,38 @@ const (
 	PluginStepReports     = "reports"
 )
 
+// Staged result states
+const (
+	StagedResultStateSkipped = "skipped"
+	StagedResultStateStarved = "starved"
+)
+
+// Oracle observation issues
+const (
+	OracleIssueDecode            = "decode"
+	OracleIssueValidation        = "validation"
+	OracleIssueNoQuorumOverlap   = "no_quorum_overlap"
+	OracleIssueStaleBlockHistory = "stale_block_history"
+)
+
+// Stuck transmit states
+const (
+	StuckTransmitStateDetected = "detected"
+	StuckTransmitStateReleased = "released"
+)
+
+// Result store eviction reasons
+const (
+	ResultStoreEvictionExpired  = "expired"
+	ResultStoreEvictionCapacity = "capacity"
+)
+
+// Upkeep types
+const (
+	UpkeepTypeConditional = "conditional"
+	UpkeepTypeLogTrigger  = "log_trigger"
+)
+
 // Automation metrics
 var (
 	AutomationPluginPerformables = promauto.NewGaugeVec(prometheus.GaugeOpts{
@@ -40,4 +73,83 @@ var (
 		"step",
 		"error",
 	})
+	AutomationStagedResults = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: NamespaceAutomation,
+		Name:      "staged_results",
+		Help:      "How many staged results were left out of the last observation, and how many of those were checked in an older window of blocks than the newest staged result",
+	}, []string{
+		"state",
+	})
+	AutomationStagedResultMaxSkipCount = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: NamespaceAutomation,
+		Name:      "staged_result_max_skip_count",
+		Help:      "The highest number of consecutive observations any staged result has been left out of",
+	})
+	AutomationOracleObservations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: NamespaceAutomation,
+		Name:      "oracle_observations",
+		Help:      "Count of how many observations were received from an oracle in the outcome step",
+	}, []string{
+		"config_digest",
+		"oracle",
+	})
+	AutomationOracleObservationIssues = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: NamespaceAutomation,
+		Name:      "oracle_observation_issues",
+		Help:      "Count of how many observations received from an oracle had an issue, by issue type",
+	}, []string{
+		"config_digest",
+		"oracle",
+		"issue",
+	})
+	AutomationStuckTransmits = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: NamespaceAutomation,
+		Name:      "stuck_transmits",
+		Help:      "Count of how many accepted reports were pending longer than the expected inclusion window, and how many of those were released",
+	}, []string{
+		"state",
+	})
+	AutomationUncheckedTransmits = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: NamespaceAutomation,
+		Name:      "unchecked_transmits",
+		Help:      "Count of how many transmits can't be checked for reorgs as their block left the block history before its hash was known",
+	})
+	AutomationMetadataStoreProposals = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: NamespaceAutomation,
+		Name:      "metadata_store_proposals",
+		Help:      "How many proposals are kept in the metadata store, by upkeep type",
+	}, []string{
+		"upkeep_type",
+	})
+	AutomationMetadataStoreEvictions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: NamespaceAutomation,
+		Name:      "metadata_store_evictions",
+		Help:      "Count of how many proposals were evicted from the full metadata store, by upkeep type",
+	}, []string{
+		"upkeep_type",
+	})
+	AutomationResultStoreSize = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: NamespaceAutomation,
+		Name:      "result_store_size",
+		Help:      "How many eligible results are kept in the result store",
+	})
+	AutomationResultStoreEvictions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: NamespaceAutomation,
+		Name:      "result_store_evictions",
+		Help:      "Count of how many results were removed from the result store because they expired, or dropped because the store was full",
+	}, []string{
+		"reason",
+	})
+	AutomationResultStoreOldestAge = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: NamespaceAutomation,
+		Name:      "result_store_oldest_age_seconds",
+		Help:      "The age in seconds of the oldest result that was not expired when the result store was last viewed",
+	})
+	AutomationProposalBuildFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: NamespaceAutomation,
+		Name:      "proposal_build_failures",
+		Help:      "Count of how many coordinated proposals failed to build a payload, by upkeep type",
+	}, []string{
+		"upkeep_type",
+	})
 )
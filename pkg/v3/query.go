@@ -0,0 +1,138 @@
+package ocr2keepers
+
+import (
+	"fmt"
+
+	"github.com/smartcontractkit/libocr/commontypes"
+
+	"github.com/smartcontractkit/chainlink-automation/pkg/v3/config"
+	"github.com/smartcontractkit/chainlink-automation/pkg/v3/types"
+	ocr2keepers "github.com/smartcontractkit/chainlink-common/pkg/types/automation"
+)
+
+// NOTE: Any change to these values should keep backwards compatibility in mind
+// as different nodes would upgrade at different times and would need to
+// adhere to each others' limits
+const (
+	// QueryInFlightWorkIDsLimit is the max number of in flight workIDs a
+	// leader can send in a query
+	QueryInFlightWorkIDsLimit = 500
+	// QueryBlockHistoryWindow is the number of blocks older than the latest
+	// block of the leader that followers keep in their observed block history
+	QueryBlockHistoryWindow = 64
+	// MaxQueryLength applies a limit to the total length of bytes in a query.
+	// NOTE: This is derived from the in flight workID limit and the ceilings
+	// on the number of proposals in an observation
+	MaxQueryLength = 100_000
+)
+
+// AutomationQuery holds hints sent by the leader of a round to all followers.
+// Followers use the hints to leave out data from their observations that is
+// either already known to the leader or unlikely to be used in the outcome.
+// Hints are an optimization only; a missing or invalid query results in the
+// same observations as before queries were introduced. Hints are not verified,
+// so followers only leave out a bounded share of their observations because of
+// them.
+// NOTE: Any change to this structure should keep backwards compatibility in mind
+// as different nodes would upgrade at different times and would need to understand
+// each others' queries meanwhile
+type AutomationQuery struct {
+	// Leader is the oracle that built the query
+	Leader commontypes.OracleID
+	// LatestBlock is the latest block known to the leader
+	LatestBlock ocr2keepers.BlockKey
+	// InFlightWorkIDs are workIDs that the leader knows to be pending
+	// transmission. These will not be performed again until the transmission
+	// is confirmed or times out
+	InFlightWorkIDs []string
+	// UpkeepProposals are the proposals the leader includes in its own
+	// observation. As a single node is enough to surface a proposal, there is
+	// no need for followers to propose these as well
+	UpkeepProposals []ocr2keepers.CoordinatedBlockProposal
+}
+
+// InFlight returns the set of workIDs the leader reported as in flight
+func (query AutomationQuery) InFlight() map[string]bool {
+	inFlight := make(map[string]bool, len(query.InFlightWorkIDs))
+	for _, workID := range query.InFlightWorkIDs {
+		inFlight[workID] = true
+	}
+	return inFlight
+}
+
+// Proposed returns the set of workIDs the leader proposes in its observation
+func (query AutomationQuery) Proposed() map[string]bool {
+	proposed := make(map[string]bool, len(query.UpkeepProposals))
+	for _, proposal := range query.UpkeepProposals {
+		proposed[proposal.WorkID] = true
+	}
+	return proposed
+}
+
+// Encode produces a binary encoded array of bytes prefixed with the codec
+// version.
+func (query AutomationQuery) Encode() ([]byte, error) {
+	e := newBinaryEncoder(codecVersionV1)
+	e.writeByte(byte(query.Leader))
+	e.writeBlockKey(query.LatestBlock)
+	e.writeStrings(query.InFlightWorkIDs)
+	e.writeProposals(query.UpkeepProposals)
+	return e.bytes(), nil
+}
+
+// DecodeAutomationQuery decodes an AutomationQuery from an encoded array of
+// bytes. An empty encoding, as sent by leaders of previous releases, is a valid
+// query without hints.
+func DecodeAutomationQuery(data []byte, utg types.UpkeepTypeGetter, wg types.WorkIDGenerator, limits config.ProtocolLimits) (AutomationQuery, error) {
+	aq, err := decodeAutomationQuery(data)
+	if err != nil {
+		return AutomationQuery{}, err
+	}
+	err = validateAutomationQuery(aq, utg, wg, limits)
+	if err != nil {
+		return AutomationQuery{}, err
+	}
+	return aq, nil
+}
+
+func decodeAutomationQuery(data []byte) (AutomationQuery, error) {
+	aq := AutomationQuery{}
+	if len(data) == 0 {
+		return aq, nil
+	}
+
+	d := newBinaryDecoder(data)
+	switch version := d.readByte(); version {
+	case codecVersionV1:
+		aq.Leader = commontypes.OracleID(d.readByte())
+		aq.LatestBlock = d.readBlockKey()
+		aq.InFlightWorkIDs = d.readStrings()
+		aq.UpkeepProposals = d.readProposals()
+		d.finish()
+	default:
+		return AutomationQuery{}, fmt.Errorf("unsupported query encoding version %d", version)
+	}
+	if d.err != nil {
+		return AutomationQuery{}, d.err
+	}
+	return aq, nil
+}
+
+func validateAutomationQuery(q AutomationQuery, utg types.UpkeepTypeGetter, wg types.WorkIDGenerator, limits config.ProtocolLimits) error {
+	if len(q.InFlightWorkIDs) > QueryInFlightWorkIDsLimit {
+		return fmt.Errorf("in flight workIDs length cannot be greater than %d", QueryInFlightWorkIDsLimit)
+	}
+
+	proposalsLimit := limits.ObservationConditionalsProposalsLimit + limits.ObservationLogRecoveryProposalsLimit
+	if len(q.UpkeepProposals) > proposalsLimit {
+		return fmt.Errorf("query upkeep proposals length cannot be greater than %d", proposalsLimit)
+	}
+
+	for _, proposal := range q.UpkeepProposals {
+		if err := validateUpkeepProposal(proposal, utg, wg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
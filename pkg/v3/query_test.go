@@ -0,0 +1,86 @@
+package ocr2keepers
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/smartcontractkit/chainlink-automation/pkg/v3/config"
+	commontypes "github.com/smartcontractkit/chainlink-common/pkg/types/automation"
+)
+
+var validQuery = AutomationQuery{
+	Leader:          3,
+	LatestBlock:     commontypes.BlockKey{Number: 10, Hash: [32]byte{1}},
+	InFlightWorkIDs: []string{"workID1", "workID2"},
+	UpkeepProposals: []commontypes.CoordinatedBlockProposal{validConditionalProposal, validLogProposal},
+}
+
+func TestValidAutomationQuery(t *testing.T) {
+	encoded, err := validQuery.Encode()
+	assert.NoError(t, err, "no error in encoding valid automation query")
+
+	decoded, err := DecodeAutomationQuery(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator, config.DefaultProtocolLimitsV0)
+	assert.NoError(t, err, "no error in decoding valid automation query")
+	assert.Equal(t, validQuery, decoded, "final result from encoding and decoding should match")
+
+	assert.Equal(t, map[string]bool{"workID1": true, "workID2": true}, decoded.InFlight())
+	assert.Equal(t, map[string]bool{validConditionalProposal.WorkID: true, validLogProposal.WorkID: true}, decoded.Proposed())
+}
+
+func TestAutomationQueryDecodeEmpty(t *testing.T) {
+	decoded, err := DecodeAutomationQuery(nil, mockUpkeepTypeGetter, mockWorkIDGenerator, config.DefaultProtocolLimitsV0)
+	assert.NoError(t, err, "an empty query is sent by leaders without hints")
+	assert.Equal(t, AutomationQuery{}, decoded)
+}
+
+func TestAutomationQueryDecodeInvalidEncoding(t *testing.T) {
+	encoded, err := validQuery.Encode()
+	assert.NoError(t, err)
+
+	_, err = DecodeAutomationQuery(encoded[:len(encoded)-10], mockUpkeepTypeGetter, mockWorkIDGenerator, config.DefaultProtocolLimitsV0)
+	assert.Error(t, err, "truncated encoding cannot be decoded")
+
+	unknownVersion := append([]byte{}, encoded...)
+	unknownVersion[0] = 255
+	_, err = DecodeAutomationQuery(unknownVersion, mockUpkeepTypeGetter, mockWorkIDGenerator, config.DefaultProtocolLimitsV0)
+	assert.ErrorContains(t, err, "unsupported query encoding version 255")
+
+	_, err = DecodeAutomationQuery(append(encoded, 0), mockUpkeepTypeGetter, mockWorkIDGenerator, config.DefaultProtocolLimitsV0)
+	assert.ErrorContains(t, err, "unexpected trailing bytes")
+}
+
+func TestAutomationQueryLimits(t *testing.T) {
+	query := AutomationQuery{}
+	for i := 0; i < QueryInFlightWorkIDsLimit+1; i++ {
+		query.InFlightWorkIDs = append(query.InFlightWorkIDs, fmt.Sprintf("workID%d", i))
+	}
+	encoded, err := query.Encode()
+	assert.NoError(t, err)
+	_, err = DecodeAutomationQuery(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator, config.DefaultProtocolLimitsV0)
+	assert.ErrorContains(t, err, "in flight workIDs length cannot be greater than")
+
+	query = AutomationQuery{}
+	limits := config.DefaultProtocolLimitsV0
+	for i := 0; i < limits.ObservationConditionalsProposalsLimit+limits.ObservationLogRecoveryProposalsLimit+1; i++ {
+		query.UpkeepProposals = append(query.UpkeepProposals, validConditionalProposal)
+	}
+	encoded, err = query.Encode()
+	assert.NoError(t, err)
+	_, err = DecodeAutomationQuery(encoded, mockUpkeepTypeGetter, mockWorkIDGenerator, limits)
+	assert.ErrorContains(t, err, "query upkeep proposals length cannot be greater than")
+}
+
+func TestAutomationQueryMaxLength(t *testing.T) {
+	query := AutomationQuery{LatestBlock: commontypes.BlockKey{Number: 10}}
+	for i := 0; i < QueryInFlightWorkIDsLimit; i++ {
+		query.InFlightWorkIDs = append(query.InFlightWorkIDs, fmt.Sprintf("%064d", i))
+	}
+	for i := 0; i < config.MaxProtocolLimits.ObservationConditionalsProposalsLimit+config.MaxProtocolLimits.ObservationLogRecoveryProposalsLimit; i++ {
+		query.UpkeepProposals = append(query.UpkeepProposals, validLogProposal)
+	}
+	encoded, err := query.Encode()
+	assert.NoError(t, err)
+	assert.LessOrEqual(t, len(encoded), MaxQueryLength)
+}
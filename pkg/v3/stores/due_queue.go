@@ -0,0 +1,87 @@
+package stores
+
+import (
+	"container/heap"
+	"time"
+)
+
+// dueItem references a queue record by key. The seq identifies the item such
+// that records can ignore items that were superseded by a later push.
+type dueItem struct {
+	key string
+	due time.Time
+	seq uint64
+}
+
+type dueHeap []dueItem
+
+var _ heap.Interface = (*dueHeap)(nil)
+
+func (h dueHeap) Len() int { return len(h) }
+
+func (h dueHeap) Less(i, j int) bool {
+	if h[i].due.Equal(h[j].due) {
+		return h[i].seq < h[j].seq
+	}
+	return h[i].due.Before(h[j].due)
+}
+
+func (h dueHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *dueHeap) Push(x any) { *h = append(*h, x.(dueItem)) }
+
+func (h *dueHeap) Pop() any {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
+
+// dueQueue is a min-heap of record keys ordered by due time and, for equal due
+// times, by the order in which they were pushed. Items are not updated or
+// removed in place; a record is pushed again whenever its due time changes and
+// outdated items are skipped when they are popped.
+type dueQueue struct {
+	items dueHeap
+	seq   uint64
+}
+
+// push adds an item and returns its seq
+func (q *dueQueue) push(key string, due time.Time) uint64 {
+	q.seq++
+	heap.Push(&q.items, dueItem{key: key, due: due, seq: q.seq})
+	return q.seq
+}
+
+// popDue removes and returns the first item if it is due at the provided time
+func (q *dueQueue) popDue(now time.Time) (dueItem, bool) {
+	if len(q.items) == 0 || q.items[0].due.After(now) {
+		return dueItem{}, false
+	}
+	return heap.Pop(&q.items).(dueItem), true
+}
+
+func (q *dueQueue) len() int {
+	return len(q.items)
+}
+
+// compact drops all items that are no longer current
+func (q *dueQueue) compact(current func(dueItem) bool) {
+	items := q.items[:0]
+	for _, item := range q.items {
+		if current(item) {
+			items = append(items, item)
+		}
+	}
+	// clear the references in the truncated tail
+	for i := len(items); i < len(q.items); i++ {
+		q.items[i] = dueItem{}
+	}
+	q.items = items
+	heap.Init(&q.items)
+}
+
+// shouldCompact returns true if the outdated items outnumber the live records
+func (q *dueQueue) shouldCompact(records int) bool {
+	return q.len() > 2*records+64
+}
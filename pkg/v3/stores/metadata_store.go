@@ -3,11 +3,15 @@ package stores
 import (
 	"context"
 	"fmt"
+	"io"
+	"log"
 	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/smartcontractkit/chainlink-automation/pkg/v3/prommetrics"
+	"github.com/smartcontractkit/chainlink-automation/pkg/v3/telemetry"
 	"github.com/smartcontractkit/chainlink-automation/pkg/v3/types"
 	commontypes "github.com/smartcontractkit/chainlink-common/pkg/types/automation"
 )
@@ -17,6 +21,15 @@ const (
 	conditionalExpiry = 24 * time.Hour
 )
 
+var (
+	// DefaultMaxConditionalProposals is the default number of conditional
+	// proposals kept in the metadata store
+	DefaultMaxConditionalProposals = 10_000
+	// DefaultMaxLogRecoveryProposals is the default number of log recovery
+	// proposals kept in the metadata store
+	DefaultMaxLogRecoveryProposals = 100_000
+)
+
 var (
 	timeFn = time.Now
 )
@@ -30,23 +43,48 @@ func (r expiringRecord) expired(expr time.Duration) bool {
 	return time.Since(r.createdAt) > expr
 }
 
+// MetadataEntry is the exported state of a proposal in the metadata store
+type MetadataEntry struct {
+	Proposal  commontypes.CoordinatedBlockProposal `json:"proposal"`
+	CreatedAt time.Time                            `json:"createdAt"`
+}
+
 type metadataStore struct {
 	chID                 int
 	ch                   chan commontypes.BlockHistory
 	subscriber           commontypes.BlockSubscriber
 	blockHistory         commontypes.BlockHistory
 	blockHistoryMutex    sync.RWMutex
-	conditionalProposals orderedMap
+	conditionalProposals *proposalIndex
 	conditionalMutex     sync.RWMutex
-	logRecoveryProposals orderedMap
+	logRecoveryProposals *proposalIndex
 	logRecoveryMutex     sync.RWMutex
 	running              atomic.Bool
 	stopCh               chan struct{}
 
 	typeGetter types.UpkeepTypeGetter
+	lggr       *log.Logger
 }
 
+// NewMetadataStore creates a metadata store with the default limits that
+// does not log evictions
 func NewMetadataStore(subscriber commontypes.BlockSubscriber, typeGetter types.UpkeepTypeGetter) (*metadataStore, error) {
+	return NewMetadataStoreWithLimits(log.New(io.Discard, "", 0), subscriber, typeGetter, 0, 0)
+}
+
+// NewMetadataStoreWithLimits creates a metadata store that keeps at most the
+// provided number of proposals of every upkeep type and evicts the oldest
+// proposals once full. Zero limits use the defaults, such that proposals are
+// evicted even when no limits are configured. Evictions are logged and counted
+// in the metadata store evictions metric.
+func NewMetadataStoreWithLimits(lggr *log.Logger, subscriber commontypes.BlockSubscriber, typeGetter types.UpkeepTypeGetter, maxConditionalProposals, maxLogRecoveryProposals int) (*metadataStore, error) {
+	if maxConditionalProposals <= 0 {
+		maxConditionalProposals = DefaultMaxConditionalProposals
+	}
+	if maxLogRecoveryProposals <= 0 {
+		maxLogRecoveryProposals = DefaultMaxLogRecoveryProposals
+	}
+
 	chID, ch, err := subscriber.Subscribe()
 	if err != nil {
 		return nil, err
@@ -57,10 +95,11 @@ func NewMetadataStore(subscriber commontypes.BlockSubscriber, typeGetter types.U
 		ch:                   ch,
 		subscriber:           subscriber,
 		blockHistory:         commontypes.BlockHistory{},
-		conditionalProposals: newOrderedMap(),
-		logRecoveryProposals: newOrderedMap(),
+		conditionalProposals: newProposalIndex(maxConditionalProposals),
+		logRecoveryProposals: newProposalIndex(maxLogRecoveryProposals),
 		stopCh:               make(chan struct{}, 1),
 		typeGetter:           typeGetter,
+		lggr:                 log.New(lggr.Writer(), fmt.Sprintf("[%s | metadata-store]", telemetry.ServiceName), telemetry.LogPkgStdFlags),
 	}, nil
 }
 
@@ -111,6 +150,73 @@ func (m *metadataStore) RemoveProposals(proposals ...commontypes.CoordinatedBloc
 	}
 }
 
+// ProposalEntries returns the proposals of all upkeep types that did not
+// expire, along with the time they were added
+func (m *metadataStore) ProposalEntries() []MetadataEntry {
+	var entries []MetadataEntry
+	collect := func(idx *proposalIndex, expr time.Duration) {
+		idx.Range(func(_ string, record expiringRecord) {
+			if !record.expired(expr) {
+				entries = append(entries, MetadataEntry{Proposal: record.proposal, CreatedAt: record.createdAt})
+			}
+		})
+	}
+
+	m.conditionalMutex.RLock()
+	collect(m.conditionalProposals, conditionalExpiry)
+	m.conditionalMutex.RUnlock()
+
+	m.logRecoveryMutex.RLock()
+	collect(m.logRecoveryProposals, logRecoveryExpiry)
+	m.logRecoveryMutex.RUnlock()
+
+	return entries
+}
+
+// RestoreProposals adds exported proposals with the time they were
+// originally added. Expired proposals and proposals of workIDs already in the
+// store are skipped.
+func (m *metadataStore) RestoreProposals(entries ...MetadataEntry) {
+	// the oldest proposals are added first, such that they are evicted first
+	sorted := make([]MetadataEntry, len(entries))
+	copy(sorted, entries)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].CreatedAt.Before(sorted[j].CreatedAt)
+	})
+
+	restore := func(idx *proposalIndex, expr time.Duration, entry MetadataEntry) {
+		record := expiringRecord{createdAt: entry.CreatedAt, proposal: entry.Proposal}
+		if record.expired(expr) {
+			return
+		}
+		if _, ok := idx.Get(entry.Proposal.WorkID); ok {
+			return
+		}
+		idx.Add(entry.Proposal.WorkID, record)
+	}
+
+	for _, entry := range sorted {
+		switch m.typeGetter(entry.Proposal.UpkeepID) {
+		case types.LogTrigger:
+			m.logRecoveryMutex.Lock()
+			restore(m.logRecoveryProposals, logRecoveryExpiry, entry)
+			m.logRecoveryMutex.Unlock()
+		case types.ConditionTrigger:
+			m.conditionalMutex.Lock()
+			restore(m.conditionalProposals, conditionalExpiry, entry)
+			m.conditionalMutex.Unlock()
+		}
+	}
+
+	m.logRecoveryMutex.RLock()
+	prommetrics.AutomationMetadataStoreProposals.WithLabelValues(prommetrics.UpkeepTypeLogTrigger).Set(float64(m.logRecoveryProposals.Len()))
+	m.logRecoveryMutex.RUnlock()
+
+	m.conditionalMutex.RLock()
+	prommetrics.AutomationMetadataStoreProposals.WithLabelValues(prommetrics.UpkeepTypeConditional).Set(float64(m.conditionalProposals.Len()))
+	m.conditionalMutex.RUnlock()
+}
+
 func (m *metadataStore) Start(ctx context.Context) error {
 	if m.running.Load() {
 		return fmt.Errorf("service already running")
@@ -149,12 +255,19 @@ func (m *metadataStore) addLogRecoveryProposal(proposals ...commontypes.Coordina
 	m.logRecoveryMutex.Lock()
 	defer m.logRecoveryMutex.Unlock()
 
+	evicted := 0
 	for _, proposal := range proposals {
-		m.logRecoveryProposals.Add(proposal.WorkID, expiringRecord{
+		evicted += m.logRecoveryProposals.Add(proposal.WorkID, expiringRecord{
 			createdAt: timeFn(),
 			proposal:  proposal,
 		})
 	}
+
+	if evicted > 0 {
+		m.lggr.Printf("evicted %d oldest log recovery proposals as the store is limited to %d proposals", evicted, m.logRecoveryProposals.capacity)
+		prommetrics.AutomationMetadataStoreEvictions.WithLabelValues(prommetrics.UpkeepTypeLogTrigger).Add(float64(evicted))
+	}
+	prommetrics.AutomationMetadataStoreProposals.WithLabelValues(prommetrics.UpkeepTypeLogTrigger).Set(float64(m.logRecoveryProposals.Len()))
 }
 
 func (m *metadataStore) viewLogRecoveryProposal() []commontypes.CoordinatedBlockProposal {
@@ -164,14 +277,19 @@ func (m *metadataStore) viewLogRecoveryProposal() []commontypes.CoordinatedBlock
 
 	res := make([]commontypes.CoordinatedBlockProposal, 0)
 
-	for _, key := range m.logRecoveryProposals.Keys() {
-		record := m.logRecoveryProposals.Get(key)
+	var expired []string
+	m.logRecoveryProposals.Range(func(key string, record expiringRecord) {
 		if record.expired(logRecoveryExpiry) {
-			m.logRecoveryProposals.Delete(key)
+			expired = append(expired, key)
 		} else {
 			res = append(res, record.proposal)
 		}
+	})
+
+	for _, key := range expired {
+		m.logRecoveryProposals.Delete(key)
 	}
+	prommetrics.AutomationMetadataStoreProposals.WithLabelValues(prommetrics.UpkeepTypeLogTrigger).Set(float64(m.logRecoveryProposals.Len()))
 
 	return res
 }
@@ -183,18 +301,26 @@ func (m *metadataStore) removeLogRecoveryProposal(proposals ...commontypes.Coord
 	for _, proposal := range proposals {
 		m.logRecoveryProposals.Delete(proposal.WorkID)
 	}
+	prommetrics.AutomationMetadataStoreProposals.WithLabelValues(prommetrics.UpkeepTypeLogTrigger).Set(float64(m.logRecoveryProposals.Len()))
 }
 
 func (m *metadataStore) addConditionalProposal(proposals ...commontypes.CoordinatedBlockProposal) {
 	m.conditionalMutex.Lock()
 	defer m.conditionalMutex.Unlock()
 
+	evicted := 0
 	for _, proposal := range proposals {
-		m.conditionalProposals.Add(proposal.WorkID, expiringRecord{
+		evicted += m.conditionalProposals.Add(proposal.WorkID, expiringRecord{
 			createdAt: timeFn(),
 			proposal:  proposal,
 		})
 	}
+
+	if evicted > 0 {
+		m.lggr.Printf("evicted %d oldest conditional proposals as the store is limited to %d proposals", evicted, m.conditionalProposals.capacity)
+		prommetrics.AutomationMetadataStoreEvictions.WithLabelValues(prommetrics.UpkeepTypeConditional).Add(float64(evicted))
+	}
+	prommetrics.AutomationMetadataStoreProposals.WithLabelValues(prommetrics.UpkeepTypeConditional).Set(float64(m.conditionalProposals.Len()))
 }
 
 func (m *metadataStore) viewConditionalProposal() []commontypes.CoordinatedBlockProposal {
@@ -204,17 +330,21 @@ func (m *metadataStore) viewConditionalProposal() []commontypes.CoordinatedBlock
 
 	res := make([]commontypes.CoordinatedBlockProposal, 0)
 
-	for _, key := range m.conditionalProposals.Keys() {
-		record := m.conditionalProposals.Get(key)
+	var expired []string
+	m.conditionalProposals.Range(func(key string, record expiringRecord) {
 		if record.expired(conditionalExpiry) {
-			m.conditionalProposals.Delete(key)
+			expired = append(expired, key)
 		} else {
 			res = append(res, record.proposal)
 		}
+	})
+
+	for _, key := range expired {
+		m.conditionalProposals.Delete(key)
 	}
+	prommetrics.AutomationMetadataStoreProposals.WithLabelValues(prommetrics.UpkeepTypeConditional).Set(float64(m.conditionalProposals.Len()))
 
 	return res
-
 }
 
 func (m *metadataStore) removeConditionalProposal(proposals ...commontypes.CoordinatedBlockProposal) {
@@ -224,44 +354,5 @@ func (m *metadataStore) removeConditionalProposal(proposals ...commontypes.Coord
 	for _, proposal := range proposals {
 		m.conditionalProposals.Delete(proposal.WorkID)
 	}
-}
-
-func newOrderedMap() orderedMap {
-	return orderedMap{
-		keys:   []string{},
-		values: map[string]expiringRecord{},
-	}
-}
-
-type orderedMap struct {
-	keys   []string
-	values map[string]expiringRecord
-}
-
-func (m *orderedMap) Add(key string, value expiringRecord) {
-	if _, ok := m.values[key]; ok {
-		m.values[key] = value
-	} else {
-		m.keys = append(m.keys, key)
-		m.values[key] = value
-	}
-}
-
-func (m *orderedMap) Get(key string) expiringRecord {
-	return m.values[key]
-}
-
-func (m *orderedMap) Keys() []string {
-	sort.Strings(m.keys)
-	return m.keys
-}
-
-func (m *orderedMap) Delete(key string) {
-	delete(m.values, key)
-	for i, v := range m.keys {
-		if v == key {
-			m.keys = append(m.keys[:i], m.keys[i+1:]...)
-			break
-		}
-	}
+	prommetrics.AutomationMetadataStoreProposals.WithLabelValues(prommetrics.UpkeepTypeConditional).Set(float64(m.conditionalProposals.Len()))
 }
@@ -1,13 +1,18 @@
 package stores
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"log"
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
+	"github.com/smartcontractkit/chainlink-automation/pkg/v3/prommetrics"
 	"github.com/smartcontractkit/chainlink-automation/pkg/v3/types"
 	commontypes "github.com/smartcontractkit/chainlink-common/pkg/types/automation"
 )
@@ -524,3 +529,66 @@ func (r *mockBlockSubscriber) Start(ctx context.Context) error {
 func (r *mockBlockSubscriber) Close() error {
 	return r.CloseFn()
 }
+
+func TestMetadataStore_EvictsOldestProposals(t *testing.T) {
+	blockSubscriber := &mockBlockSubscriber{
+		SubscribeFn: func() (int, chan commontypes.BlockHistory, error) {
+			return 1, make(chan commontypes.BlockHistory), nil
+		},
+	}
+
+	var logBuf bytes.Buffer
+	store, err := NewMetadataStoreWithLimits(log.New(&logBuf, "", 0), blockSubscriber, nil, 0, 2)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultMaxConditionalProposals, store.conditionalProposals.capacity)
+
+	evictions := prommetrics.AutomationMetadataStoreEvictions.WithLabelValues(prommetrics.UpkeepTypeLogTrigger)
+	initialEvictions := testutil.ToFloat64(evictions)
+
+	store.addLogRecoveryProposal(
+		commontypes.CoordinatedBlockProposal{WorkID: "workID3"},
+		commontypes.CoordinatedBlockProposal{WorkID: "workID1"},
+		commontypes.CoordinatedBlockProposal{WorkID: "workID2"},
+	)
+
+	assert.Equal(t, []commontypes.CoordinatedBlockProposal{{WorkID: "workID1"}, {WorkID: "workID2"}}, store.viewLogRecoveryProposal())
+	assert.Equal(t, float64(1), testutil.ToFloat64(evictions)-initialEvictions)
+	assert.Contains(t, logBuf.String(), "evicted 1 oldest log recovery proposals as the store is limited to 2 proposals")
+	assert.Equal(t, float64(2), testutil.ToFloat64(prommetrics.AutomationMetadataStoreProposals.WithLabelValues(prommetrics.UpkeepTypeLogTrigger)))
+}
+
+func TestMetadataStore_RestoreProposals(t *testing.T) {
+	blockSubscriber := &mockBlockSubscriber{
+		SubscribeFn: func() (int, chan commontypes.BlockHistory, error) {
+			return 1, make(chan commontypes.BlockHistory), nil
+		},
+	}
+	typeGetter := func(uid commontypes.UpkeepIdentifier) types.UpkeepType {
+		return types.UpkeepType(uid[15])
+	}
+
+	conditional := commontypes.CoordinatedBlockProposal{UpkeepID: upkeepId(types.ConditionTrigger, []byte("0x1")), WorkID: "workID1"}
+	logRecovery := commontypes.CoordinatedBlockProposal{UpkeepID: upkeepId(types.LogTrigger, []byte("0x2")), WorkID: "workID2"}
+
+	store, err := NewMetadataStore(blockSubscriber, typeGetter)
+	require.NoError(t, err)
+	store.addConditionalProposal(conditional)
+	store.addLogRecoveryProposal(logRecovery)
+
+	entries := store.ProposalEntries()
+	require.Len(t, entries, 2)
+
+	restored, err := NewMetadataStore(blockSubscriber, typeGetter)
+	require.NoError(t, err)
+	restored.RestoreProposals(append(entries,
+		// expired proposals are skipped
+		MetadataEntry{
+			Proposal:  commontypes.CoordinatedBlockProposal{UpkeepID: upkeepId(types.LogTrigger, []byte("0x3")), WorkID: "workID3"},
+			CreatedAt: time.Now().Add(-2 * logRecoveryExpiry),
+		},
+	)...)
+
+	assert.Equal(t, []commontypes.CoordinatedBlockProposal{conditional}, restored.viewConditionalProposal())
+	assert.Equal(t, []commontypes.CoordinatedBlockProposal{logRecovery}, restored.viewLogRecoveryProposal())
+	assert.ElementsMatch(t, entries, restored.ProposalEntries())
+}
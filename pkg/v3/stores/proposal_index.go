@@ -0,0 +1,173 @@
+package stores
+
+import (
+	"math/rand"
+	"time"
+)
+
+const (
+	// maxIndexLevel bounds the levels of the skip list, which comfortably
+	// indexes millions of proposals
+	maxIndexLevel = 24
+	// indexLevelProbability is the probability of a node to reach the next
+	// level of the skip list
+	indexLevelProbability = 0.25
+)
+
+type indexNode struct {
+	key    string
+	record expiringRecord
+	// next holds the following node in key order on every level of the skip
+	// list
+	next []*indexNode
+	// older and newer link the nodes in the order they were added
+	older, newer *indexNode
+}
+
+// proposalIndex holds expiring records in key order, with O(log n) insert and
+// delete, and in the order they were added, such that the oldest records can
+// be evicted without scanning all records. A capacity of zero does not limit
+// the number of records.
+type proposalIndex struct {
+	capacity int
+	nodes    map[string]*indexNode
+	head     *indexNode
+	level    int
+	oldest   *indexNode
+	newest   *indexNode
+	rnd      *rand.Rand
+}
+
+func newProposalIndex(capacity int) *proposalIndex {
+	return &proposalIndex{
+		capacity: capacity,
+		nodes:    map[string]*indexNode{},
+		head:     &indexNode{next: make([]*indexNode, maxIndexLevel)},
+		level:    1,
+		rnd:      rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Add adds or replaces the record of a key. A replaced record counts as the
+// newest record. If the index is full, the oldest records are evicted and
+// their number is returned.
+func (idx *proposalIndex) Add(key string, record expiringRecord) int {
+	if node, ok := idx.nodes[key]; ok {
+		node.record = record
+		idx.unlinkAge(node)
+		idx.linkAge(node)
+		return 0
+	}
+
+	evicted := 0
+	for idx.capacity > 0 && len(idx.nodes) >= idx.capacity {
+		idx.Delete(idx.oldest.key)
+		evicted++
+	}
+
+	var update [maxIndexLevel]*indexNode
+	x := idx.head
+	for i := idx.level - 1; i >= 0; i-- {
+		for x.next[i] != nil && x.next[i].key < key {
+			x = x.next[i]
+		}
+		update[i] = x
+	}
+
+	level := idx.randomLevel()
+	if level > idx.level {
+		for i := idx.level; i < level; i++ {
+			update[i] = idx.head
+		}
+		idx.level = level
+	}
+
+	node := &indexNode{key: key, record: record, next: make([]*indexNode, level)}
+	for i := 0; i < level; i++ {
+		node.next[i] = update[i].next[i]
+		update[i].next[i] = node
+	}
+
+	idx.nodes[key] = node
+	idx.linkAge(node)
+
+	return evicted
+}
+
+func (idx *proposalIndex) Get(key string) (expiringRecord, bool) {
+	node, ok := idx.nodes[key]
+	if !ok {
+		return expiringRecord{}, false
+	}
+	return node.record, true
+}
+
+// Delete removes the record of a key, if any
+func (idx *proposalIndex) Delete(key string) {
+	node, ok := idx.nodes[key]
+	if !ok {
+		return
+	}
+
+	x := idx.head
+	for i := idx.level - 1; i >= 0; i-- {
+		for x.next[i] != nil && x.next[i].key < key {
+			x = x.next[i]
+		}
+		if x.next[i] == node {
+			x.next[i] = node.next[i]
+		}
+	}
+
+	for idx.level > 1 && idx.head.next[idx.level-1] == nil {
+		idx.level--
+	}
+
+	idx.unlinkAge(node)
+	delete(idx.nodes, key)
+}
+
+// Range calls fn for every record in key order. The index must not be
+// modified by fn.
+func (idx *proposalIndex) Range(fn func(key string, record expiringRecord)) {
+	for x := idx.head.next[0]; x != nil; x = x.next[0] {
+		fn(x.key, x.record)
+	}
+}
+
+func (idx *proposalIndex) Len() int {
+	return len(idx.nodes)
+}
+
+func (idx *proposalIndex) randomLevel() int {
+	level := 1
+	for level < maxIndexLevel && idx.rnd.Float64() < indexLevelProbability {
+		level++
+	}
+	return level
+}
+
+func (idx *proposalIndex) linkAge(node *indexNode) {
+	node.older = idx.newest
+	node.newer = nil
+	if idx.newest != nil {
+		idx.newest.newer = node
+	} else {
+		idx.oldest = node
+	}
+	idx.newest = node
+}
+
+func (idx *proposalIndex) unlinkAge(node *indexNode) {
+	if node.older != nil {
+		node.older.newer = node.newer
+	} else {
+		idx.oldest = node.newer
+	}
+	if node.newer != nil {
+		node.newer.older = node.older
+	} else {
+		idx.newest = node.older
+	}
+	node.older, node.newer = nil, nil
+}
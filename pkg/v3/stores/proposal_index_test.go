@@ -0,0 +1,85 @@
+package stores
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProposalIndex(t *testing.T) {
+	idx := newProposalIndex(0)
+	rnd := rand.New(rand.NewSource(1))
+
+	expected := map[string]bool{}
+	for i := 0; i < 2000; i++ {
+		key := fmt.Sprintf("workID%d", rnd.Intn(500))
+		if rnd.Intn(3) == 0 {
+			idx.Delete(key)
+			delete(expected, key)
+		} else {
+			assert.Equal(t, 0, idx.Add(key, expiringRecord{createdAt: time.Now()}))
+			expected[key] = true
+		}
+	}
+
+	var keys []string
+	for key := range expected {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	// records are iterated in key order
+	var ranged []string
+	idx.Range(func(key string, _ expiringRecord) {
+		ranged = append(ranged, key)
+	})
+	assert.Equal(t, keys, ranged)
+	assert.Equal(t, len(keys), idx.Len())
+}
+
+func TestProposalIndex_EvictsOldest(t *testing.T) {
+	idx := newProposalIndex(3)
+
+	for _, key := range []string{"c", "a", "b"} {
+		require.Equal(t, 0, idx.Add(key, expiringRecord{}))
+	}
+
+	// replacing a record makes it the newest record
+	require.Equal(t, 0, idx.Add("c", expiringRecord{}))
+	require.Equal(t, 1, idx.Add("d", expiringRecord{}))
+
+	_, ok := idx.Get("a")
+	assert.False(t, ok)
+
+	require.Equal(t, 1, idx.Add("e", expiringRecord{}))
+	_, ok = idx.Get("b")
+	assert.False(t, ok)
+
+	var ranged []string
+	idx.Range(func(key string, _ expiringRecord) {
+		ranged = append(ranged, key)
+	})
+	assert.Equal(t, []string{"c", "d", "e"}, ranged)
+}
+
+func BenchmarkProposalIndex(b *testing.B) {
+	keys := make([]string, 100_000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("workID%d", rand.Int())
+	}
+
+	for i := 0; i < b.N; i++ {
+		idx := newProposalIndex(len(keys) / 2)
+		for _, key := range keys {
+			idx.Add(key, expiringRecord{})
+		}
+		for _, key := range keys {
+			idx.Delete(key)
+		}
+	}
+}
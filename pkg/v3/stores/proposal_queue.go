@@ -1,9 +1,13 @@
 package stores
 
 import (
+	"fmt"
+	"io"
+	"log"
 	"sync"
 	"time"
 
+	"github.com/smartcontractkit/chainlink-automation/pkg/v3/telemetry"
 	"github.com/smartcontractkit/chainlink-automation/pkg/v3/types"
 
 	ocr2keepers "github.com/smartcontractkit/chainlink-common/pkg/types/automation"
@@ -15,6 +19,12 @@ type proposalQueueRecord struct {
 	removed bool
 	// createdAt is the first time the proposal was seen by the queue
 	createdAt time.Time
+	// upkeepType is the type of the proposal upkeep
+	upkeepType types.UpkeepType
+	// seq identifies the current item of the record in the due queue
+	seq uint64
+	// requeues is the number of times the record was requeued
+	requeues int
 }
 
 // Default expiry for a proposal in the queue
@@ -24,6 +34,10 @@ type proposalQueueRecord struct {
 // be processed on a new block
 const proposalExpiry = 20 * time.Second
 
+// MaxProposalRequeues is the number of times a dequeued proposal can be
+// returned to the queue, e.g. because its payload failed to build
+var MaxProposalRequeues = 3
+
 func (r proposalQueueRecord) expired(now time.Time, expr time.Duration) bool {
 	return now.Sub(r.createdAt) > expr
 }
@@ -31,16 +45,98 @@ func (r proposalQueueRecord) expired(now time.Time, expr time.Duration) bool {
 type proposalQueue struct {
 	lock    sync.RWMutex
 	records map[string]proposalQueueRecord
+	// due orders the records of every upkeep type by the time they were
+	// enqueued or, once they were dequeued, by the time they expire
+	due map[types.UpkeepType]*dueQueue
 
 	typeGetter types.UpkeepTypeGetter
+	store      QueueStore
+	lggr       *log.Logger
 }
 
 var _ types.ProposalQueue = &proposalQueue{}
 
 func NewProposalQueue(typeGetter types.UpkeepTypeGetter) *proposalQueue {
-	return &proposalQueue{
+	return NewProposalQueueWithStore(typeGetter, nil, log.New(io.Discard, "", 0))
+}
+
+// NewProposalQueueWithStore creates a proposal queue that persists its
+// proposals in the provided store. Proposals that were persisted by a previous
+// queue and did not expire yet are restored, including proposals that were
+// already dequeued but might not have been processed. A nil store keeps the
+// queue in memory only.
+func NewProposalQueueWithStore(typeGetter types.UpkeepTypeGetter, store QueueStore, lggr *log.Logger) *proposalQueue {
+	pq := &proposalQueue{
 		records:    map[string]proposalQueueRecord{},
+		due:        map[types.UpkeepType]*dueQueue{},
 		typeGetter: typeGetter,
+		store:      store,
+		lggr:       log.New(lggr.Writer(), fmt.Sprintf("[%s | proposal-queue]", telemetry.ServiceName), telemetry.LogPkgStdFlags),
+	}
+
+	pq.restore()
+
+	return pq
+}
+
+// restore loads the records from the store
+func (pq *proposalQueue) restore() {
+	if pq.store == nil {
+		return
+	}
+
+	entries, err := pq.store.Proposals()
+	if err != nil {
+		pq.lggr.Printf("failed to restore proposal queue: %s", err)
+		return
+	}
+
+	pq.lock.Lock()
+	defer pq.lock.Unlock()
+
+	now := time.Now()
+	for _, entry := range entries {
+		record := proposalQueueRecord{
+			proposal:   entry.Proposal,
+			createdAt:  entry.CreatedAt,
+			upkeepType: pq.typeGetter(entry.Proposal.UpkeepID),
+		}
+		if record.expired(now, proposalExpiry) {
+			pq.forget(entry.Proposal.WorkID)
+			continue
+		}
+		record.seq = pq.dueQueue(record.upkeepType).push(entry.Proposal.WorkID, record.createdAt)
+		pq.records[entry.Proposal.WorkID] = record
+	}
+
+	if len(pq.records) > 0 {
+		pq.lggr.Printf("restored %d proposals", len(pq.records))
+	}
+}
+
+// persist writes the record to the store. Must be called with the lock held.
+func (pq *proposalQueue) persist(record proposalQueueRecord) {
+	if pq.store == nil {
+		return
+	}
+
+	if err := pq.store.SetProposal(ProposalEntry{
+		Proposal:  record.proposal,
+		CreatedAt: record.createdAt,
+		Expires:   record.createdAt.Add(proposalExpiry),
+	}); err != nil {
+		pq.lggr.Printf("failed to persist proposal of workID %s: %s", record.proposal.WorkID, err)
+	}
+}
+
+// forget removes the record from the store. Must be called with the lock held.
+func (pq *proposalQueue) forget(workID string) {
+	if pq.store == nil {
+		return
+	}
+
+	if err := pq.store.DeleteProposal(workID); err != nil {
+		pq.lggr.Printf("failed to remove proposal of workID %s: %s", workID, err)
 	}
 }
 
@@ -55,45 +151,84 @@ func (pq *proposalQueue) Enqueue(newProposals ...ocr2keepers.CoordinatedBlockPro
 				continue
 			}
 		}
-		pq.records[p.WorkID] = proposalQueueRecord{
-			proposal:  p,
-			createdAt: time.Now(),
+		record := proposalQueueRecord{
+			proposal:   p,
+			createdAt:  time.Now(),
+			upkeepType: pq.typeGetter(p.UpkeepID),
 		}
+		record.seq = pq.dueQueue(record.upkeepType).push(p.WorkID, record.createdAt)
+		pq.records[p.WorkID] = record
+		pq.persist(record)
 	}
 
+	pq.compact()
+
 	return nil
 }
 
+// Dequeue returns up to n proposals of the upkeep type in the order in which
+// they were enqueued. Expired proposals are removed from the queue.
 func (pq *proposalQueue) Dequeue(t types.UpkeepType, n int) ([]ocr2keepers.CoordinatedBlockProposal, error) {
 	pq.lock.Lock()
 	defer pq.lock.Unlock()
 
+	now := time.Now()
+	due := pq.dueQueue(t)
+
 	var proposals []ocr2keepers.CoordinatedBlockProposal
-	for _, record := range pq.records {
-		if record.expired(time.Now(), proposalExpiry) {
-			delete(pq.records, record.proposal.WorkID)
-			continue
+	for len(proposals) < n {
+		item, ok := due.popDue(now)
+		if !ok {
+			break
 		}
-		if record.removed {
+		record, ok := pq.records[item.key]
+		if !ok || record.seq != item.seq {
+			// the record was removed or replaced after this item
 			continue
 		}
-		if pq.typeGetter(record.proposal.UpkeepID) == t {
-			proposals = append(proposals, record.proposal)
+		if record.removed || record.expired(now, proposalExpiry) {
+			delete(pq.records, item.key)
+			pq.forget(item.key)
+			continue
 		}
+		proposals = append(proposals, record.proposal)
+		// mark result as removed, it is kept until it expires such that the
+		// same proposal is not enqueued again
+		record.removed = true
+		record.seq = due.push(item.key, record.createdAt.Add(proposalExpiry))
+		pq.records[item.key] = record
 	}
-	if len(proposals) < n {
-		n = len(proposals)
-	}
-	// limit the number of proposals returned
-	proposals = proposals[:n]
-	// mark results as removed
+
+	return proposals, nil
+}
+
+// Requeue returns dequeued proposals to the back of the queue. Proposals that
+// were replaced, expired or used up their requeues are skipped.
+func (pq *proposalQueue) Requeue(proposals ...ocr2keepers.CoordinatedBlockProposal) error {
+	pq.lock.Lock()
+	defer pq.lock.Unlock()
+
+	now := time.Now()
+
 	for _, p := range proposals {
-		proposal := pq.records[p.WorkID]
-		proposal.removed = true
-		pq.records[p.WorkID] = proposal
+		record, ok := pq.records[p.WorkID]
+		if !ok || !record.removed || record.proposal.Trigger.BlockNumber != p.Trigger.BlockNumber {
+			continue
+		}
+		if record.expired(now, proposalExpiry) {
+			continue
+		}
+		if record.requeues >= MaxProposalRequeues {
+			pq.lggr.Printf("dropping proposal of workID %s after %d requeues", p.WorkID, record.requeues)
+			continue
+		}
+		record.removed = false
+		record.requeues++
+		record.seq = pq.dueQueue(record.upkeepType).push(p.WorkID, now)
+		pq.records[p.WorkID] = record
 	}
 
-	return proposals, nil
+	return nil
 }
 
 func (pq *proposalQueue) Size() int {
@@ -112,3 +247,28 @@ func (pq *proposalQueue) Size() int {
 
 	return size
 }
+
+// dueQueue returns the due queue of the upkeep type. Must be called with the
+// lock held.
+func (pq *proposalQueue) dueQueue(t types.UpkeepType) *dueQueue {
+	due, ok := pq.due[t]
+	if !ok {
+		due = &dueQueue{}
+		pq.due[t] = due
+	}
+	return due
+}
+
+// compact drops the outdated items of the due queues once they outnumber the
+// records. Must be called with the lock held.
+func (pq *proposalQueue) compact() {
+	for t, due := range pq.due {
+		if !due.shouldCompact(len(pq.records)) {
+			continue
+		}
+		due.compact(func(item dueItem) bool {
+			record, ok := pq.records[item.key]
+			return ok && record.upkeepType == t && record.seq == item.seq
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package stores
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/smartcontractkit/chainlink-automation/pkg/v3/types"
@@ -155,6 +156,119 @@ func TestProposalQueue_Dequeue(t *testing.T) {
 	}
 }
 
+func TestProposalQueue_DequeueOrder(t *testing.T) {
+	q := NewProposalQueue(func(uid ocr2keepers.UpkeepIdentifier) types.UpkeepType {
+		return types.UpkeepType(uid[15])
+	})
+
+	proposal := func(workID string, block ocr2keepers.BlockNumber) ocr2keepers.CoordinatedBlockProposal {
+		return ocr2keepers.CoordinatedBlockProposal{
+			UpkeepID: upkeepId(types.LogTrigger, []byte(workID)),
+			WorkID:   workID,
+			Trigger:  ocr2keepers.Trigger{BlockNumber: block},
+		}
+	}
+
+	require.NoError(t, q.Enqueue(proposal("0x3", 1), proposal("0x1", 1)))
+	require.NoError(t, q.Enqueue(proposal("0x2", 1)))
+	// a newer proposal is queued behind the existing ones
+	require.NoError(t, q.Enqueue(proposal("0x3", 2)))
+
+	proposals, err := q.Dequeue(types.LogTrigger, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []ocr2keepers.CoordinatedBlockProposal{proposal("0x1", 1), proposal("0x2", 1)}, proposals)
+
+	proposals, err = q.Dequeue(types.LogTrigger, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []ocr2keepers.CoordinatedBlockProposal{proposal("0x3", 2)}, proposals)
+
+	// dequeued proposals are not enqueued again until they expire
+	require.NoError(t, q.Enqueue(proposal("0x1", 1)))
+	assert.Equal(t, 0, q.Size())
+
+	// outdated items do not pile up in the due queue
+	for i := 0; i < 1000; i++ {
+		require.NoError(t, q.Enqueue(proposal("0x4", ocr2keepers.BlockNumber(i+1))))
+	}
+	assert.LessOrEqual(t, q.dueQueue(types.LogTrigger).len(), 2*len(q.records)+64)
+}
+
+func TestProposalQueue_Requeue(t *testing.T) {
+	q := NewProposalQueue(func(uid ocr2keepers.UpkeepIdentifier) types.UpkeepType {
+		return types.UpkeepType(uid[15])
+	})
+
+	proposal := func(workID string, block ocr2keepers.BlockNumber) ocr2keepers.CoordinatedBlockProposal {
+		return ocr2keepers.CoordinatedBlockProposal{
+			UpkeepID: upkeepId(types.LogTrigger, []byte(workID)),
+			WorkID:   workID,
+			Trigger:  ocr2keepers.Trigger{BlockNumber: block},
+		}
+	}
+
+	require.NoError(t, q.Enqueue(proposal("0x1", 1), proposal("0x2", 1)))
+
+	// proposals that were not dequeued are not requeued
+	require.NoError(t, q.Requeue(proposal("0x1", 1), proposal("0x3", 1)))
+	assert.Equal(t, 2, q.Size())
+
+	proposals, err := q.Dequeue(types.LogTrigger, 2)
+	require.NoError(t, err)
+	require.Len(t, proposals, 2)
+
+	// a proposal for a different block is not requeued
+	require.NoError(t, q.Requeue(proposal("0x1", 2)))
+	assert.Equal(t, 0, q.Size())
+
+	for i := 0; i < MaxProposalRequeues; i++ {
+		require.NoError(t, q.Requeue(proposal("0x1", 1)))
+		proposals, err = q.Dequeue(types.LogTrigger, 2)
+		require.NoError(t, err)
+		assert.Equal(t, []ocr2keepers.CoordinatedBlockProposal{proposal("0x1", 1)}, proposals)
+	}
+
+	// the retry budget is used up
+	require.NoError(t, q.Requeue(proposal("0x1", 1)))
+	assert.Equal(t, 0, q.Size())
+}
+
+func BenchmarkProposalQueue_Dequeue(b *testing.B) {
+	typeGetter := func(uid ocr2keepers.UpkeepIdentifier) types.UpkeepType {
+		return types.UpkeepType(uid[15])
+	}
+
+	proposals := make([]ocr2keepers.CoordinatedBlockProposal, 10_000)
+	for i := range proposals {
+		utype := types.LogTrigger
+		if i%2 == 0 {
+			utype = types.ConditionTrigger
+		}
+		proposals[i] = ocr2keepers.CoordinatedBlockProposal{
+			UpkeepID: upkeepId(utype, []byte(fmt.Sprint(i))),
+			WorkID:   fmt.Sprint(i),
+		}
+	}
+
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		q := NewProposalQueue(typeGetter)
+		if err := q.Enqueue(proposals...); err != nil {
+			b.Fatal(err)
+		}
+		b.StartTimer()
+
+		for {
+			dequeued, err := q.Dequeue(types.LogTrigger, 100)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if len(dequeued) == 0 {
+				break
+			}
+		}
+	}
+}
+
 func upkeepId(utype types.UpkeepType, rand []byte) ocr2keepers.UpkeepIdentifier {
 	id := [32]byte{}
 	id[15] = byte(utype)
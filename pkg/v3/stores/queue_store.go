@@ -0,0 +1,269 @@
+package stores
+
+import (
+	"sync"
+	"time"
+
+	ocr2keepers "github.com/smartcontractkit/chainlink-common/pkg/types/automation"
+
+	"github.com/smartcontractkit/chainlink-automation/pkg/util"
+)
+
+// RetryEntry is the persisted state of a payload in the retry queue
+type RetryEntry struct {
+	Payload   ocr2keepers.UpkeepPayload `json:"payload"`
+	Interval  time.Duration             `json:"interval"`
+	Attempts  int                       `json:"attempts"`
+	CreatedAt time.Time                 `json:"createdAt"`
+	UpdatedAt time.Time                 `json:"updatedAt"`
+	// Expires is the time after which the entry is no longer relevant
+	Expires time.Time `json:"expires"`
+}
+
+// ProposalEntry is the persisted state of a proposal in the proposal queue
+type ProposalEntry struct {
+	Proposal  ocr2keepers.CoordinatedBlockProposal `json:"proposal"`
+	CreatedAt time.Time                            `json:"createdAt"`
+	// Expires is the time after which the entry is no longer relevant
+	Expires time.Time `json:"expires"`
+}
+
+// QueueStore persists the retry and proposal queues such that pending retries
+// and proposals survive a restart. Entries are keyed by workID. A store can be
+// shared by consecutive queues, i.e. across plugin instances, but not by
+// queues running at the same time.
+type QueueStore interface {
+	// SetRetry adds or replaces the retry entry of a workID
+	SetRetry(RetryEntry) error
+	// DeleteRetry removes the retry entry of a workID
+	DeleteRetry(workID string) error
+	// Retries returns all retry entries that are not expired
+	Retries() ([]RetryEntry, error)
+	// SetProposal adds or replaces the proposal entry of a workID
+	SetProposal(ProposalEntry) error
+	// DeleteProposal removes the proposal entry of a workID
+	DeleteProposal(workID string) error
+	// Proposals returns all proposal entries that are not expired
+	Proposals() ([]ProposalEntry, error)
+}
+
+func entryExpired(expires, now time.Time) bool {
+	return !expires.IsZero() && now.After(expires)
+}
+
+type memoryQueueStore struct {
+	mu        sync.RWMutex
+	retries   map[string]RetryEntry
+	proposals map[string]ProposalEntry
+}
+
+var _ QueueStore = (*memoryQueueStore)(nil)
+
+// NewMemoryQueueStore returns a QueueStore that keeps the queues in memory.
+// The queues survive a re-instantiation of the plugin, but not a restart of
+// the process.
+func NewMemoryQueueStore() *memoryQueueStore {
+	return &memoryQueueStore{
+		retries:   make(map[string]RetryEntry),
+		proposals: make(map[string]ProposalEntry),
+	}
+}
+
+func (s *memoryQueueStore) SetRetry(e RetryEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.retries[e.Payload.WorkID] = e
+	return nil
+}
+
+func (s *memoryQueueStore) DeleteRetry(workID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.retries, workID)
+	return nil
+}
+
+func (s *memoryQueueStore) Retries() ([]RetryEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	entries := make([]RetryEntry, 0, len(s.retries))
+	for _, e := range s.retries {
+		if !entryExpired(e.Expires, now) {
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
+
+func (s *memoryQueueStore) SetProposal(e ProposalEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.proposals[e.Proposal.WorkID] = e
+	return nil
+}
+
+func (s *memoryQueueStore) DeleteProposal(workID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.proposals, workID)
+	return nil
+}
+
+func (s *memoryQueueStore) Proposals() ([]ProposalEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	entries := make([]ProposalEntry, 0, len(s.proposals))
+	for _, e := range s.proposals {
+		if !entryExpired(e.Expires, now) {
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
+
+func (s *memoryQueueStore) clearExpired(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for workID, e := range s.retries {
+		if entryExpired(e.Expires, now) {
+			delete(s.retries, workID)
+		}
+	}
+	for workID, e := range s.proposals {
+		if entryExpired(e.Expires, now) {
+			delete(s.proposals, workID)
+		}
+	}
+}
+
+func (s *memoryQueueStore) size() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.retries) + len(s.proposals)
+}
+
+// FileQueueStoreVersion is the version of the log written by a
+// FileQueueStore. A change to the format of the log entries requires a new
+// version.
+const FileQueueStoreVersion = 1
+
+// queueStoreEntry is a single line in the append only log of a file queue
+// store
+type queueStoreEntry struct {
+	Retry           *RetryEntry    `json:"retry,omitempty"`
+	DeletedRetry    string         `json:"deletedRetry,omitempty"`
+	Proposal        *ProposalEntry `json:"proposal,omitempty"`
+	DeletedProposal string         `json:"deletedProposal,omitempty"`
+}
+
+// FileQueueStore is a QueueStore that persists the queues in an append only
+// log of JSON lines. The log is compacted when the store is opened and when
+// outdated lines outnumber the live entries.
+type FileQueueStore struct {
+	log    *util.JSONLog[queueStoreEntry]
+	memory *memoryQueueStore
+}
+
+var _ QueueStore = (*FileQueueStore)(nil)
+
+// NewFileQueueStore opens or creates the store at the provided path and loads
+// the persisted queues. Lines that cannot be decoded, e.g. a partially written
+// last line after a crash, are dropped. A log of another version is an error.
+func NewFileQueueStore(path string) (*FileQueueStore, error) {
+	s := &FileQueueStore{
+		memory: NewMemoryQueueStore(),
+	}
+	s.log = util.NewJSONLog[queueStoreEntry](path, "queue store", FileQueueStoreVersion, s.entries, s.memory.size)
+
+	if err := s.log.Load(s.apply); err != nil {
+		return nil, err
+	}
+
+	if err := s.log.Compact(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// apply applies a line of the log to the loaded state
+func (s *FileQueueStore) apply(entry queueStoreEntry) {
+	if entry.Retry != nil {
+		_ = s.memory.SetRetry(*entry.Retry)
+	}
+	if entry.DeletedRetry != "" {
+		_ = s.memory.DeleteRetry(entry.DeletedRetry)
+	}
+	if entry.Proposal != nil {
+		_ = s.memory.SetProposal(*entry.Proposal)
+	}
+	if entry.DeletedProposal != "" {
+		_ = s.memory.DeleteProposal(entry.DeletedProposal)
+	}
+}
+
+// entries returns the lines that describe the queues without expired entries
+func (s *FileQueueStore) entries() []queueStoreEntry {
+	s.memory.clearExpired(time.Now())
+
+	retries, _ := s.memory.Retries()
+	proposals, _ := s.memory.Proposals()
+
+	entries := make([]queueStoreEntry, 0, len(retries)+len(proposals))
+	for i := range retries {
+		entries = append(entries, queueStoreEntry{Retry: &retries[i]})
+	}
+	for i := range proposals {
+		entries = append(entries, queueStoreEntry{Proposal: &proposals[i]})
+	}
+
+	return entries
+}
+
+func (s *FileQueueStore) SetRetry(e RetryEntry) error {
+	return s.log.Write(queueStoreEntry{Retry: &e}, func() error {
+		return s.memory.SetRetry(e)
+	})
+}
+
+func (s *FileQueueStore) DeleteRetry(workID string) error {
+	return s.log.Write(queueStoreEntry{DeletedRetry: workID}, func() error {
+		return s.memory.DeleteRetry(workID)
+	})
+}
+
+func (s *FileQueueStore) Retries() ([]RetryEntry, error) {
+	return s.memory.Retries()
+}
+
+func (s *FileQueueStore) SetProposal(e ProposalEntry) error {
+	return s.log.Write(queueStoreEntry{Proposal: &e}, func() error {
+		return s.memory.SetProposal(e)
+	})
+}
+
+func (s *FileQueueStore) DeleteProposal(workID string) error {
+	return s.log.Write(queueStoreEntry{DeletedProposal: workID}, func() error {
+		return s.memory.DeleteProposal(workID)
+	})
+}
+
+func (s *FileQueueStore) Proposals() ([]ProposalEntry, error) {
+	return s.memory.Proposals()
+}
+
+// Close closes the underlying file. The store cannot be written after it is
+// closed.
+func (s *FileQueueStore) Close() error {
+	return s.log.Close()
+}
@@ -0,0 +1,169 @@
+package stores
+
+import (
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	ocr2keepers "github.com/smartcontractkit/chainlink-common/pkg/types/automation"
+
+	"github.com/smartcontractkit/chainlink-automation/pkg/util"
+	"github.com/smartcontractkit/chainlink-automation/pkg/v3/types"
+)
+
+func TestFileQueueStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queues.jsonl")
+	now := time.Now().UTC()
+	expires := now.Add(time.Hour)
+
+	retry := RetryEntry{
+		Payload:   ocr2keepers.UpkeepPayload{WorkID: "retry1", CheckData: []byte{0x1}, Trigger: ocr2keepers.Trigger{BlockNumber: 10, LogTriggerExtension: &ocr2keepers.LogTriggerExtension{Index: 1}}},
+		Interval:  time.Second,
+		Attempts:  2,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Expires:   expires,
+	}
+	proposal := ProposalEntry{
+		Proposal:  ocr2keepers.CoordinatedBlockProposal{WorkID: "proposal1", Trigger: ocr2keepers.Trigger{BlockNumber: 10}},
+		CreatedAt: now,
+		Expires:   expires,
+	}
+
+	s, err := NewFileQueueStore(path)
+	require.NoError(t, err)
+
+	require.NoError(t, s.SetRetry(retry))
+	require.NoError(t, s.SetRetry(RetryEntry{Payload: ocr2keepers.UpkeepPayload{WorkID: "retry2"}, Expires: expires}))
+	require.NoError(t, s.DeleteRetry("retry2"))
+	require.NoError(t, s.SetRetry(RetryEntry{Payload: ocr2keepers.UpkeepPayload{WorkID: "expired"}, Expires: now.Add(-time.Second)}))
+	require.NoError(t, s.SetProposal(proposal))
+	require.NoError(t, s.SetProposal(ProposalEntry{Proposal: ocr2keepers.CoordinatedBlockProposal{WorkID: "proposal2"}, Expires: expires}))
+	require.NoError(t, s.DeleteProposal("proposal2"))
+	require.NoError(t, s.Close())
+
+	// simulate a crash in the middle of writing a line
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"retry":{"payload":`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	s, err = NewFileQueueStore(path)
+	require.NoError(t, err)
+	defer s.Close()
+
+	retries, err := s.Retries()
+	require.NoError(t, err)
+	assert.Equal(t, []RetryEntry{retry}, retries)
+
+	proposals, err := s.Proposals()
+	require.NoError(t, err)
+	assert.Equal(t, []ProposalEntry{proposal}, proposals)
+
+	// the log is compacted when opened, the first line holds the version
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 3, countLines(b))
+
+	// the log is compacted once outdated lines outnumber the entries
+	for i := 0; i < 2*util.MinCompactionLines; i++ {
+		require.NoError(t, s.SetRetry(retry))
+	}
+	b, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Less(t, countLines(b), util.MinCompactionLines+10)
+}
+
+func TestRetryQueue_RestoresFromStore(t *testing.T) {
+	revert := overrideDefaults(time.Minute, time.Millisecond)
+	defer revert()
+
+	logger := log.New(io.Discard, "", 0)
+	store := NewMemoryQueueStore()
+
+	q := NewRetryQueueWithStore(logger, 0, 0, nil, store)
+	require.NoError(t, q.Enqueue(
+		newRetryRecord(ocr2keepers.UpkeepPayload{WorkID: "1"}, 0),
+		newRetryRecord(ocr2keepers.UpkeepPayload{WorkID: "2"}, time.Hour),
+	))
+	require.Eventually(t, func() bool {
+		items, err := q.Dequeue(1)
+		return err == nil && len(items) == 1
+	}, time.Second, time.Millisecond)
+
+	// an expired entry is dropped on restore
+	require.NoError(t, store.SetRetry(RetryEntry{
+		Payload:   ocr2keepers.UpkeepPayload{WorkID: "3"},
+		CreatedAt: time.Now().Add(-2 * time.Minute),
+		UpdatedAt: time.Now().Add(-2 * time.Minute),
+	}))
+
+	restored := NewRetryQueueWithStore(logger, 0, 0, nil, store)
+	// the pending payload is retried again and keeps its attempts
+	assert.Equal(t, 2, restored.Size())
+	assert.Equal(t, 1, restored.records["1"].attempts)
+
+	items, err := restored.Dequeue(10)
+	require.NoError(t, err)
+	assert.Equal(t, []ocr2keepers.UpkeepPayload{{WorkID: "1"}}, items)
+
+	retries, err := store.Retries()
+	require.NoError(t, err)
+	assert.Len(t, retries, 2)
+}
+
+func TestProposalQueue_RestoresFromStore(t *testing.T) {
+	logger := log.New(io.Discard, "", 0)
+	typeGetter := func(uid ocr2keepers.UpkeepIdentifier) types.UpkeepType {
+		return types.UpkeepType(uid[15])
+	}
+	store := NewMemoryQueueStore()
+
+	proposal := func(workID string) ocr2keepers.CoordinatedBlockProposal {
+		return ocr2keepers.CoordinatedBlockProposal{
+			UpkeepID: upkeepId(types.LogTrigger, []byte(workID)),
+			WorkID:   workID,
+		}
+	}
+
+	q := NewProposalQueueWithStore(typeGetter, store, logger)
+	require.NoError(t, q.Enqueue(proposal("0x1"), proposal("0x2")))
+	proposals, err := q.Dequeue(types.LogTrigger, 1)
+	require.NoError(t, err)
+	require.Equal(t, []ocr2keepers.CoordinatedBlockProposal{proposal("0x1")}, proposals)
+
+	// an expired entry is dropped on restore
+	require.NoError(t, store.SetProposal(ProposalEntry{
+		Proposal:  proposal("0x3"),
+		CreatedAt: time.Now().Add(-2 * proposalExpiry),
+	}))
+
+	restored := NewProposalQueueWithStore(typeGetter, store, logger)
+	assert.Equal(t, 2, restored.Size())
+
+	// a dequeued proposal might not have been processed and is dequeued again
+	proposals, err = restored.Dequeue(types.LogTrigger, 10)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []ocr2keepers.CoordinatedBlockProposal{proposal("0x1"), proposal("0x2")}, proposals)
+
+	entries, err := store.Proposals()
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+}
+
+func countLines(b []byte) int {
+	var n int
+	for _, c := range b {
+		if c == '\n' {
+			n++
+		}
+	}
+	return n
+}
@@ -4,9 +4,11 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"sort"
 	"sync"
 	"time"
 
+	"github.com/smartcontractkit/chainlink-automation/pkg/v3/prommetrics"
 	"github.com/smartcontractkit/chainlink-automation/pkg/v3/types"
 
 	ocr2keepers "github.com/smartcontractkit/chainlink-common/pkg/types/automation"
@@ -19,6 +21,16 @@ var (
 	gcInterval = 30 * time.Second
 )
 
+// DefaultResultStoreCapacity is the default number of results kept in the
+// result store
+var DefaultResultStoreCapacity = 10_000
+
+// ResultEntry is the exported state of a result in the result store
+type ResultEntry struct {
+	Result  ocr2keepers.CheckResult `json:"result"`
+	AddedAt time.Time               `json:"addedAt"`
+}
+
 // result is an internal representation of a check result, with added time for TTL.
 type result struct {
 	data    ocr2keepers.CheckResult
@@ -32,18 +44,39 @@ type resultStore struct {
 	close    chan bool
 	closedCh chan struct{}
 
-	data map[string]result
-	lock sync.RWMutex
+	data     map[string]result
+	capacity int
+	lock     sync.RWMutex
+
+	// snapshot holds the results ordered by the time they were added, such
+	// that views share the snapshot until the store changes. addedAt holds
+	// the time every result in the snapshot was added.
+	snapshot []ocr2keepers.CheckResult
+	addedAt  []time.Time
+	stale    bool
 }
 
 var _ types.ResultStore = (*resultStore)(nil)
 
 func New(lggr *log.Logger) *resultStore {
+	return NewWithCapacity(lggr, 0)
+}
+
+// NewWithCapacity creates a result store that keeps at most capacity results.
+// Once full, new results are dropped such that the results that were eligible
+// the longest are kept until they are performed or expire. A capacity of zero
+// uses the default.
+func NewWithCapacity(lggr *log.Logger, capacity int) *resultStore {
+	if capacity <= 0 {
+		capacity = DefaultResultStoreCapacity
+	}
+
 	return &resultStore{
 		lggr:     log.New(lggr.Writer(), fmt.Sprintf("[%s | result-store]", telemetry.ServiceName), telemetry.LogPkgStdFlags),
 		close:    make(chan bool, 1),
 		closedCh: make(chan struct{}, 1),
 		data:     make(map[string]result),
+		capacity: capacity,
 		lock:     sync.RWMutex{},
 	}
 }
@@ -83,17 +116,39 @@ func (s *resultStore) Add(results ...ocr2keepers.CheckResult) {
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
+	dropped, changed, purged := 0, false, false
 	for _, r := range results {
 		v, ok := s.data[r.WorkID]
+		if !ok && len(s.data) >= s.capacity && !purged {
+			// expired results make room before new results are dropped
+			s.removeExpired()
+			purged = true
+		}
+		if !ok && len(s.data) >= s.capacity {
+			dropped++
+			continue
+		}
 		if !ok {
+			changed = true
 			s.data[r.WorkID] = result{data: r, addedAt: time.Now()}
 			s.lggr.Printf("Result added for upkeep id '%s' and trigger '%+v'", r.UpkeepID.String(), r.Trigger)
 		} else if v.data.Trigger.BlockNumber < r.Trigger.BlockNumber {
 			// result is newer -> replace existing data
+			changed = true
 			s.data[r.WorkID] = result{data: r, addedAt: time.Now()}
 			s.lggr.Printf("Result updated for upkeep id '%s' to higher check block from (%d) to trigger '%+v'", r.UpkeepID.String(), v.data.Trigger.BlockNumber, r.Trigger)
 		}
 	}
+
+	if dropped > 0 {
+		s.lggr.Printf("Result store is full, dropped %d results", dropped)
+		prommetrics.AutomationResultStoreEvictions.WithLabelValues(prommetrics.ResultStoreEvictionCapacity).Add(float64(dropped))
+	}
+
+	if changed {
+		s.stale = true
+	}
+	prommetrics.AutomationResultStoreSize.Set(float64(len(s.data)))
 }
 
 // Remove removes element/s from the store.
@@ -102,34 +157,136 @@ func (s *resultStore) Remove(ids ...string) {
 	defer s.lock.Unlock()
 
 	for _, id := range ids {
-		s.remove(id)
+		if s.remove(id) {
+			s.stale = true
+		}
 
 		s.lggr.Printf("Result removed from result store for key '%s'", id)
 	}
+
+	prommetrics.AutomationResultStoreSize.Set(float64(len(s.data)))
+}
+
+// Entries returns all results in the store that did not expire, along with
+// the time they were added
+func (s *resultStore) Entries() []ResultEntry {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	entries := make([]ResultEntry, 0, len(s.data))
+	for _, r := range s.data {
+		if time.Since(r.addedAt) > storeTTL {
+			continue
+		}
+		entries = append(entries, ResultEntry{Result: r.data, AddedAt: r.addedAt})
+	}
+	return entries
+}
+
+// Restore adds exported results with the time they were originally added.
+// Expired results, results of workIDs already in the store and results that
+// exceed the capacity are skipped.
+func (s *resultStore) Restore(entries ...ResultEntry) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	restored := 0
+	for _, e := range entries {
+		if time.Since(e.AddedAt) > storeTTL || len(s.data) >= s.capacity {
+			continue
+		}
+		if _, ok := s.data[e.Result.WorkID]; ok {
+			continue
+		}
+		s.data[e.Result.WorkID] = result{data: e.Result, addedAt: e.AddedAt}
+		restored++
+	}
+
+	if restored > 0 {
+		s.stale = true
+		s.lggr.Printf("Restored %d results", restored)
+	}
+	prommetrics.AutomationResultStoreSize.Set(float64(len(s.data)))
 }
 
-// View returns a copy of the data in the store.
+// View returns a copy of the results in the store that did not expire,
+// ordered by the time they were added
 func (s *resultStore) View() ([]ocr2keepers.CheckResult, error) {
+	results := s.viewResults()
+
+	view := make([]ocr2keepers.CheckResult, len(results))
+	copy(view, results)
+
+	return view, nil
+}
+
+// ViewShared returns the results in the store like View, without copying
+// them. The returned slice is shared by views until the store changes and must
+// not be modified.
+func (s *resultStore) ViewShared() ([]ocr2keepers.CheckResult, error) {
 	return s.viewResults(), nil
 }
 
 func (s *resultStore) viewResults() []ocr2keepers.CheckResult {
+	s.lock.RLock()
+	stale := s.stale
+	s.lock.RUnlock()
+
+	if stale {
+		s.lock.Lock()
+		s.refreshSnapshot()
+		s.lock.Unlock()
+	}
+
 	s.lock.RLock()
 	defer s.lock.RUnlock()
 
-	var results []ocr2keepers.CheckResult
+	// expired results are at the start of the snapshot; they are not removed
+	// here as it requires to acquire a write lock, which slows down the View
+	// method
+	now := time.Now()
+	first := sort.Search(len(s.addedAt), func(i int) bool {
+		return now.Sub(s.addedAt[i]) <= storeTTL
+	})
+	results := s.snapshot[first:len(s.snapshot):len(s.snapshot)]
+
+	if len(results) > 0 {
+		prommetrics.AutomationResultStoreOldestAge.Set(now.Sub(s.addedAt[first]).Seconds())
+	} else {
+		prommetrics.AutomationResultStoreOldestAge.Set(0)
+	}
+
+	s.lggr.Printf("Viewed %d results", len(results))
+	return results
+}
+
+// refreshSnapshot rebuilds the snapshot if the store changed since it was
+// built.
+// NOTE: not thread safe, must be called with lock held
+func (s *resultStore) refreshSnapshot() {
+	if !s.stale {
+		return
+	}
 
+	ordered := make([]result, 0, len(s.data))
 	for _, r := range s.data {
-		if time.Since(r.addedAt) > storeTTL {
-			// expired, we don't want to remove the element here
-			// as it requires to acquire a write lock, which slows down the View method
-			continue
+		ordered = append(ordered, r)
+	}
+	sort.Slice(ordered, func(i, j int) bool {
+		if ordered[i].addedAt.Equal(ordered[j].addedAt) {
+			return ordered[i].data.WorkID < ordered[j].data.WorkID
 		}
+		return ordered[i].addedAt.Before(ordered[j].addedAt)
+	})
 
-		results = append(results, r.data)
+	// a new snapshot is allocated as the previous one might still be in use
+	s.snapshot = make([]ocr2keepers.CheckResult, len(ordered))
+	s.addedAt = make([]time.Time, len(ordered))
+	for i, r := range ordered {
+		s.snapshot[i] = r.data
+		s.addedAt[i] = r.addedAt
 	}
-	s.lggr.Printf("Viewed %d results", len(results))
-	return results
+	s.stale = false
 }
 
 func (s *resultStore) gc() {
@@ -138,21 +295,36 @@ func (s *resultStore) gc() {
 
 	s.lggr.Println("Garbage collecting result store")
 
+	s.removeExpired()
+}
+
+// removeExpired removes all expired results.
+// NOTE: not thread safe, must be called with lock held
+func (s *resultStore) removeExpired() {
+	removed := 0
 	for k, v := range s.data {
 		if time.Since(v.addedAt) > storeTTL {
 			delete(s.data, k)
+			removed++
 
 			s.lggr.Printf("Value evicted from result store for upkeep id '%s' and work id '%s'", v.data.UpkeepID.String(), v.data.WorkID)
 		}
 	}
+
+	if removed > 0 {
+		s.stale = true
+		prommetrics.AutomationResultStoreEvictions.WithLabelValues(prommetrics.ResultStoreEvictionExpired).Add(float64(removed))
+	}
+	prommetrics.AutomationResultStoreSize.Set(float64(len(s.data)))
 }
 
-// remove removes an element from the store.
+// remove removes an element from the store and returns true if it existed.
 // NOTE: not thread safe, must be called with lock held
-func (s *resultStore) remove(id string) {
+func (s *resultStore) remove(id string) bool {
 	_, ok := s.data[id]
 	if !ok {
-		return
+		return false
 	}
 	delete(s.data, id)
+	return true
 }
@@ -2,6 +2,7 @@ package stores
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"log"
 	"os"
@@ -10,9 +11,13 @@ import (
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	ocr2keepers "github.com/smartcontractkit/chainlink-common/pkg/types/automation"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smartcontractkit/chainlink-automation/pkg/v3/prommetrics"
 )
 
 var (
@@ -285,6 +290,108 @@ func TestResultStore_View(t *testing.T) {
 	})
 }
 
+func TestResultStore_Capacity(t *testing.T) {
+	lggr := log.New(io.Discard, "", 0)
+	store := NewWithCapacity(lggr, 2)
+
+	dropped := prommetrics.AutomationResultStoreEvictions.WithLabelValues(prommetrics.ResultStoreEvictionCapacity)
+	expired := prommetrics.AutomationResultStoreEvictions.WithLabelValues(prommetrics.ResultStoreEvictionExpired)
+	initialDropped, initialExpired := testutil.ToFloat64(dropped), testutil.ToFloat64(expired)
+
+	store.Add(result1, result2)
+	// the oldest results are kept when the store is full
+	store.Add(result3)
+	assert.Len(t, store.data, 2)
+	assert.Contains(t, store.data, "workID1")
+	assert.Contains(t, store.data, "workID2")
+	assert.Equal(t, float64(1), testutil.ToFloat64(dropped)-initialDropped)
+
+	// a newer result of a stored workID replaces the stored result
+	newer := result1
+	newer.Trigger.BlockNumber = 10
+	store.Add(newer)
+	assert.Equal(t, ocr2keepers.BlockNumber(10), store.data["workID1"].data.Trigger.BlockNumber)
+
+	// expired results make room for new results
+	store.lock.Lock()
+	el := store.data["workID2"]
+	el.addedAt = time.Now().Add(-2 * storeTTL)
+	store.data["workID2"] = el
+	store.lock.Unlock()
+
+	store.Add(result3)
+	assert.Len(t, store.data, 2)
+	assert.Contains(t, store.data, "workID3")
+	assert.Equal(t, float64(1), testutil.ToFloat64(expired)-initialExpired)
+	assert.Equal(t, float64(2), testutil.ToFloat64(prommetrics.AutomationResultStoreSize))
+}
+
+func TestResultStore_ViewShared(t *testing.T) {
+	lggr := log.New(io.Discard, "", 0)
+	store := New(lggr)
+
+	store.Add(result1, result2)
+	store.lock.Lock()
+	el := store.data["workID2"]
+	el.addedAt = time.Now().Add(-time.Minute)
+	store.data["workID2"] = el
+	store.lock.Unlock()
+
+	// results are ordered by the time they were added
+	v1, err := store.ViewShared()
+	assert.NoError(t, err)
+	assert.Equal(t, []ocr2keepers.CheckResult{result2, result1}, v1)
+
+	// shared views share the snapshot until the store changes
+	v2, err := store.ViewShared()
+	assert.NoError(t, err)
+	assert.Same(t, &v1[0], &v2[0])
+
+	store.Add(result1)
+	v3, err := store.ViewShared()
+	assert.NoError(t, err)
+	assert.Same(t, &v1[0], &v3[0])
+
+	// views are copies that callers can modify
+	copied, err := store.View()
+	assert.NoError(t, err)
+	assert.Equal(t, v1, copied)
+	assert.NotSame(t, &v1[0], &copied[0])
+	copied[0] = result1
+	assert.Equal(t, []ocr2keepers.CheckResult{result2, result1}, v1)
+
+	store.Remove("workID2")
+	v4, err := store.ViewShared()
+	assert.NoError(t, err)
+	assert.Equal(t, []ocr2keepers.CheckResult{result1}, v4)
+	assert.Equal(t, []ocr2keepers.CheckResult{result2, result1}, v1)
+}
+
+func BenchmarkResultStore_View(b *testing.B) {
+	store := New(log.New(io.Discard, "", 0))
+	for i := 0; i < DefaultResultStoreCapacity; i++ {
+		result := result1
+		result.WorkID = fmt.Sprint(i)
+		store.Add(result)
+	}
+
+	b.Run("copy", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := store.View(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("shared", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := store.ViewShared(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
 //
 //func mockItems(i, count int) []ocr2keepers.CheckResult {
 //	items := make([]ocr2keepers.CheckResult, count)
@@ -305,3 +412,34 @@ func TestResultStore_View(t *testing.T) {
 //
 //	return items
 //}
+
+func TestResultStore_Restore(t *testing.T) {
+	lggr := log.New(io.Discard, "", 0)
+	store := NewWithCapacity(lggr, 2)
+	store.Add(result1)
+
+	entries := store.Entries()
+	require.Len(t, entries, 1)
+	assert.Equal(t, result1, entries[0].Result)
+
+	restored := NewWithCapacity(lggr, 2)
+	restored.Add(result2)
+	restored.Restore(append(entries,
+		// expired results are skipped
+		ResultEntry{Result: result3, AddedAt: time.Now().Add(-2 * storeTTL)},
+		// a stored workID keeps its result
+		ResultEntry{Result: result2, AddedAt: time.Now().Add(-time.Minute)},
+	)...)
+
+	assert.Len(t, restored.data, 2)
+	assert.Equal(t, entries[0].AddedAt, restored.data["workID1"].addedAt)
+
+	// restored results are viewed in the order they were originally added
+	view, err := restored.View()
+	require.NoError(t, err)
+	assert.Equal(t, []ocr2keepers.CheckResult{result1, result2}, view)
+
+	// results over the capacity are skipped
+	restored.Restore(ResultEntry{Result: result3, AddedAt: time.Now()})
+	assert.Len(t, restored.data, 2)
+}
@@ -1,8 +1,10 @@
 package stores
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"math/rand"
 	"sync"
 	"time"
 
@@ -16,6 +18,9 @@ var (
 	DefaultExpiration = 24 * time.Hour
 	// RetryInterval is the default time between retries
 	RetryInterval = 30 * time.Second
+	// RetryJitter is the fraction by which a backed off retry interval is
+	// randomly shortened or extended, such that failing payloads are spread out
+	RetryJitter = 0.1
 )
 
 type retryQueueRecord struct {
@@ -25,14 +30,14 @@ type retryQueueRecord struct {
 	interval time.Duration
 	// pending is true if the item is currently being retried
 	pending bool
+	// attempts is the number of times the item was dequeued to be retried
+	attempts int
 	// createdAt is the first time the item was seen by the queue
 	createdAt time.Time
 	// updatedAt is the last time the item was added to the queue
 	updatedAt time.Time
-}
-
-func (r retryQueueRecord) elapsed(now time.Time, expr time.Duration) bool {
-	return now.Sub(r.updatedAt) > expr
+	// seq identifies the current item of the record in the due queue
+	seq uint64
 }
 
 func (r retryQueueRecord) expired(now time.Time, expr time.Duration) bool {
@@ -42,28 +47,129 @@ func (r retryQueueRecord) expired(now time.Time, expr time.Duration) bool {
 type retryQueue struct {
 	lggr *log.Logger
 
-	records    map[string]retryQueueRecord
-	lock       sync.RWMutex
-	expiration time.Duration
-	interval   time.Duration
+	records map[string]retryQueueRecord
+	// due orders the records by the time they can be retried or, while they
+	// are pending, by the time they expire
+	due         dueQueue
+	lock        sync.RWMutex
+	expiration  time.Duration
+	interval    time.Duration
+	maxInterval time.Duration
+	maxAttempts int
+	deadLetter  types.DeadLetterHandler
+	store       QueueStore
 }
 
 var _ types.RetryQueue = (*retryQueue)(nil)
 
+// NewRetryQueue creates a retry queue that retries payloads at the retry
+// interval until they expire
 func NewRetryQueue(lggr *log.Logger) *retryQueue {
-	return &retryQueue{
-		lggr:       log.New(lggr.Writer(), fmt.Sprintf("[%s | retry-queue]", telemetry.ServiceName), telemetry.LogPkgStdFlags),
-		records:    map[string]retryQueueRecord{},
-		lock:       sync.RWMutex{},
-		expiration: DefaultExpiration,
-		interval:   RetryInterval,
+	return NewRetryQueueWithDeadLetter(lggr, 0, nil)
+}
+
+// NewRetryQueueWithDeadLetter creates a retry queue that stops retrying a
+// payload after maxAttempts retries and passes it to the dead letter handler.
+// A maxAttempts of zero retries payloads until they expire.
+func NewRetryQueueWithDeadLetter(lggr *log.Logger, maxAttempts int, deadLetter types.DeadLetterHandler) *retryQueue {
+	return NewRetryQueueWithStore(lggr, maxAttempts, 0, deadLetter, nil)
+}
+
+// NewRetryQueueWithStore creates a retry queue like NewRetryQueueWithDeadLetter
+// that persists its payloads in the provided store. Payloads that were
+// persisted by a previous queue and did not expire yet are restored. Payloads
+// that were pending when the previous queue stopped are retried again. A nil
+// store keeps the queue in memory only. A positive maxInterval backs off
+// retries exponentially up to maxInterval; zero retries at a fixed interval.
+func NewRetryQueueWithStore(lggr *log.Logger, maxAttempts int, maxInterval time.Duration, deadLetter types.DeadLetterHandler, store QueueStore) *retryQueue {
+	q := &retryQueue{
+		lggr:        log.New(lggr.Writer(), fmt.Sprintf("[%s | retry-queue]", telemetry.ServiceName), telemetry.LogPkgStdFlags),
+		records:     map[string]retryQueueRecord{},
+		lock:        sync.RWMutex{},
+		expiration:  DefaultExpiration,
+		interval:    RetryInterval,
+		maxInterval: maxInterval,
+		maxAttempts: maxAttempts,
+		deadLetter:  deadLetter,
+		store:       store,
 	}
+
+	q.restore()
+
+	return q
 }
 
-func (q *retryQueue) Enqueue(records ...types.RetryRecord) error {
+// restore loads the records from the store
+func (q *retryQueue) restore() {
+	if q.store == nil {
+		return
+	}
+
+	entries, err := q.store.Retries()
+	if err != nil {
+		q.lggr.Printf("failed to restore retry queue: %s", err)
+		return
+	}
+
 	q.lock.Lock()
 	defer q.lock.Unlock()
 
+	now := time.Now()
+	for _, entry := range entries {
+		record := retryQueueRecord{
+			payload:   entry.Payload,
+			interval:  entry.Interval,
+			attempts:  entry.Attempts,
+			createdAt: entry.CreatedAt,
+			updatedAt: entry.UpdatedAt,
+		}
+		if record.expired(now, q.expiration) {
+			q.forget(entry.Payload.WorkID)
+			continue
+		}
+		record.seq = q.due.push(entry.Payload.WorkID, record.updatedAt.Add(record.interval))
+		q.records[entry.Payload.WorkID] = record
+	}
+
+	if len(q.records) > 0 {
+		q.lggr.Printf("restored %d payloads", len(q.records))
+	}
+}
+
+// persist writes the record to the store. Must be called with the lock held.
+func (q *retryQueue) persist(record retryQueueRecord) {
+	if q.store == nil {
+		return
+	}
+
+	if err := q.store.SetRetry(RetryEntry{
+		Payload:   record.payload,
+		Interval:  record.interval,
+		Attempts:  record.attempts,
+		CreatedAt: record.createdAt,
+		UpdatedAt: record.updatedAt,
+		Expires:   record.createdAt.Add(q.expiration),
+	}); err != nil {
+		q.lggr.Printf("failed to persist retry of workID %s: %s", record.payload.WorkID, err)
+	}
+}
+
+// forget removes the record from the store. Must be called with the lock held.
+func (q *retryQueue) forget(workID string) {
+	if q.store == nil {
+		return
+	}
+
+	if err := q.store.DeleteRetry(workID); err != nil {
+		q.lggr.Printf("failed to remove retry of workID %s: %s", workID, err)
+	}
+}
+
+func (q *retryQueue) Enqueue(records ...types.RetryRecord) error {
+	var exhausted []retryQueueRecord
+
+	q.lock.Lock()
+
 	now := time.Now()
 
 	for _, rec := range records {
@@ -80,26 +186,73 @@ func (q *retryQueue) Enqueue(records ...types.RetryRecord) error {
 			q.lggr.Printf("updating payload for workID %s on block %d", payload.WorkID, payload.Trigger.BlockNumber)
 			record.payload = payload
 		}
-		// Enqueue the item with updatedAt = now. It will be dequeue-able after RetryInterval
+		if q.maxAttempts > 0 && record.attempts >= q.maxAttempts {
+			// the last allowed retry failed as well
+			q.lggr.Printf("workID %s failed after %d retry attempts", payload.WorkID, record.attempts)
+			delete(q.records, payload.WorkID)
+			q.forget(payload.WorkID)
+			exhausted = append(exhausted, record)
+			continue
+		}
+		// Enqueue the item with updatedAt = now. It will be dequeue-able after the retry interval
 		// If item was already pending, it will be eligible to get retried again
 		// (can happen when the same payload gets retryable error again)
 		record.updatedAt = now
 		record.pending = false
-		// if some custom interval is set for this record, use it.
-		// otherwise use the default interval
+		// if some custom interval is set for this record, use it as the base
+		// of the backoff. otherwise use the default interval
+		base := q.interval
 		if rec.Interval > 0 {
-			record.interval = rec.Interval
-		} else {
-			record.interval = q.interval
+			base = rec.Interval
 		}
+		record.interval = q.backoff(base, record.attempts)
+		record.seq = q.due.push(payload.WorkID, record.updatedAt.Add(record.interval))
 		q.records[payload.WorkID] = record
+		q.persist(record)
+	}
+
+	q.compact()
+
+	q.lock.Unlock()
+
+	// the handler is called without holding the lock such that it can take
+	// its time or enqueue again
+	if q.deadLetter != nil {
+		for _, record := range exhausted {
+			q.deadLetter(record.payload, record.attempts)
+		}
 	}
 
 	return nil
 }
 
+// backoff doubles the base interval for every attempt, up to the max interval,
+// and applies a random jitter. Without a max interval the base interval is
+// used for every attempt.
+func (q *retryQueue) backoff(base time.Duration, attempts int) time.Duration {
+	if q.maxInterval <= 0 {
+		return base
+	}
+
+	interval := base
+	for i := 0; i < attempts && interval < q.maxInterval; i++ {
+		interval *= 2
+	}
+	if interval > q.maxInterval {
+		interval = q.maxInterval
+	}
+
+	if attempts == 0 || RetryJitter <= 0 {
+		return interval
+	}
+
+	jitter := (rand.Float64()*2 - 1) * RetryJitter
+	return interval + time.Duration(float64(interval)*jitter)
+}
+
 // Dequeue returns the next n items in the queue, considering retry time schedules
-// Returns only non-pending items that are within their retry interval.
+// Returns only non-pending items that are within their retry interval, in the
+// order in which they became due.
 //
 // NOTE: Items that are expired are removed from the queue.
 func (q *retryQueue) Dequeue(n int) ([]commontypes.UpkeepPayload, error) {
@@ -109,23 +262,31 @@ func (q *retryQueue) Dequeue(n int) ([]commontypes.UpkeepPayload, error) {
 	now := time.Now()
 
 	var results []commontypes.UpkeepPayload
-	for k, record := range q.records {
-		if record.expired(now, q.expiration) {
-			q.lggr.Printf("removing expired record %s", k)
-			delete(q.records, k)
-			continue
+	for len(results) < n {
+		item, ok := q.due.popDue(now)
+		if !ok {
+			break
 		}
-		if record.pending {
+		record, ok := q.records[item.key]
+		if !ok || record.seq != item.seq {
+			// the record was removed or enqueued again after this item
 			continue
 		}
-		if record.elapsed(now, record.interval) {
-			results = append(results, record.payload)
-			record.pending = true
-			q.records[k] = record
-			if len(results) >= n {
-				break
-			}
+		// pending records are only due once they expire
+		if record.pending || record.expired(now, q.expiration) {
+			q.lggr.Printf("removing expired record %s", item.key)
+			delete(q.records, item.key)
+			q.forget(item.key)
+			continue
 		}
+		results = append(results, record.payload)
+		record.pending = true
+		record.attempts++
+		// a pending record is removed once it expires unless it is enqueued
+		// again before that
+		record.seq = q.due.push(item.key, record.createdAt.Add(q.expiration))
+		q.records[item.key] = record
+		q.persist(record)
 	}
 
 	if len(results) > 0 {
@@ -135,6 +296,18 @@ func (q *retryQueue) Dequeue(n int) ([]commontypes.UpkeepPayload, error) {
 	return results, nil
 }
 
+// compact drops the outdated items of the due queue once they outnumber the
+// records. Must be called with the lock held.
+func (q *retryQueue) compact() {
+	if !q.due.shouldCompact(len(q.records)) {
+		return
+	}
+	q.due.compact(func(item dueItem) bool {
+		record, ok := q.records[item.key]
+		return ok && record.seq == item.seq
+	})
+}
+
 // Size returns the number of items in the queue that are not expired
 func (q *retryQueue) Size() int {
 	q.lock.RLock()
@@ -152,3 +325,18 @@ func (q *retryQueue) Size() int {
 
 	return size
 }
+
+// UpkeepStateDeadLetter returns a dead letter handler that marks payloads
+// that exhausted their retry attempts as ineligible with the state updater
+func UpkeepStateDeadLetter(updater commontypes.UpkeepStateUpdater, lggr *log.Logger) types.DeadLetterHandler {
+	return func(payload commontypes.UpkeepPayload, attempts int) {
+		result := commontypes.CheckResult{
+			UpkeepID: payload.UpkeepID,
+			WorkID:   payload.WorkID,
+			Trigger:  payload.Trigger,
+		}
+		if err := updater.SetUpkeepState(context.Background(), result, commontypes.Ineligible); err != nil {
+			lggr.Printf("failed to set state of workID %s that exhausted %d retry attempts: %s", payload.WorkID, attempts, err)
+		}
+	}
+}
@@ -2,6 +2,8 @@ package stores
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io"
 	"log"
 	"testing"
@@ -114,6 +116,139 @@ func TestRetryQueue_Expiration(t *testing.T) {
 	})
 }
 
+func TestRetryQueue_Backoff(t *testing.T) {
+	q := NewRetryQueue(log.New(io.Discard, "", 0))
+
+	// without a max interval retries are not backed off
+	require.Equal(t, time.Second, q.backoff(time.Second, 0))
+	require.Equal(t, time.Second, q.backoff(time.Second, 5))
+
+	q = NewRetryQueueWithStore(log.New(io.Discard, "", 0), 0, time.Minute, nil, nil)
+
+	require.Equal(t, time.Second, q.backoff(time.Second, 0))
+	for attempts, expected := range []time.Duration{2 * time.Second, 4 * time.Second, 8 * time.Second} {
+		interval := q.backoff(time.Second, attempts+1)
+		require.InDelta(t, float64(expected), float64(interval), float64(expected)*RetryJitter)
+	}
+	// the interval is capped
+	require.InDelta(t, float64(time.Minute), float64(q.backoff(time.Second, 10)), float64(time.Minute)*RetryJitter)
+	require.InDelta(t, float64(time.Minute), float64(q.backoff(time.Second, 1000)), float64(time.Minute)*RetryJitter)
+}
+
+func TestRetryQueue_DeadLetter(t *testing.T) {
+	revert := overrideDefaults(time.Minute, time.Millisecond)
+	defer revert()
+
+	var deadLetters []ocr2keepers.UpkeepPayload
+	var deadAttempts []int
+	q := NewRetryQueueWithDeadLetter(log.New(io.Discard, "", 0), 2, func(payload ocr2keepers.UpkeepPayload, attempts int) {
+		deadLetters = append(deadLetters, payload)
+		deadAttempts = append(deadAttempts, attempts)
+	})
+
+	payload := ocr2keepers.UpkeepPayload{WorkID: "1"}
+	for i := 0; i < 2; i++ {
+		require.NoError(t, q.Enqueue(newRetryRecord(payload, 0)))
+		require.Eventually(t, func() bool {
+			items, err := q.Dequeue(1)
+			return err == nil && len(items) == 1
+		}, time.Second, time.Millisecond)
+	}
+	require.Empty(t, deadLetters)
+
+	// the second retry failed as well
+	require.NoError(t, q.Enqueue(newRetryRecord(payload, 0)))
+	require.Equal(t, []ocr2keepers.UpkeepPayload{payload}, deadLetters)
+	require.Equal(t, []int{2}, deadAttempts)
+	require.Equal(t, 0, q.Size())
+
+	// a later failure starts over
+	require.NoError(t, q.Enqueue(newRetryRecord(payload, 0)))
+	require.Equal(t, 1, q.Size())
+}
+
+func TestRetryQueue_DequeueOrder(t *testing.T) {
+	revert := overrideDefaults(time.Minute, time.Millisecond)
+	defer revert()
+
+	q := NewRetryQueue(log.New(io.Discard, "", 0))
+
+	require.NoError(t, q.Enqueue(
+		newRetryRecord(ocr2keepers.UpkeepPayload{WorkID: "1"}, 30*time.Millisecond),
+		newRetryRecord(ocr2keepers.UpkeepPayload{WorkID: "2"}, 10*time.Millisecond),
+		newRetryRecord(ocr2keepers.UpkeepPayload{WorkID: "3"}, 20*time.Millisecond),
+		newRetryRecord(ocr2keepers.UpkeepPayload{WorkID: "4"}, time.Hour),
+	))
+
+	<-time.After(50 * time.Millisecond)
+
+	// items are dequeued in the order in which they became due
+	items, err := q.Dequeue(2)
+	require.NoError(t, err)
+	require.Equal(t, []ocr2keepers.UpkeepPayload{{WorkID: "2"}, {WorkID: "3"}}, items)
+
+	items, err = q.Dequeue(10)
+	require.NoError(t, err)
+	require.Equal(t, []ocr2keepers.UpkeepPayload{{WorkID: "1"}}, items)
+
+	// outdated items do not pile up in the due queue
+	for i := 0; i < 1000; i++ {
+		require.NoError(t, q.Enqueue(newRetryRecord(ocr2keepers.UpkeepPayload{WorkID: "4"}, time.Hour)))
+	}
+	require.LessOrEqual(t, q.due.len(), 2*len(q.records)+64)
+}
+
+func BenchmarkRetryQueue_Dequeue(b *testing.B) {
+	revert := overrideDefaults(time.Hour, time.Nanosecond)
+	defer revert()
+
+	records := make([]types.RetryRecord, 10_000)
+	for i := range records {
+		records[i] = newRetryRecord(ocr2keepers.UpkeepPayload{WorkID: fmt.Sprint(i)}, 0)
+	}
+
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		q := NewRetryQueue(log.New(io.Discard, "", 0))
+		if err := q.Enqueue(records...); err != nil {
+			b.Fatal(err)
+		}
+		b.StartTimer()
+
+		for {
+			items, err := q.Dequeue(100)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if len(items) == 0 {
+				break
+			}
+		}
+	}
+}
+
+type mockUpkeepStateUpdater struct {
+	results []ocr2keepers.CheckResult
+	states  []ocr2keepers.UpkeepState
+	err     error
+}
+
+func (u *mockUpkeepStateUpdater) SetUpkeepState(_ context.Context, result ocr2keepers.CheckResult, state ocr2keepers.UpkeepState) error {
+	u.results = append(u.results, result)
+	u.states = append(u.states, state)
+	return u.err
+}
+
+func TestUpkeepStateDeadLetter(t *testing.T) {
+	updater := &mockUpkeepStateUpdater{err: errors.New("db down")}
+	handler := UpkeepStateDeadLetter(updater, log.New(io.Discard, "", 0))
+
+	handler(ocr2keepers.UpkeepPayload{WorkID: "1", Trigger: ocr2keepers.Trigger{BlockNumber: 10}}, 3)
+
+	require.Equal(t, []ocr2keepers.CheckResult{{WorkID: "1", Trigger: ocr2keepers.Trigger{BlockNumber: 10}}}, updater.results)
+	require.Equal(t, []ocr2keepers.UpkeepState{ocr2keepers.Ineligible}, updater.states)
+}
+
 func newRetryRecord(payload ocr2keepers.UpkeepPayload, interval time.Duration) types.RetryRecord {
 	return types.RetryRecord{
 		Payload:  payload,
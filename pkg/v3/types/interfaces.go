@@ -30,11 +30,18 @@ type RetryQueue interface {
 	Dequeue(n int) ([]automation.UpkeepPayload, error)
 }
 
+// DeadLetterHandler receives payloads that exhausted their retry attempts
+// along with the number of attempts
+type DeadLetterHandler func(payload automation.UpkeepPayload, attempts int)
+
 type ProposalQueue interface {
 	// Enqueue adds new items to the queue
 	Enqueue(items ...automation.CoordinatedBlockProposal) error
 	// Dequeue returns the next n items in the queue, considering retry time schedules
 	Dequeue(t UpkeepType, n int) ([]automation.CoordinatedBlockProposal, error)
+	// Requeue returns dequeued items to the queue such that they are dequeued
+	// again, as long as they did not use up their retry budget
+	Requeue(items ...automation.CoordinatedBlockProposal) error
 }
 
 //go:generate mockery --name TransmitEventProvider --srcpkg "github.com/smartcontractkit/chainlink-automation/pkg/v3/types" --case underscore --filename transmit_event_provider.generated.go
@@ -42,6 +49,17 @@ type TransmitEventProvider interface {
 	GetLatestEvents(context.Context) ([]automation.TransmitEvent, error)
 }
 
+// TransmitEventSubscriber can be implemented by a TransmitEventProvider to
+// push transmit events as they are indexed instead of having them polled.
+// Pushed events are subject to the same minimum confirmations as polled
+// events, so an event should be pushed again as its confirmations increase.
+type TransmitEventSubscriber interface {
+	// SubscribeTransmitEvents provides an identifier integer, a new channel, and potentially an error
+	SubscribeTransmitEvents() (int, chan []automation.TransmitEvent, error)
+	// UnsubscribeTransmitEvents requires an identifier integer and indicates the provided channel should be closed
+	UnsubscribeTransmitEvents(int) error
+}
+
 //go:generate mockery --name Runnable --structname MockRunnable --srcpkg "github.com/smartcontractkit/chainlink-automation/pkg/v3/types" --case underscore --filename runnable.generated.go
 type Runnable interface {
 	// Can get results for a subset of payloads along with an error
@@ -52,7 +70,13 @@ type Runnable interface {
 type ResultStore interface {
 	Add(...automation.CheckResult)
 	Remove(...string)
+	// View returns a copy of the results in the store
 	View() ([]automation.CheckResult, error)
+	// ViewShared returns the results in the store without copying them. The
+	// returned slice can be shared with other callers and must not be
+	// modified, callers that reorder or change the results need to copy them
+	// first.
+	ViewShared() ([]automation.CheckResult, error)
 }
 
 //go:generate mockery --name Coordinator --structname MockCoordinator --srcpkg "github.com/smartcontractkit/chainlink-automation/pkg/v3/types" --case underscore --filename coordinator.generated.go
@@ -63,6 +87,9 @@ type Coordinator interface {
 	ShouldTransmit(automation.ReportedUpkeep) bool
 	FilterResults([]automation.CheckResult) ([]automation.CheckResult, error)
 	FilterProposals([]automation.CoordinatedBlockProposal) ([]automation.CoordinatedBlockProposal, error)
+	// InFlightWorkIDs returns the workIDs that are accepted in a report and
+	// still pending transmission
+	InFlightWorkIDs() []string
 }
 
 //go:generate mockery --name MetadataStore --structname MockMetadataStore --srcpkg "github.com/smartcontractkit/chainlink-automation/pkg/v3/types" --case underscore --filename metadatastore.generated.go
@@ -78,6 +105,11 @@ type MetadataStore interface {
 	Close() error
 }
 
+// BlockHistoryGetter provides the latest block history
+type BlockHistoryGetter interface {
+	GetBlockHistory() automation.BlockHistory
+}
+
 //go:generate mockery --name Ratio --structname MockRatio --srcpkg "github.com/smartcontractkit/chainlink-automation/pkg/v3/types" --case underscore --filename ratio.generated.go
 type Ratio interface {
 	// OfInt should return n out of x such that n/x ~ r (ratio)
@@ -93,6 +93,26 @@ func (_m *MockCoordinator) FilterResults(_a0 []automation.CheckResult) ([]automa
 	return r0, r1
 }
 
+// InFlightWorkIDs provides a mock function with given fields:
+func (_m *MockCoordinator) InFlightWorkIDs() []string {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for InFlightWorkIDs")
+	}
+
+	var r0 []string
+	if rf, ok := ret.Get(0).(func() []string); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	return r0
+}
+
 // PreProcess provides a mock function with given fields: _a0, payloads
 func (_m *MockCoordinator) PreProcess(_a0 context.Context, payloads []automation.UpkeepPayload) ([]automation.UpkeepPayload, error) {
 	ret := _m.Called(_a0, payloads)
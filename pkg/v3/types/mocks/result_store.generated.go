@@ -64,6 +64,36 @@ func (_m *MockResultStore) View() ([]automation.CheckResult, error) {
 	return r0, r1
 }
 
+// ViewShared provides a mock function with given fields:
+func (_m *MockResultStore) ViewShared() ([]automation.CheckResult, error) {
+	ret := _m.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for ViewShared")
+	}
+
+	var r0 []automation.CheckResult
+	var r1 error
+	if rf, ok := ret.Get(0).(func() ([]automation.CheckResult, error)); ok {
+		return rf()
+	}
+	if rf, ok := ret.Get(0).(func() []automation.CheckResult); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]automation.CheckResult)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // NewMockResultStore creates a new instance of MockResultStore. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewMockResultStore(t interface {